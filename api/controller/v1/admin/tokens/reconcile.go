@@ -0,0 +1,80 @@
+package tokens
+
+import (
+	"net/http"
+	"time"
+
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/tokenbalance"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	ListReconcileReq struct {
+		Page      uint64 `form:"page" binding:"required,min=1"`
+		Size      uint64 `form:"size" binding:"required,min=1,max=100"`
+		ChainID   int64  `form:"chain_id" binding:"omitempty,min=1"`
+		Token     string `form:"token" binding:"omitempty"`
+		DriftOnly bool   `form:"drift_only" binding:"omitempty"` // only holders whose ledger balance does not match
+	}
+
+	ListReconcileRes struct {
+		Results []Reconcile `json:"results"`
+		Total   int64       `json:"total"`
+	}
+
+	Reconcile struct {
+		ChainID       int64     `json:"chain_id"`
+		Token         string    `json:"token"`
+		Holder        string    `json:"holder"`
+		BlockNumber   uint64    `json:"block_number"`
+		LedgerBalance string    `json:"ledger_balance"`
+		ChainBalance  string    `json:"chain_balance"`
+		Drift         string    `json:"drift"` // chain balance minus ledger balance
+		CheckedAt     time.Time `json:"checked_at"`
+	}
+)
+
+// ListReconcile reports the last comparison of the sampled ledger balances with balanceOf, latest checks first
+func ListReconcile(c *gin.Context) {
+	res := &ListReconcileRes{
+		Results: make([]Reconcile, 0),
+	}
+	c.Set(middleware.CtxResponse, res)
+
+	var req ListReconcileReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	results, total, err := service.GetReconcilesWithTotal(c.Request.Context(), &tokenbalance.GetReconcileFilter{
+		ChainID:    req.ChainID,
+		Token:      req.Token,
+		DriftOnly:  req.DriftOnly,
+		Pagination: &model.Pagination{Page: req.Page, Size: req.Size},
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res.Total = total
+	for _, v := range results {
+		res.Results = append(res.Results, Reconcile{
+			ChainID:       v.ChainID,
+			Token:         v.Token,
+			Holder:        v.Holder,
+			BlockNumber:   v.BlockNumber,
+			LedgerBalance: v.LedgerBalance.String(),
+			ChainBalance:  v.ChainBalance.String(),
+			Drift:         v.Drift.String(),
+			CheckedAt:     v.CheckedAt,
+		})
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,48 @@
+package contracts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// finalized entries no longer change, clients may keep them for a year
+const immutableMaxAge = 365 * 24 * time.Hour
+
+// setETag sets the ETag of a response, entries that can still change are revalidated on every request,
+// it reports whether the copy of the client matches the If-None-Match header.
+func setETag(c *gin.Context, res any, immutable bool) (bool, error) {
+	b, err := json.Marshal(res)
+	if err != nil {
+		return false, err
+	}
+
+	sum := sha256.Sum256(b)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	// responses are per user behind the authorization, shared caches should not keep them
+	c.Header("ETag", etag)
+	if immutable {
+		c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d, immutable", int(immutableMaxAge.Seconds())))
+	} else {
+		c.Header("Cache-Control", "private, no-cache")
+	}
+
+	return matchETag(c.GetHeader("If-None-Match"), etag), nil
+}
+
+// matchETag reports whether an If-None-Match header matches the etag, weak tags are compared by value
+func matchETag(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
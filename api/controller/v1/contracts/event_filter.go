@@ -0,0 +1,161 @@
+package contracts
+
+import (
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/decoder"
+	"evm_event_indexer/internal/decoder/provider"
+	"evm_event_indexer/internal/errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gin-gonic/gin"
+)
+
+// prefix of the indexed argument filters in a query, e.g. args.from=0x...
+const argsQueryPrefix = "args."
+
+// queryArgs returns the indexed argument filters of a query, name to comma separated values,
+// repeated keys are joined, e.g. args.from=0x1&args.from=0x2 as from: 0x1,0x2
+func queryArgs(c *gin.Context) map[string]string {
+	var args map[string]string
+	for key, values := range c.Request.URL.Query() {
+		name, ok := strings.CutPrefix(key, argsQueryPrefix)
+		if !ok || name == "" {
+			continue
+		}
+		if args == nil {
+			args = make(map[string]string)
+		}
+		args[name] = strings.Join(values, ",")
+	}
+	return args
+}
+
+// resolveEvents resolves event filters into topic0s, an event is a signature such as Transfer(address,address,uint256)
+// or the name of a registered event. Returns the registered events selected by the filters.
+func resolveEvents(values []string) ([]string, []provider.Event, error) {
+	registered := make(map[string]provider.Event)
+	for _, event := range decoder.Provider.Events() {
+		registered[event.Topic0.Hex()] = event
+	}
+
+	seen := make(map[string]bool)
+	topic0s := make([]string, 0, len(values))
+	events := make([]provider.Event, 0, len(values))
+	add := func(topic0 string) {
+		if seen[topic0] {
+			return
+		}
+		seen[topic0] = true
+		topic0s = append(topic0s, topic0)
+		if event, ok := registered[topic0]; ok {
+			events = append(events, event)
+		}
+	}
+
+	for _, value := range values {
+		// signatures are canonical, whitespace is ignored
+		value = strings.Join(strings.Fields(value), "")
+		if value == "" {
+			continue
+		}
+
+		if strings.Contains(value, "(") {
+			if !strings.HasSuffix(value, ")") {
+				return nil, nil, errors.ErrApiInvalidParam.New(fmt.Sprintf("invalid event signature: %s", value))
+			}
+			add(crypto.Keccak256Hash([]byte(value)).Hex())
+			continue
+		}
+
+		named := decoder.Provider.EventsByName(value)
+		if len(named) == 0 {
+			return nil, nil, errors.ErrApiInvalidParam.New(fmt.Sprintf("event %s is not registered, use its signature", value))
+		}
+		for _, event := range named {
+			add(event.Topic0.Hex())
+		}
+	}
+
+	return topic0s, events, nil
+}
+
+// resolveArgs resolves indexed argument filters into the topics of the arguments, args are name to comma separated values.
+// The arguments are looked up in the given events, and the topic0s are narrowed to the events declaring all of them.
+func resolveArgs(args map[string]string, events []provider.Event) (topic0s []string, topics [4][]string, err error) {
+	if len(args) > config.Get().API.MaxFilterValues {
+		return nil, topics, errors.ErrApiInvalidParam.New(fmt.Sprintf("args should not have more than %d arguments", config.Get().API.MaxFilterValues))
+	}
+
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var field *provider.Field
+		matched := make([]provider.Event, 0, len(events))
+		for _, event := range events {
+			for _, f := range event.Fields {
+				if f.Name != name {
+					continue
+				}
+				if f.Topic == 0 {
+					return nil, topics, errors.ErrApiInvalidParam.New(fmt.Sprintf("argument %s of %s is not indexed, use decoded[%s]", name, event.Name, name))
+				}
+				if field != nil && field.Topic != f.Topic {
+					return nil, topics, errors.ErrApiInvalidParam.New(fmt.Sprintf("argument %s is indexed at different positions, filter by event", name))
+				}
+				field = &f
+				matched = append(matched, event)
+			}
+		}
+
+		if field == nil {
+			return nil, topics, errors.ErrApiInvalidParam.New(fmt.Sprintf("argument %s is not declared by the filtered events", name))
+		}
+		events = matched
+
+		values := strings.Split(args[name], ",")
+		if len(values) > config.Get().API.MaxFilterValues {
+			return nil, topics, errors.ErrApiInvalidParam.New(fmt.Sprintf("args.%s should not have more than %d values", name, config.Get().API.MaxFilterValues))
+		}
+
+		for _, value := range values {
+			if value = strings.TrimSpace(value); value == "" {
+				continue
+			}
+			topic, err := field.Type.Topic(value)
+			if err != nil {
+				return nil, topics, errors.ErrApiInvalidParam.Wrap(err, fmt.Sprintf("invalid value of args.%s", name))
+			}
+			topics[field.Topic] = append(topics[field.Topic], topic)
+		}
+	}
+
+	for _, event := range events {
+		topic0s = append(topic0s, event.Topic0.Hex())
+	}
+
+	return topic0s, topics, nil
+}
+
+// registeredEvents returns the registered events of the topic0s, all registered events when no topic0 is given
+func registeredEvents(topic0s []string) []provider.Event {
+	events := decoder.Provider.Events()
+	if len(topic0s) == 0 {
+		return events
+	}
+
+	res := make([]provider.Event, 0, len(topic0s))
+	for _, event := range events {
+		if slices.Contains(topic0s, event.Topic0.Hex()) {
+			res = append(res, event)
+		}
+	}
+	return res
+}
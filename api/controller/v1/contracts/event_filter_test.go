@@ -0,0 +1,123 @@
+package contracts_test
+
+import (
+	"evm_event_indexer/api/controller/v1/contracts"
+	"evm_event_indexer/internal/decoder"
+	"evm_event_indexer/internal/decoder/erc20"
+	"evm_event_indexer/internal/decoder/provider"
+	"evm_event_indexer/service/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	transferTopic0 = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	approvalTopic0 = "0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"
+)
+
+func registerERC20() {
+	decoder.Provider.Register("Transfer(address,address,uint256)", &erc20.TransferDecoder{})
+	decoder.Provider.Register("Approval(address,address,uint256)", &erc20.ApprovalDecoder{})
+}
+
+func TestLogFilter_Event(t *testing.T) {
+	registerERC20()
+
+	param, err := contracts.LogFilter{Event: []string{"Transfer(address, address, uint256)"}}.ToParam(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{transferTopic0}, param.Topic0s)
+
+	param, err = contracts.LogFilter{Event: []string{"transfer", "Approval"}}.ToParam(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{transferTopic0, approvalTopic0}, param.Topic0s)
+
+	_, err = contracts.LogFilter{Event: []string{"Unknown"}}.ToParam(nil)
+	assert.Error(t, err)
+
+	_, err = contracts.LogFilter{Event: []string{"Transfer"}, Signature: []string{transferTopic0}}.ToParam(nil)
+	assert.Error(t, err)
+}
+
+func TestLogFilter_Args(t *testing.T) {
+	registerERC20()
+
+	owner := "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	ownerTopic := "0x000000000000000000000000f39fd6e51aad88f6f4ce6ab8827279cfffb92266"
+
+	// the argument selects the events declaring it
+	param, err := contracts.LogFilter{Args: map[string]string{"owner": owner}}.ToParam(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{approvalTopic0}, param.Topic0s)
+	assert.Equal(t, []string{ownerTopic}, param.Topic1s)
+
+	param, err = contracts.LogFilter{Event: []string{"Transfer"}, Args: map[string]string{"to": owner + "," + owner}}.ToParam(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{transferTopic0}, param.Topic0s)
+	assert.Empty(t, param.Topic1s)
+	assert.Equal(t, []string{ownerTopic, ownerTopic}, param.Topic2s)
+
+	// value is in the data
+	_, err = contracts.LogFilter{Args: map[string]string{"value": "1"}}.ToParam(nil)
+	assert.Error(t, err)
+
+	// no event declares both
+	_, err = contracts.LogFilter{Args: map[string]string{"from": owner, "owner": owner}}.ToParam(nil)
+	assert.Error(t, err)
+
+	// owner is not an argument of Transfer
+	_, err = contracts.LogFilter{Event: []string{"Transfer"}, Args: map[string]string{"owner": owner}}.ToParam(nil)
+	assert.Error(t, err)
+
+	// conflicts with the from filter on topic1
+	_, err = contracts.LogFilter{From: []string{owner}, Args: map[string]string{"from": owner}}.ToParam(nil)
+	assert.Error(t, err)
+}
+
+// refDecoder declares a ref field, typed differently per event
+type refDecoder struct {
+	name      string
+	fieldType provider.FieldType
+}
+
+func (d *refDecoder) EventName() string { return d.name }
+
+func (d *refDecoder) Decode(log *model.Log) (map[string]string, error) { return nil, nil }
+
+func (d *refDecoder) Fields() []provider.Field {
+	return []provider.Field{{Name: "ref", Type: d.fieldType}}
+}
+
+func TestLogFilter_DecodedTypes(t *testing.T) {
+	registerERC20()
+	decoder.Provider.Register("Deposit(bytes32)", &refDecoder{name: "Deposit", fieldType: provider.FieldBytes32})
+	decoder.Provider.Register("Withdraw(address)", &refDecoder{name: "Withdraw", fieldType: provider.FieldAddress})
+
+	ref := "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+
+	// the type of ref depends on the event
+	_, err := contracts.LogFilter{}.ToParam(map[string]string{"ref": ref})
+	assert.ErrorContains(t, err, "different types")
+
+	param, err := contracts.LogFilter{Event: []string{"Withdraw"}}.ToParam(map[string]string{"ref": ref})
+	require.NoError(t, err)
+	if assert.Len(t, param.Args, 1) {
+		assert.Equal(t, "0xf39fd6e51aad88f6f4ce6ab8827279cfffb92266", param.Args[0].Value)
+	}
+
+	// a 20-byte value is not a bytes32
+	_, err = contracts.LogFilter{Event: []string{"Deposit"}}.ToParam(map[string]string{"ref": ref})
+	assert.Error(t, err)
+
+	// value is only declared by Transfer
+	param, err = contracts.LogFilter{}.ToParam(map[string]string{"value": "gte:1"})
+	require.NoError(t, err)
+	if assert.Len(t, param.Args, 1) {
+		assert.Equal(t, model.ArgOpGte, param.Args[0].Op)
+	}
+
+	// value is not an argument of Withdraw
+	_, err = contracts.LogFilter{Event: []string{"Withdraw"}}.ToParam(map[string]string{"value": "1"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package contracts
+
+import (
+	"evm_event_indexer/internal/decoder"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/internal/export"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	// ExportFilterReq selects the exported logs, with the filters and range of the logs query
+	ExportFilterReq struct {
+		LogFilter
+		BNStart   uint64 `form:"bn_start" json:"bn_start" binding:"omitempty"`
+		BNEnd     uint64 `form:"bn_end" json:"bn_end" binding:"omitempty"`
+		StartTime string `form:"start_time" json:"start_time" binding:"required_with=EndTime"`
+		EndTime   string `form:"end_time" json:"end_time" binding:"required_with=StartTime"`
+	}
+
+	ExportLogReq struct {
+		ExportFilterReq
+		Format string `form:"format" binding:"required,oneof=csv ndjson"` // parquet is written by an export job
+	}
+)
+
+// ExportLog streams all the logs matching the filters as csv or ndjson in block number order,
+// decoded fields are flattened into decoded_<name> columns
+func ExportLog(c *gin.Context) {
+	var req ExportLogReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	req.Args = queryArgs(c)
+	filter, err := req.ExportFilterReq.ToFilter(c.QueryMap("decoded"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	format := export.Format(req.Format)
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="logs.%s"`, format))
+	c.Status(http.StatusOK)
+
+	w, err := export.NewWriter(format, c.Writer, decoder.Provider.Fields())
+	if err != nil {
+		c.Error(errors.ErrInternalServerError.Wrap(err, "failed to create writer"))
+		return
+	}
+
+	rows, err := service.ExportLogs(c.Request.Context(), filter, w)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		// nothing sent yet, the error is still returned as a response
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			c.Error(errors.ErrInternalServerError.Wrap(err, "failed to export logs"))
+			return
+		}
+		// the status is sent, the truncated body is the only signal to the client
+		slog.Error("export logs error", slog.Any("error", err), slog.Int64("rows", rows))
+		return
+	}
+
+	// an empty ndjson export has no body, send the headers so no response is added
+	c.Writer.WriteHeaderNow()
+}
+
+// ToFilter validates and normalizes the filters and range into an export filter,
+// decoded are the decoded argument filters, field to op:value.
+func (r ExportFilterReq) ToFilter(decoded map[string]string) (*model.ExportFilter, error) {
+	param, err := r.LogFilter.ToParam(decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	param.StartTime, param.EndTime, err = parseTimeRange(r.StartTime, r.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	param.BlockNumberGTE = r.BNStart
+	param.BlockNumberLTE = r.BNEnd
+
+	filter := service.NewExportFilter(param)
+	if err := service.CheckExportFilter(filter); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,115 @@
+package contracts
+
+import (
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	logRepo "evm_event_indexer/service/repo/eventlog"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	GetAddressLogUriReq struct {
+		Address string `uri:"address" binding:"required"`
+	}
+
+	GetAddressLogReq struct {
+		ChainID  int64    `form:"chain_id" binding:"required,min=1"`
+		Contract []string `form:"contract" collection_format:"csv" binding:"omitempty"` // contract addresses, all indexed contracts when empty
+		BNStart  uint64   `form:"bn_start" binding:"omitempty"`                         // 0 means no limit
+		BNEnd    uint64   `form:"bn_end" binding:"omitempty"`                           // 0 means no limit
+		Size     uint64   `form:"size" binding:"required,min=1,max=100"`
+		Cursor   string   `form:"cursor" binding:"omitempty"` // next_cursor of the previous page
+	}
+
+	GetAddressLogRes struct {
+		Logs       []*EventLog `json:"logs"`
+		NextCursor string      `json:"next_cursor,omitempty"` // cursor of the next page, only when the page is full
+	}
+)
+
+// GetAddressLog retrieves the logs involving an address in an indexed topic or a decoded argument, latest first
+func GetAddressLog(c *gin.Context) {
+	res := new(GetAddressLogRes)
+	res.Logs = make([]*EventLog, 0)
+
+	c.Set(middleware.CtxResponse, res)
+
+	var uri = new(GetAddressLogUriReq)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req GetAddressLogReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !common.IsHexAddress(uri.Address) {
+		c.Error(errors.ErrApiInvalidParam.New("invalid address format"))
+		return
+	}
+
+	if len(req.Contract) > config.Get().API.MaxFilterValues {
+		c.Error(errors.ErrApiInvalidParam.New(fmt.Sprintf("contract should not have more than %d values", config.Get().API.MaxFilterValues)))
+		return
+	}
+
+	contracts := make([]string, 0, len(req.Contract))
+	for _, contract := range req.Contract {
+		contract = strings.TrimSpace(contract)
+		if contract == "" {
+			continue
+		}
+		if !common.IsHexAddress(contract) {
+			c.Error(errors.ErrApiInvalidParam.New("invalid contract format"))
+			return
+		}
+		contracts = append(contracts, contract)
+	}
+
+	var cursor *model.LogCursor
+	if req.Cursor != "" {
+		var err error
+		cursor, err = model.DecodeLogCursor(req.Cursor)
+		if err != nil {
+			c.Error(errors.ErrApiInvalidParam.Wrap(err, "invalid cursor"))
+			return
+		}
+	}
+
+	logs, err := service.GetAddressLogs(c.Request.Context(), &logRepo.GetParticipantParam{
+		ChainID:        req.ChainID,
+		Participant:    strings.ToLower(common.HexToAddress(uri.Address).Hex()),
+		Addresses:      contracts,
+		BlockNumberGTE: req.BNStart,
+		BlockNumberLTE: req.BNEnd,
+		Cursor:         cursor,
+		Limit:          req.Size,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// a full page means there may be more logs before the last one
+	if len(logs) > 0 && uint64(len(logs)) == req.Size {
+		res.NextCursor = model.NewLogCursor(logs[len(logs)-1]).Encode()
+	}
+
+	res.Logs = make([]*EventLog, len(logs))
+	for i, log := range logs {
+		res.Logs[i] = newEventLog(log)
+	}
+
+	c.Status(http.StatusOK)
+}
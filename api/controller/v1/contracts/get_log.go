@@ -3,11 +3,16 @@ package contracts
 import (
 	"encoding/hex"
 	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/decoder"
+	"evm_event_indexer/internal/decoder/provider"
 	"evm_event_indexer/internal/errors"
 	"evm_event_indexer/service"
 	"evm_event_indexer/service/model"
 	logRepo "evm_event_indexer/service/repo/eventlog"
+	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -16,30 +21,46 @@ import (
 )
 
 type (
+	// LogFilter are the filters shared by the logs query, the logs stream and the webhooks,
+	// list filters accept repeated keys or comma separated values, e.g. address=0x1&address=0x2 or address=0x1,0x2
+	LogFilter struct {
+		ChainID   int64    `form:"chain_id" json:"chain_id"`
+		Address   []string `form:"address" json:"address" collection_format:"csv" binding:"omitempty"`
+		TxHash    []string `form:"tx_hash" json:"tx_hash" collection_format:"csv" binding:"omitempty"`
+		Signature []string `form:"signature" json:"signature" collection_format:"csv" binding:"omitempty"`
+		From      []string `form:"from" json:"from" collection_format:"csv" binding:"omitempty"`
+		To        []string `form:"to" json:"to" collection_format:"csv" binding:"omitempty"`
+		Topic3    []string `form:"topic3" json:"topic3" collection_format:"csv" binding:"omitempty"`
+		// event signatures such as Transfer(address,address,uint256) or registered event names, repeated keys only as signatures contain commas
+		Event []string `form:"event" json:"event" binding:"omitempty"`
+		// indexed argument filters of the registered events, name to comma separated values, args.<name>=<value> in a query
+		Args map[string]string `form:"-" json:"args"`
+	}
+
 	GetLogReq struct {
-		ChainID   int64  `form:"chain_id"`
-		Address   string `form:"address" binding:"omitempty"`
-		TxHash    string `form:"tx_hash" binding:"omitempty"`
+		LogFilter
 		BNStart   uint64 `form:"bn_start" binding:"omitempty"` // 0 means no limit
 		BNEnd     uint64 `form:"bn_end" binding:"omitempty"`   // 0 means no limit
-		Signature string `form:"signature" binding:"omitempty"`
-		From      string `form:"from" binding:"omitempty"`
-		To        string `form:"to" binding:"omitempty"`
-		StartTime string `form:"start_time" binding:"required"`
-		EndTime   string `form:"end_time" binding:"required"`
+		StartTime string `form:"start_time" binding:"required_with=EndTime"`
+		EndTime   string `form:"end_time" binding:"required_with=StartTime"`
 		OrderBy   int8   `form:"order_by"`
 		Desc      bool   `form:"desc"`
-		Page      uint64 `form:"page" binding:"required,min=1"`
+		Page      uint64 `form:"page" binding:"required_without=Cursor,omitempty,min=1"`
 		Size      uint64 `form:"size" binding:"required,min=1,max=100"`
+		Cursor    string `form:"cursor" binding:"omitempty"`     // next_cursor of the previous page, replaces page
+		SkipTotal bool   `form:"skip_total" binding:"omitempty"` // skip counting the total
+		// add the uint256 decoded fields formatted with the token decimals, e.g. value 1500000000000000000 as 1.5
+		FormatAmounts bool `form:"format_amounts" binding:"omitempty"`
 	}
 
 	GetLogRes struct {
-		Logs  []*EventLog `json:"logs"`
-		Total int64       `json:"total"`
+		Logs       []*EventLog `json:"logs"`
+		Total      *int64      `json:"total,omitempty"`       // omitted when skip_total is set or a cursor is given
+		NextCursor string      `json:"next_cursor,omitempty"` // cursor of the next page, only when ordered by block number within a chain
 	}
 
 	EventLog struct {
-		ID             int64               `json:"id"`
+		ID             int64               `json:"id,omitempty"` // not known yet for streamed logs
 		ChainID        int64               `json:"chain_id"`
 		BlockNumber    uint64              `json:"block_number"`
 		BlockHash      string              `json:"block_hash"`
@@ -50,7 +71,11 @@ type (
 		TxIndex        int32               `json:"tx_index"`
 		LogIndex       int32               `json:"log_index"`
 		DecodedEvent   *model.DecodedEvent `json:"decoded_event"`
+		FormattedData  map[string]string   `json:"formatted_data,omitempty"` // with format_amounts, omitted when the token metadata is not cached
 		BlockTimestamp time.Time           `json:"block_timestamp"`
+		Confirmed      bool                `json:"confirmed"`                 // false until the scanner reconciles a log pushed by the live subscription
+		L1BlockNumber  uint64              `json:"l1_block_number,omitempty"` // L1 origin of L2 chains
+		L1BlockHash    string              `json:"l1_block_hash,omitempty"`
 	}
 )
 
@@ -67,23 +92,16 @@ func GetLog(c *gin.Context) {
 		return
 	}
 
-	// Validate address format
-	if req.Address != "" && !common.IsHexAddress(req.Address) {
-		c.Error(errors.ErrApiInvalidParam.Wrap(nil, "invalid address format"))
-		return
-	}
-
-	// Parse start_time (RFC3339 format)
-	startTime, err := time.Parse(time.RFC3339, req.StartTime)
+	req.Args = queryArgs(c)
+	param, err := req.LogFilter.ToParam(c.QueryMap("decoded"))
 	if err != nil {
-		c.Error(errors.ErrApiInvalidParam.Wrap(err, "invalid start_time format, expected RFC3339"))
+		c.Error(err)
 		return
 	}
 
-	// Parse end_time (RFC3339 format)
-	endTime, err := time.Parse(time.RFC3339, req.EndTime)
+	startTime, endTime, err := parseTimeRange(req.StartTime, req.EndTime)
 	if err != nil {
-		c.Error(errors.ErrApiInvalidParam.Wrap(err, "invalid end_time format, expected RFC3339"))
+		c.Error(err)
 		return
 	}
 
@@ -92,82 +110,350 @@ func GetLog(c *gin.Context) {
 		req.OrderBy = 2
 	}
 
-	if req.Signature != "" && !isHex32Bytes(req.Signature) {
-		c.Error(errors.ErrApiInvalidParam.New("invalid signature, expected 32-byte hex"))
-		return
+	// keyset pagination follows the block number order of a single chain
+	var cursor *model.LogCursor
+	if req.Cursor != "" {
+		if req.OrderBy != 2 {
+			c.Error(errors.ErrApiInvalidParam.New("cursor only supports order_by block number"))
+			return
+		}
+		if req.ChainID == 0 {
+			c.Error(errors.ErrApiInvalidParam.New("chain_id is required with cursor"))
+			return
+		}
+
+		cursor, err = model.DecodeLogCursor(req.Cursor)
+		if err != nil {
+			c.Error(errors.ErrApiInvalidParam.Wrap(err, "invalid cursor"))
+			return
+		}
 	}
 
-	fromTopic, err := normalizeTopicAddressOrHash(req.From)
-	if err != nil {
-		c.Error(err)
-		return
+	param.StartTime = startTime
+	param.EndTime = endTime
+	param.BlockNumberGTE = req.BNStart
+	param.BlockNumberLTE = req.BNEnd
+	param.OrderBy = req.OrderBy
+	param.Desc = req.Desc
+	param.Cursor = cursor
+	param.Pagination = &model.Pagination{
+		Page: req.Page,
+		Size: req.Size,
 	}
-	toTopic, err := normalizeTopicAddressOrHash(req.To)
-	if err != nil {
+
+	// a query needs a time range, a block range or a tx hash
+	if err := service.CheckLogRange(param); err != nil {
 		c.Error(err)
 		return
 	}
 
-	param := &logRepo.GetLogParam{
-		ChainID:        req.ChainID,
-		Address:        req.Address,
-		StartTime:      startTime,
-		EndTime:        endTime,
-		Topic0:         strings.ToLower(strings.TrimSpace(req.Signature)),
-		Topic1:         fromTopic,
-		Topic2:         toTopic,
-		TxHash:         req.TxHash,
-		BlockNumberGTE: req.BNStart,
-		BlockNumberLTE: req.BNEnd,
-		OrderBy:        req.OrderBy,
-		Desc:           req.Desc,
-		Pagination: &model.Pagination{
-			Page: req.Page,
-			Size: req.Size,
-		},
-	}
-
-	logs, total, err := service.GetLogsWithTotal(c.Request.Context(), param)
+	var logs []*model.Log
+	if req.SkipTotal || cursor != nil {
+		logs, err = service.GetLogsWithoutTotal(c.Request.Context(), param)
+	} else {
+		var total int64
+		logs, total, err = service.GetLogsWithTotal(c.Request.Context(), param)
+		res.Total = &total
+	}
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	res.Total = total
+	// a full page means there may be more logs after the last one
+	if req.OrderBy == 2 && req.ChainID != 0 && len(logs) > 0 && uint64(len(logs)) == req.Size {
+		res.NextCursor = model.NewLogCursor(logs[len(logs)-1]).Encode()
+	}
+
+	var metadata map[string]*model.TokenMetadata
+	if req.FormatAmounts {
+		metadata, err = service.GetLogTokenMetadata(c.Request.Context(), logs)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
 	res.Logs = make([]*EventLog, len(logs))
 	for i, log := range logs {
+		res.Logs[i] = newEventLog(log)
+		if token, ok := metadata[model.NewTokenKey(log.ChainID, log.Address)]; ok {
+			res.Logs[i].FormattedData = formatAmounts(log, token)
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// parseTimeRange parses an optional RFC3339 time range, zero times when not given
+func parseTimeRange(start string, end string) (startTime time.Time, endTime time.Time, err error) {
+	if start == "" {
+		return startTime, endTime, nil
+	}
+
+	startTime, err = time.Parse(time.RFC3339, start)
+	if err != nil {
+		return startTime, endTime, errors.ErrApiInvalidParam.Wrap(err, "invalid start_time format, expected RFC3339")
+	}
+
+	endTime, err = time.Parse(time.RFC3339, end)
+	if err != nil {
+		return startTime, endTime, errors.ErrApiInvalidParam.Wrap(err, "invalid end_time format, expected RFC3339")
+	}
+
+	return startTime, endTime, nil
+}
+
+func newEventLog(log *model.Log) *EventLog {
+	topics := make([]string, 0)
+	if log.Topic0 != "" {
+		topics = append(topics, log.Topic0)
+	}
+	if log.Topic1 != "" {
+		topics = append(topics, log.Topic1)
+	}
+	if log.Topic2 != "" {
+		topics = append(topics, log.Topic2)
+	}
+	if log.Topic3 != "" {
+		topics = append(topics, log.Topic3)
+	}
+
+	return &EventLog{
+		ID:             log.ID,
+		ChainID:        log.ChainID,
+		BlockNumber:    log.BlockNumber,
+		BlockHash:      log.BlockHash,
+		TxHash:         log.TxHash,
+		Address:        log.Address,
+		Topics:         topics,
+		Data:           "0x" + hex.EncodeToString(log.Data),
+		LogIndex:       log.LogIndex,
+		TxIndex:        log.TxIndex,
+		DecodedEvent:   log.DecodedEvent,
+		BlockTimestamp: log.BlockTimestamp,
+		Confirmed:      log.Confirmed,
+		L1BlockNumber:  log.L1BlockNumber,
+		L1BlockHash:    log.L1BlockHash,
+	}
+}
+
+// formatAmounts formats the uint256 fields of the decoded event of a log with the token decimals, nil if there is none
+func formatAmounts(log *model.Log, token *model.TokenMetadata) map[string]string {
+	if log.DecodedEvent == nil {
+		return nil
+	}
 
-		topics := make([]string, 0)
-		if log.Topic0 != "" {
-			topics = append(topics, log.Topic0)
+	var res map[string]string
+	for _, field := range decoder.Provider.EventFields(log.Topic0) {
+		if field.Type != provider.FieldUint256 {
+			continue
 		}
-		if log.Topic1 != "" {
-			topics = append(topics, log.Topic1)
+
+		value, ok := log.DecodedEvent.EventData[field.Name]
+		if !ok {
+			continue
 		}
-		if log.Topic2 != "" {
-			topics = append(topics, log.Topic2)
+
+		amount, err := token.FormatAmount(value)
+		if err != nil {
+			continue
 		}
-		if log.Topic3 != "" {
-			topics = append(topics, log.Topic3)
+
+		if res == nil {
+			res = make(map[string]string)
 		}
+		res[field.Name] = amount
+	}
 
-		res.Logs[i] = &EventLog{
-			ID:             log.ID,
-			ChainID:        log.ChainID,
-			BlockNumber:    log.BlockNumber,
-			BlockHash:      log.BlockHash,
-			TxHash:         log.TxHash,
-			Address:        log.Address,
-			Topics:         topics,
-			Data:           "0x" + hex.EncodeToString(log.Data),
-			LogIndex:       log.LogIndex,
-			TxIndex:        log.TxIndex,
-			DecodedEvent:   log.DecodedEvent,
-			BlockTimestamp: log.BlockTimestamp,
+	return res
+}
+
+// ToParam validates and normalizes the filters into a logs query without range, order and pagination,
+// decoded are the decoded argument filters, field to op:value.
+func (f LogFilter) ToParam(decoded map[string]string) (*logRepo.GetLogParam, error) {
+	// limit the number of values of each list filter
+	for name, values := range map[string][]string{
+		"address":   f.Address,
+		"tx_hash":   f.TxHash,
+		"signature": f.Signature,
+		"from":      f.From,
+		"to":        f.To,
+		"topic3":    f.Topic3,
+		"event":     f.Event,
+	} {
+		if len(values) > config.Get().API.MaxFilterValues {
+			return nil, errors.ErrApiInvalidParam.New(fmt.Sprintf("%s should not have more than %d values", name, config.Get().API.MaxFilterValues))
 		}
 	}
 
-	c.Status(http.StatusOK)
+	// Validate address format
+	addresses := make([]string, 0, len(f.Address))
+	for _, address := range f.Address {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+		if !common.IsHexAddress(address) {
+			return nil, errors.ErrApiInvalidParam.Wrap(nil, "invalid address format")
+		}
+		addresses = append(addresses, address)
+	}
+
+	txHashes := make([]string, 0, len(f.TxHash))
+	for _, txHash := range f.TxHash {
+		if txHash = strings.TrimSpace(txHash); txHash != "" {
+			txHashes = append(txHashes, txHash)
+		}
+	}
+
+	signatures := make([]string, 0, len(f.Signature))
+	for _, signature := range f.Signature {
+		signature = strings.TrimSpace(signature)
+		if signature == "" {
+			continue
+		}
+		if !isHex32Bytes(signature) {
+			return nil, errors.ErrApiInvalidParam.New("invalid signature, expected 32-byte hex")
+		}
+		signatures = append(signatures, strings.ToLower(signature))
+	}
+
+	fromTopics, err := normalizeTopicsAddressOrHash(f.From)
+	if err != nil {
+		return nil, err
+	}
+	toTopics, err := normalizeTopicsAddressOrHash(f.To)
+	if err != nil {
+		return nil, err
+	}
+	topic3s, err := normalizeTopicsAddressOrHash(f.Topic3)
+	if err != nil {
+		return nil, err
+	}
+
+	// events are the readable form of the signature filter
+	eventTopics, events, err := resolveEvents(f.Event)
+	if err != nil {
+		return nil, err
+	}
+	if len(eventTopics) > 0 {
+		if len(signatures) > 0 {
+			return nil, errors.ErrApiInvalidParam.New("signature and event should not be combined")
+		}
+		signatures = eventTopics
+	}
+
+	// indexed arguments are the readable form of the topic filters, looked up in the filtered events
+	if len(f.Args) > 0 {
+		if len(f.Event) == 0 {
+			events = registeredEvents(signatures)
+		}
+
+		topic0s, topics, err := resolveArgs(f.Args, events)
+		if err != nil {
+			return nil, err
+		}
+		signatures = topic0s
+
+		for position, filter := range map[int]*[]string{1: &fromTopics, 2: &toTopics, 3: &topic3s} {
+			if len(topics[position]) == 0 {
+				continue
+			}
+			if len(*filter) > 0 {
+				return nil, errors.ErrApiInvalidParam.New(fmt.Sprintf("args filter on topic %d should not be combined with the topic filter", position))
+			}
+			*filter = topics[position]
+		}
+	}
+
+	// decoded arguments are typed by the filtered events, all registered events when no event is filtered
+	args, err := parseDecodedFilters(decoded, registeredEvents(signatures))
+	if err != nil {
+		return nil, err
+	}
+
+	return &logRepo.GetLogParam{
+		ChainID:   f.ChainID,
+		Addresses: addresses,
+		TxHashes:  txHashes,
+		Topic0s:   signatures,
+		Topic1s:   fromTopics,
+		Topic2s:   toTopics,
+		Topic3s:   topic3s,
+		Args:      args,
+	}, nil
+}
+
+// parseDecodedFilters parses decoded argument filters, decoded[field]=op:value,
+// the operator defaults to eq, e.g. decoded[value]=gte:1e18 or decoded[from]=0xf39f...
+// A field is typed by the given events declaring it, events declaring it with different types must be narrowed by an event filter.
+func parseDecodedFilters(query map[string]string, events []provider.Event) ([]logRepo.ArgFilter, error) {
+	if len(query) > config.Get().API.MaxFilterValues {
+		return nil, errors.ErrApiInvalidParam.New(fmt.Sprintf("decoded should not have more than %d fields", config.Get().API.MaxFilterValues))
+	}
+
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	filters := make([]logRepo.ArgFilter, 0, len(names))
+	for _, name := range names {
+		fieldType, err := decodedFieldType(name, events)
+		if err != nil {
+			return nil, err
+		}
+
+		op, value := model.ArgOpEq, query[name]
+		if raw, v, found := strings.Cut(value, ":"); found {
+			op, value = model.ArgOp(raw), v
+		}
+
+		if !fieldType.Supports(op) {
+			return nil, errors.ErrApiInvalidParam.New(fmt.Sprintf("operator %s is not supported on decoded field %s", op, name))
+		}
+
+		if op == model.ArgOpPrefix {
+			value, err = fieldType.NormalizePrefix(value)
+		} else {
+			value, err = fieldType.Normalize(value)
+		}
+		if err != nil {
+			return nil, errors.ErrApiInvalidParam.Wrap(err, fmt.Sprintf("invalid value of decoded field %s", name))
+		}
+
+		filters = append(filters, logRepo.ArgFilter{
+			Name:  name,
+			Op:    op,
+			Value: value,
+		})
+	}
+
+	return filters, nil
+}
+
+// decodedFieldType returns the type of a decoded field declared by the events
+func decodedFieldType(name string, events []provider.Event) (provider.FieldType, error) {
+	var fieldType provider.FieldType
+	for _, event := range events {
+		for _, field := range event.Fields {
+			if field.Name != name {
+				continue
+			}
+			if fieldType != "" && fieldType != field.Type {
+				return "", errors.ErrApiInvalidParam.New(fmt.Sprintf("decoded field %s has different types in the filtered events, filter by event", name))
+			}
+			fieldType = field.Type
+		}
+	}
+
+	if fieldType == "" {
+		return "", errors.ErrApiInvalidParam.New(fmt.Sprintf("decoded field %s is not queryable", name))
+	}
+
+	return fieldType, nil
 }
 
 func isHex32Bytes(s string) bool {
@@ -179,6 +465,21 @@ func isHex32Bytes(s string) bool {
 	return err == nil
 }
 
+// normalizeTopicsAddressOrHash normalizes a list of topics, empty values are dropped
+func normalizeTopicsAddressOrHash(values []string) ([]string, error) {
+	topics := make([]string, 0, len(values))
+	for _, v := range values {
+		topic, err := normalizeTopicAddressOrHash(v)
+		if err != nil {
+			return nil, err
+		}
+		if topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+	return topics, nil
+}
+
 func normalizeTopicAddressOrHash(s string) (string, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
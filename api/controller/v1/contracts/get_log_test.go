@@ -62,7 +62,7 @@ func TestGetLog_Success(t *testing.T) {
 	}))
 	t.Cleanup(func() {
 		_ = utils.NewTx(db).Exec(ctx, func(ctx context.Context, tx *sql.Tx) error {
-			return logRepo.TxDeleteLog(ctx, tx, address, 0)
+			return logRepo.TxDeleteLog(ctx, tx, chainID, address, 0)
 		})
 	})
 
@@ -203,7 +203,7 @@ func TestGetLog_InvalidRequest(t *testing.T) {
 	}))
 	t.Cleanup(func() {
 		_ = utils.NewTx(db).Exec(ctx, func(ctx context.Context, tx *sql.Tx) error {
-			return logRepo.TxDeleteLog(ctx, tx, address, 0)
+			return logRepo.TxDeleteLog(ctx, tx, chainID, address, 0)
 		})
 	})
 
@@ -0,0 +1,121 @@
+package contracts
+
+import (
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/decoder"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/eventrollup"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	GetStatsReq struct {
+		ChainID   int64    `form:"chain_id" binding:"required,min=1"`
+		Address   string   `form:"address" binding:"required"`
+		Signature []string `form:"signature" collection_format:"csv" binding:"omitempty"` // topic0 of the events, all events if empty
+		Bucket    string   `form:"bucket" binding:"required,oneof=hour day"`
+		StartTime string   `form:"start_time" binding:"required"`
+		EndTime   string   `form:"end_time" binding:"required"`
+	}
+
+	GetStatsRes struct {
+		Stats []*Stat `json:"stats"`
+	}
+
+	Stat struct {
+		BucketStart time.Time `json:"bucket_start"`
+		Signature   string    `json:"signature"`
+		EventName   string    `json:"event_name,omitempty"` // empty for events without a decoder
+		Count       int64     `json:"count"`
+		Volume      string    `json:"volume"`  // sum of the first uint256 decoded field, e.g. value of Transfer
+		Senders     int64     `json:"senders"` // unique values of the first address decoded field, e.g. from of Transfer
+	}
+)
+
+// GetStats returns the log count, volume and unique senders of a contract per time bucket, buckets without logs are omitted
+func GetStats(c *gin.Context) {
+	res := &GetStatsRes{
+		Stats: make([]*Stat, 0),
+	}
+	c.Set(middleware.CtxResponse, res)
+
+	var req GetStatsReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !common.IsHexAddress(req.Address) {
+		c.Error(errors.ErrApiInvalidParam.New("invalid address format"))
+		return
+	}
+
+	if len(req.Signature) > config.Get().API.MaxFilterValues {
+		c.Error(errors.ErrApiInvalidParam.New(fmt.Sprintf("signature should not have more than %d values", config.Get().API.MaxFilterValues)))
+		return
+	}
+
+	signatures := make([]string, 0, len(req.Signature))
+	for _, signature := range req.Signature {
+		if signature = strings.TrimSpace(signature); signature == "" {
+			continue
+		}
+		if !isHex32Bytes(signature) {
+			c.Error(errors.ErrApiInvalidParam.New("invalid signature, expected 32-byte hex"))
+			return
+		}
+		signatures = append(signatures, strings.ToLower(signature))
+	}
+
+	startTime, err := time.Parse(time.RFC3339, req.StartTime)
+	if err != nil {
+		c.Error(errors.ErrApiInvalidParam.Wrap(err, "invalid start_time format, expected RFC3339"))
+		return
+	}
+
+	endTime, err := time.Parse(time.RFC3339, req.EndTime)
+	if err != nil {
+		c.Error(errors.ErrApiInvalidParam.Wrap(err, "invalid end_time format, expected RFC3339"))
+		return
+	}
+
+	rollups, err := service.GetEventRollups(c.Request.Context(), &eventrollup.GetRollupFilter{
+		ChainID:   req.ChainID,
+		Address:   req.Address,
+		Topic0s:   signatures,
+		Bucket:    model.RollupBucket(req.Bucket),
+		StartTime: startTime,
+		EndTime:   endTime,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	names := make(map[string]string)
+	for _, event := range decoder.Provider.Events() {
+		names[strings.ToLower(event.Topic0.Hex())] = event.Name
+	}
+
+	for _, v := range rollups {
+		res.Stats = append(res.Stats, &Stat{
+			BucketStart: v.BucketStart.UTC(),
+			Signature:   v.Topic0,
+			EventName:   names[strings.ToLower(v.Topic0)],
+			Count:       v.LogCount,
+			Volume:      v.Volume.String(),
+			Senders:     v.Senders,
+		})
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,160 @@
+package contracts
+
+import (
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	GetLogDetailUriReq struct {
+		ChainID  int64  `uri:"chain_id" binding:"required,min=1"`
+		TxHash   string `uri:"tx_hash" binding:"required"`
+		LogIndex int32  `uri:"log_index" binding:"min=0"`
+	}
+
+	GetTxUriReq struct {
+		ChainID int64  `uri:"chain_id" binding:"required,min=1"`
+		TxHash  string `uri:"tx_hash" binding:"required"`
+	}
+
+	// LogDetail is an indexed log with the sync status of its block
+	LogDetail struct {
+		*EventLog
+		Status         model.LogStatus `json:"status"`                     // pending, confirmed or finalized
+		LastSyncNumber uint64          `json:"last_sync_number,omitempty"` // last block scanned for the contract, omitted once finalized
+	}
+
+	GetTxRes struct {
+		ChainID        int64           `json:"chain_id"`
+		TxHash         string          `json:"tx_hash"`
+		TxIndex        int32           `json:"tx_index"`
+		BlockNumber    uint64          `json:"block_number"`
+		BlockHash      string          `json:"block_hash"`
+		BlockTimestamp time.Time       `json:"block_timestamp"`
+		Status         model.LogStatus `json:"status"` // the least final status of the logs
+		Logs           []*LogDetail    `json:"logs"`
+	}
+)
+
+// GetLogDetail returns an indexed log by its position in a transaction,
+// finalized logs are sent with an immutable Cache-Control and every response with an ETag.
+func GetLogDetail(c *gin.Context) {
+	res := new(LogDetail)
+	c.Set(middleware.CtxResponse, res)
+
+	var uri GetLogDetailUriReq
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !isHex32Bytes(uri.TxHash) {
+		c.Error(errors.ErrApiInvalidParam.New("invalid tx_hash, expected 32-byte hex"))
+		return
+	}
+
+	log, err := service.GetLog(c.Request.Context(), uri.ChainID, strings.ToLower(uri.TxHash), uri.LogIndex)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	syncs, err := service.GetLogSyncs(c.Request.Context(), uri.ChainID, []*model.Log{log})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	*res = *newLogDetail(log, syncs[0])
+
+	notModified, err := setETag(c, res, res.Status == model.LogStatusFinalized)
+	if err != nil {
+		c.Error(errors.ErrInternalServerError.Wrap(err, "failed to compute etag"))
+		return
+	}
+	if notModified {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// GetTx returns the indexed logs of a transaction with the sync status of its block,
+// the transaction is immutable once all of its logs are finalized.
+func GetTx(c *gin.Context) {
+	res := new(GetTxRes)
+	c.Set(middleware.CtxResponse, res)
+
+	var uri GetTxUriReq
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !isHex32Bytes(uri.TxHash) {
+		c.Error(errors.ErrApiInvalidParam.New("invalid tx_hash, expected 32-byte hex"))
+		return
+	}
+
+	logs, err := service.GetTxLogs(c.Request.Context(), uri.ChainID, strings.ToLower(uri.TxHash))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	syncs, err := service.GetLogSyncs(c.Request.Context(), uri.ChainID, logs)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res.ChainID = logs[0].ChainID
+	res.TxHash = logs[0].TxHash
+	res.TxIndex = logs[0].TxIndex
+	res.BlockNumber = logs[0].BlockNumber
+	res.BlockHash = logs[0].BlockHash
+	res.BlockTimestamp = logs[0].BlockTimestamp
+	res.Status = model.LogStatusFinalized
+	res.Logs = make([]*LogDetail, len(logs))
+	for i, log := range logs {
+		res.Logs[i] = newLogDetail(log, syncs[i])
+		if res.Logs[i].Status.Rank() < res.Status.Rank() {
+			res.Status = res.Logs[i].Status
+		}
+	}
+
+	notModified, err := setETag(c, res, res.Status == model.LogStatusFinalized)
+	if err != nil {
+		c.Error(errors.ErrInternalServerError.Wrap(err, "failed to compute etag"))
+		return
+	}
+	if notModified {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// newLogDetail returns the detail of a log, the last sync number changes on every scan so it is left out of finalized logs,
+// which keeps their body, and so their ETag, unchanged while they are cached as immutable.
+func newLogDetail(log *model.Log, sync *service.LogSync) *LogDetail {
+	detail := &LogDetail{
+		EventLog: newEventLog(log),
+		Status:   sync.Status,
+	}
+
+	if sync.Status != model.LogStatusFinalized {
+		detail.LastSyncNumber = sync.LastSyncNumber
+	}
+
+	return detail
+}
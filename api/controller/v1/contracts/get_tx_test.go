@@ -0,0 +1,180 @@
+package contracts_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"evm_event_indexer/api/protocol"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/blocksync"
+	logRepo "evm_event_indexer/service/repo/eventlog"
+	"evm_event_indexer/utils"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTx_Success(t *testing.T) {
+	db, err := storage.GetMySQL(config.EventDBM)
+	require.NoError(t, err)
+
+	userID := int64(1)
+	chainID := int64(31337)
+	address := common.HexToAddress(fmt.Sprintf("0x%040x", time.Now().UnixNano())).Hex()
+	txHash := common.HexToHash(fmt.Sprintf("0x%064x", time.Now().UnixNano())).Hex()
+
+	logs := make([]*model.Log, 2)
+	for i := range logs {
+		logs[i] = &model.Log{
+			ChainID:        chainID,
+			Address:        address,
+			BlockHash:      common.HexToHash("0x01").Hex(),
+			BlockNumber:    1,
+			Topic0:         "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+			TxIndex:        0,
+			LogIndex:       int32(i),
+			TxHash:         txHash,
+			Data:           common.LeftPadBytes(common.Big1.Bytes(), 32),
+			BlockTimestamp: time.Now(),
+			Confirmed:      true,
+			CreatedAt:      time.Now(),
+		}
+	}
+
+	require.NoError(t, utils.NewTx(db).Exec(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for _, log := range logs {
+			if err := logRepo.TxInsertLog(ctx, tx, log); err != nil {
+				return err
+			}
+		}
+		// synced far past the reorg window
+		return blocksync.TxUpsertBlock(ctx, tx, &model.BlockSync{
+			ChainID:        chainID,
+			Address:        address,
+			LastSyncNumber: 1000,
+			LastSyncHash:   common.HexToHash("0x03").Hex(),
+			UpdatedAt:      time.Now(),
+		})
+	}))
+	t.Cleanup(func() {
+		_ = utils.NewTx(db).Exec(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			return logRepo.TxDeleteLog(ctx, tx, chainID, address, 0)
+		})
+	})
+
+	sessionOut, err := service.CreateSession(ctx, userID)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = service.RevokeUserSession(ctx, userID)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/txn/tx/%d/%s", chainID, txHash), nil)
+	req.Header.Set("Authorization", "Bearer "+sessionOut.AT)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Contains(t, w.Header().Get("Cache-Control"), "immutable")
+
+	var res protocol.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	result, ok := res.Result.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, string(model.LogStatusFinalized), result["status"])
+	assert.Len(t, result["logs"], 2)
+
+	// the cached copy is still valid
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/txn/tx/%d/%s", chainID, txHash), nil)
+	req.Header.Set("Authorization", "Bearer "+sessionOut.AT)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+
+	// single log
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/txn/logs/%d/%s/1", chainID, txHash), nil)
+	req.Header.Set("Authorization", "Bearer "+sessionOut.AT)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	result, ok = res.Result.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, float64(1), result["log_index"])
+	assert.Equal(t, string(model.LogStatusFinalized), result["status"])
+	// the last sync number changes on every scan, it is left out of an immutable response
+	assert.NotContains(t, result, "last_sync_number")
+	etag = w.Header().Get("ETag")
+
+	// the etag is unchanged after further scans
+	require.NoError(t, utils.NewTx(db).Exec(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return blocksync.TxUpsertBlock(ctx, tx, &model.BlockSync{
+			ChainID:        chainID,
+			Address:        address,
+			LastSyncNumber: 1001,
+			LastSyncHash:   common.HexToHash("0x04").Hex(),
+			UpdatedAt:      time.Now(),
+		})
+	}))
+
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/txn/logs/%d/%s/1", chainID, txHash), nil)
+	req.Header.Set("Authorization", "Bearer "+sessionOut.AT)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+}
+
+func TestGetTx_NotFound(t *testing.T) {
+	userID := int64(1)
+	sessionOut, err := service.CreateSession(ctx, userID)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = service.RevokeUserSession(ctx, userID)
+	})
+
+	testCases := []struct {
+		name         string
+		path         string
+		expectedCode int
+	}{
+		{
+			name:         "invalid tx hash",
+			path:         "/api/v1/txn/tx/31337/0x02",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "tx not indexed",
+			path:         "/api/v1/txn/tx/31337/" + common.HexToHash("0xdead").Hex(),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "log not indexed",
+			path:         "/api/v1/txn/logs/31337/" + common.HexToHash("0xdead").Hex() + "/0",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer "+sessionOut.AT)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package contracts
+
+import (
+	"context"
+	stdErrors "errors"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/internal/metrics"
+	"evm_event_indexer/internal/stream"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	logRepo "evm_event_indexer/service/repo/eventlog"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// number of stored logs read per query when resuming from a cursor
+	replayPageSize = 100
+	// maximum time to write a message to a websocket client
+	wsWriteWait = 10 * time.Second
+)
+
+// stream event types, besides stream.EventLog and stream.EventRemoved
+const streamEventError = "error"
+
+// the access token is sent in the Authorization header, so any origin can open the websocket
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+type (
+	StreamLogReq struct {
+		LogFilter
+		Cursor string `form:"cursor" binding:"omitempty"` // cursor of the last received log, the stored logs after it are replayed first
+	}
+
+	// StreamEvent is a message of the logs stream
+	StreamEvent struct {
+		Type        string    `json:"type"`                   // log, removed or error
+		Cursor      string    `json:"cursor,omitempty"`       // position of the log, pass it as cursor to resume after it
+		Log         *EventLog `json:"log,omitempty"`          // indexed log, logs pushed by the live subscription are sent again once confirmed
+		ChainID     int64     `json:"chain_id,omitempty"`     // removed: chain of the rolled back logs
+		Address     string    `json:"address,omitempty"`      // removed: contract of the rolled back logs
+		BlockNumber uint64    `json:"block_number,omitempty"` // removed: logs after this block were rolled back
+		Code        int       `json:"code,omitempty"`         // error: error code, the stream is closed after an error
+		Message     string    `json:"message,omitempty"`      // error: error message
+	}
+
+	// streamWriter sends stream events to a client
+	streamWriter interface {
+		write(e *StreamEvent) error
+		ping() error
+	}
+
+	sseWriter struct {
+		c *gin.Context
+	}
+
+	wsWriter struct {
+		conn *websocket.Conn
+	}
+)
+
+// StreamLogSSE streams new event logs as server-sent events,
+// the id of each event is its cursor, so EventSource resumes with Last-Event-ID on reconnect.
+func StreamLogSSE(c *gin.Context) {
+	param, cursor, err := bindStreamReq(c, c.GetHeader("Last-Event-ID"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	serveStream(c.Request.Context(), "sse", &sseWriter{c: c}, param, cursor)
+}
+
+// StreamLogWS streams new event logs over a websocket, one json message per event
+func StreamLogWS(c *gin.Context) {
+	param, cursor, err := bindStreamReq(c, "")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// the upgrader replies with an error on failure
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		slog.Warn("websocket upgrade failed", slog.Any("error", err))
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// the client does not send messages, read until it closes the connection
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	serveStream(ctx, "websocket", &wsWriter{conn: conn}, param, cursor)
+}
+
+// bindStreamReq binds the filters and the resume cursor, the query cursor takes precedence over the fallback
+func bindStreamReq(c *gin.Context, fallbackCursor string) (*logRepo.GetLogParam, *model.LogCursor, error) {
+	var req StreamLogReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		return nil, nil, err
+	}
+
+	req.Args = queryArgs(c)
+	param, err := req.LogFilter.ToParam(c.QueryMap("decoded"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if req.Cursor == "" {
+		req.Cursor = fallbackCursor
+	}
+
+	if req.Cursor == "" {
+		return param, nil, nil
+	}
+
+	// the cursor follows the block number order of a single chain
+	if req.ChainID == 0 {
+		return nil, nil, errors.ErrApiInvalidParam.New("chain_id is required with cursor")
+	}
+
+	cursor, err := model.DecodeLogCursor(req.Cursor)
+	if err != nil {
+		return nil, nil, errors.ErrApiInvalidParam.Wrap(err, "invalid cursor")
+	}
+
+	return param, cursor, nil
+}
+
+// serveStream replays the stored logs after the cursor, then sends the published events until the client leaves.
+func serveStream(ctx context.Context, protocol string, w streamWriter, param *logRepo.GetLogParam, cursor *model.LogCursor) {
+	// subscribe before the replay, so no log committed during the replay is missed
+	sub := stream.Logs.Subscribe(stream.NewFilter(param))
+	defer stream.Logs.Unsubscribe(sub)
+
+	metrics.StreamClients.WithLabelValues(protocol).Inc()
+	defer metrics.StreamClients.WithLabelValues(protocol).Dec()
+
+	replayed := make(map[replayKey]struct{})
+	if cursor != nil {
+		var err error
+		if replayed, err = replay(ctx, w, param, cursor); err != nil {
+			w.write(newErrorEvent(err))
+			return
+		}
+	}
+
+	ticker := time.NewTicker(config.Get().Stream.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.Dropped():
+			metrics.StreamDropped.WithLabelValues(protocol).Inc()
+			w.write(newErrorEvent(errors.ErrApiTimeout.New("stream fell behind, reconnect with the cursor of the last received log")))
+			return
+		case <-ticker.C:
+			if err := w.ping(); err != nil {
+				return
+			}
+		case e := <-sub.Events():
+			// published while replaying and already sent by the replay
+			if e.Type == stream.EventLog {
+				if _, ok := replayed[newReplayKey(e.Log)]; ok {
+					continue
+				}
+			}
+
+			if err := w.write(newStreamEvent(e)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// replayKey identifies a replayed log, a confirmed log replacing a live one is not the same message
+type replayKey struct {
+	cursor    model.LogCursor
+	confirmed bool
+}
+
+func newReplayKey(log *model.Log) replayKey {
+	return replayKey{cursor: *model.NewLogCursor(log), confirmed: log.Confirmed}
+}
+
+// replay sends the stored logs after the cursor in block number order
+func replay(ctx context.Context, w streamWriter, param *logRepo.GetLogParam, cursor *model.LogCursor) (map[replayKey]struct{}, error) {
+	filter := *param
+	filter.OrderBy = 2
+	filter.Pagination = &model.Pagination{
+		Page: 1,
+		Size: replayPageSize,
+	}
+
+	replayed := make(map[replayKey]struct{})
+	for {
+		filter.Cursor = cursor
+		// bounds the range scan, the cursor alone does not pick the block number index
+		filter.BlockNumberGTE = cursor.BlockNumber
+
+		logs, err := service.GetLogsWithoutTotal(ctx, &filter)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, log := range logs {
+			if len(replayed) >= config.Get().Stream.MaxReplay {
+				return nil, errors.ErrApiInvalidParam.New("too many logs after the cursor, query them with GET /api/v1/txn/logs first")
+			}
+
+			if err := w.write(newStreamEvent(stream.Event{Type: stream.EventLog, Log: log})); err != nil {
+				return nil, err
+			}
+
+			replayed[newReplayKey(log)] = struct{}{}
+			cursor = model.NewLogCursor(log)
+		}
+
+		if len(logs) < replayPageSize {
+			return replayed, nil
+		}
+	}
+}
+
+func newStreamEvent(e stream.Event) *StreamEvent {
+	if e.Type == stream.EventRemoved {
+		return &StreamEvent{
+			Type:        string(e.Type),
+			ChainID:     e.ChainID,
+			Address:     e.Address,
+			BlockNumber: e.BlockNumber,
+		}
+	}
+
+	return &StreamEvent{
+		Type:   string(e.Type),
+		Cursor: model.NewLogCursor(e.Log).Encode(),
+		Log:    newEventLog(e.Log),
+	}
+}
+
+func newErrorEvent(err error) *StreamEvent {
+	res := &StreamEvent{
+		Type:    streamEventError,
+		Code:    errors.ErrInternalServerError.ErrorCode,
+		Message: errors.ErrInternalServerError.Message,
+	}
+
+	var e *errors.Err
+	if stdErrors.As(err, &e) {
+		res.Code = e.ErrorCode
+		res.Message = e.Message
+	}
+
+	return res
+}
+
+func (w *sseWriter) write(e *StreamEvent) error {
+	if err := sse.Encode(w.c.Writer, sse.Event{
+		Id:    e.Cursor,
+		Event: e.Type,
+		Data:  e,
+	}); err != nil {
+		return err
+	}
+
+	w.c.Writer.Flush()
+	return nil
+}
+
+// ping sends a comment line, ignored by EventSource
+func (w *sseWriter) ping() error {
+	if _, err := io.WriteString(w.c.Writer, ": ping\n\n"); err != nil {
+		return err
+	}
+
+	w.c.Writer.Flush()
+	return nil
+}
+
+func (w *wsWriter) write(e *StreamEvent) error {
+	if err := w.conn.SetWriteDeadline(time.Now().Add(wsWriteWait)); err != nil {
+		return err
+	}
+	return w.conn.WriteJSON(e)
+}
+
+func (w *wsWriter) ping() error {
+	return w.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+}
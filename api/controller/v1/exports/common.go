@@ -0,0 +1,69 @@
+package exports
+
+import (
+	"evm_event_indexer/api/controller/v1/contracts"
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/internal/enum"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	// FilterReq selects the exported logs, with the same filters and range as the logs query
+	FilterReq struct {
+		contracts.ExportFilterReq
+		Decoded map[string]string `json:"decoded"` // decoded argument filters, field to op:value, e.g. {"value": "gte:1e18"}
+	}
+
+	GetRes struct {
+		ID          int64               `json:"id"`
+		Format      string              `json:"format"`
+		Filter      *model.ExportFilter `json:"filter"`
+		Status      enum.ExportStatus   `json:"status"`
+		Rows        int64               `json:"rows"`
+		Error       string              `json:"error,omitempty"`        // failed: error of the job
+		DownloadURL string              `json:"download_url,omitempty"` // succeeded: signed link of the file, no authorization needed
+		ExpiresAt   *time.Time          `json:"expires_at,omitempty"`   // finished: the job, its file and the link expire at
+		CreatedAt   time.Time           `json:"created_at"`
+		UpdatedAt   time.Time           `json:"updated_at"`
+	}
+)
+
+func getUserID(c *gin.Context) (int64, error) {
+	userID := c.GetInt64(middleware.CtxUserID)
+	if userID <= 0 {
+		return 0, errors.ErrInvalidCredentials.New("user id not found")
+	}
+	return userID, nil
+}
+
+func newGetRes(job *model.ExportJob) *GetRes {
+	res := &GetRes{
+		ID:        job.ID,
+		Format:    job.Format,
+		Filter:    job.Filter,
+		Status:    job.Status,
+		Rows:      job.Rows,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+
+	switch job.Status {
+	case enum.ExportStatusSucceeded:
+		expiresAt := service.ExportExpiresAt(job)
+		res.ExpiresAt = &expiresAt
+		res.DownloadURL = fmt.Sprintf("/api/v1/exports/%d/download?expires=%d&signature=%s",
+			job.ID, expiresAt.Unix(), service.SignExportDownload(job.ID, expiresAt.Unix()))
+	case enum.ExportStatusFailed:
+		expiresAt := service.ExportExpiresAt(job)
+		res.ExpiresAt = &expiresAt
+	}
+
+	return res
+}
@@ -0,0 +1,52 @@
+package exports
+
+import (
+	"net/http"
+
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/internal/export"
+	"evm_event_indexer/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	CreateReq struct {
+		Format string    `json:"format" binding:"required,oneof=csv ndjson parquet"`
+		Filter FilterReq `json:"filter"`
+	}
+)
+
+// Create queues an export job of the current user, the file is written in the background
+func Create(c *gin.Context) {
+	res := new(GetRes)
+	c.Set(middleware.CtxResponse, res)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req CreateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	filter, err := req.Filter.ToFilter(req.Filter.Decoded)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	job, err := service.CreateExportJob(c.Request.Context(), userID, export.Format(req.Format), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	*res = *newGetRes(job)
+
+	c.Status(http.StatusAccepted)
+}
@@ -0,0 +1,53 @@
+package exports
+
+import (
+	"fmt"
+
+	"evm_event_indexer/internal/enum"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/internal/export"
+	"evm_event_indexer/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	DownloadReq struct {
+		Expires   int64  `form:"expires" binding:"required"`
+		Signature string `form:"signature" binding:"required"`
+	}
+)
+
+// Download serves the file of a succeeded export job, authorized by the signature of the link instead of a token
+func Download(c *gin.Context) {
+	var uri = new(GetReq)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req DownloadReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := service.VerifyExportDownload(uri.ExportID, req.Expires, req.Signature); err != nil {
+		c.Error(err)
+		return
+	}
+
+	job, err := service.GetExportJob(c.Request.Context(), 0, uri.ExportID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if job.Status != enum.ExportStatusSucceeded {
+		c.Error(errors.ErrExportNotFound.New("export is not finished"))
+		return
+	}
+
+	c.Header("Content-Type", export.Format(job.Format).ContentType())
+	c.FileAttachment(service.ExportFilePath(job), fmt.Sprintf("logs-%d.%s", job.ID, job.Format))
+}
@@ -0,0 +1,44 @@
+package exports
+
+import (
+	"net/http"
+
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	GetReq struct {
+		ExportID int64 `uri:"export_id" binding:"required,min=1"`
+	}
+)
+
+// Get retrieves an export job of the current user, with the download link once succeeded
+func Get(c *gin.Context) {
+	res := new(GetRes)
+	c.Set(middleware.CtxResponse, res)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req = new(GetReq)
+	if err := c.ShouldBindUri(req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	job, err := service.GetExportJob(c.Request.Context(), userID, req.ExportID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	*res = *newGetRes(job)
+
+	c.Status(http.StatusOK)
+}
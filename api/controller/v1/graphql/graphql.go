@@ -0,0 +1,63 @@
+package graphql
+
+import (
+	"evm_event_indexer/internal/decoder"
+	"evm_event_indexer/internal/errors"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	gql "github.com/graphql-go/graphql"
+)
+
+type QueryReq struct {
+	Query         string         `json:"query" binding:"required"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// schema is built once from the registered decoders
+var (
+	schemaOnce sync.Once
+	schema     gql.Schema
+	schemaErr  error
+)
+
+func getSchema() (gql.Schema, error) {
+	schemaOnce.Do(func() {
+		schema, schemaErr = NewSchema(decoder.Provider)
+	})
+	return schema, schemaErr
+}
+
+// Query executes a graphql query over the indexed event logs,
+// the result is returned as is, following the graphql response format.
+// Queries over the max depth or number of fields are rejected before execution.
+func Query(c *gin.Context) {
+	var req QueryReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := checkQueryLimits(req.Query); err != nil {
+		c.Error(err)
+		return
+	}
+
+	s, err := getSchema()
+	if err != nil {
+		c.Error(errors.ErrInternalServerError.Wrap(err, "failed to build graphql schema"))
+		return
+	}
+
+	result := gql.Do(gql.Params{
+		Schema:         s,
+		RequestString:  req.Query,
+		OperationName:  req.OperationName,
+		VariableValues: req.Variables,
+		Context:        withTxLogLoader(c.Request.Context()),
+	})
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,86 @@
+package graphql_test
+
+import (
+	"evm_event_indexer/api/controller/v1/graphql"
+	"evm_event_indexer/internal/testutil"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func query(t *testing.T, body string) *gin.Context {
+	t.Helper()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/graphql", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	graphql.Query(c)
+	return c
+}
+
+func Test_Query_Limits(t *testing.T) {
+	testutil.SetupTestConfig()
+	gin.SetMode(gin.TestMode)
+
+	// transaction and logs reference each other, the nesting is limited
+	deep := `{"query": "{ transaction(chainId: 1, hash: \"0x1\") { logs { transaction { logs { transaction { logs { transaction { logs { id } } } } } } } } }"}`
+	c := query(t, deep)
+	if assert.Len(t, c.Errors, 1) {
+		assert.Contains(t, c.Errors[0].Error(), "query depth")
+	}
+
+	// fragments are expanded, a fragment cannot spread itself
+	cyclic := `{"query": "{ logs(filter: {chainId: 1}) { nodes { ...f } } } fragment f on Log { transaction { logs { ...f } } }"}`
+	c = query(t, cyclic)
+	if assert.Len(t, c.Errors, 1) {
+		assert.Contains(t, c.Errors[0].Error(), "spreads itself")
+	}
+
+	// aliases multiply the fields of a level
+	var b strings.Builder
+	b.WriteString(`{"query": "{ logs(filter: {chainId: 1}) { nodes { `)
+	for i := range 250 {
+		fmt.Fprintf(&b, "id%d: id ", i)
+	}
+	b.WriteString(`} } }"}`)
+	c = query(t, b.String())
+	if assert.Len(t, c.Errors, 1) {
+		assert.Contains(t, c.Errors[0].Error(), "fields")
+	}
+
+	// introspection is counted like any other field
+	c = query(t, `{"query": "{ __schema { types { name fields { name type { name ofType { name ofType { name ofType { name ofType { name ofType { name } } } } } } } } } }"}`)
+	if assert.Len(t, c.Errors, 1) {
+		assert.Contains(t, c.Errors[0].Error(), "query depth")
+	}
+
+	b.Reset()
+	b.WriteString(`{"query": "{ `)
+	for i := range 100 {
+		fmt.Fprintf(&b, "s%d: __schema { types { name } } ", i)
+	}
+	b.WriteString(`}"}`)
+	c = query(t, b.String())
+	if assert.Len(t, c.Errors, 1) {
+		assert.Contains(t, c.Errors[0].Error(), "fields")
+	}
+
+	// only a bare __typename is not counted
+	b.Reset()
+	b.WriteString(`{"query": "{ __schema { types { name } } logs(filter: {chainId: 1}) { nodes { `)
+	for i := range 250 {
+		fmt.Fprintf(&b, "t%d: __typename ", i)
+	}
+	b.WriteString(`} } }"}`)
+	c = query(t, b.String())
+	for _, err := range c.Errors {
+		assert.NotContains(t, err.Error(), "fields")
+		assert.NotContains(t, err.Error(), "query depth")
+	}
+}
@@ -0,0 +1,107 @@
+package graphql
+
+import (
+	"evm_event_indexer/internal/errors"
+	"fmt"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+const (
+	// max nesting of selection sets, e.g. logs { nodes { transaction { logs { id } } } } has a depth of 5
+	maxQueryDepth = 8
+	// max number of selected fields of a query after expanding the fragments
+	maxQueryFields = 200
+)
+
+// checkQueryLimits rejects queries over the max depth or the max number of fields before they are executed,
+// introspection fields are counted like any other field except a bare __typename.
+// A query that cannot be parsed is left to the executor to report.
+func checkQueryLimits(query string) error {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil
+	}
+
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok && fragment.Name != nil {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		c := &limitCounter{fragments: fragments, visiting: make(map[string]bool)}
+		if err := c.walk(op.SelectionSet, 1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type limitCounter struct {
+	fragments map[string]*ast.FragmentDefinition
+	visiting  map[string]bool // fragments on the current path, cycles are rejected
+	fields    int
+}
+
+func (c *limitCounter) walk(set *ast.SelectionSet, depth int) error {
+	if set == nil {
+		return nil
+	}
+
+	if depth > maxQueryDepth {
+		return errors.ErrApiInvalidParam.New(fmt.Sprintf("query depth should not exceed %d", maxQueryDepth))
+	}
+
+	for _, selection := range set.Selections {
+		switch v := selection.(type) {
+		case *ast.Field:
+			// __typename has no selection set, it only names the type of its parent
+			if v.Name != nil && v.Name.Value == "__typename" && v.SelectionSet == nil {
+				continue
+			}
+
+			c.fields++
+			if c.fields > maxQueryFields {
+				return errors.ErrApiInvalidParam.New(fmt.Sprintf("query should not select more than %d fields", maxQueryFields))
+			}
+
+			if err := c.walk(v.SelectionSet, depth+1); err != nil {
+				return err
+			}
+		case *ast.InlineFragment:
+			if err := c.walk(v.SelectionSet, depth); err != nil {
+				return err
+			}
+		case *ast.FragmentSpread:
+			if v.Name == nil {
+				continue
+			}
+
+			fragment, ok := c.fragments[v.Name.Value]
+			if !ok {
+				continue
+			}
+
+			if c.visiting[fragment.Name.Value] {
+				return errors.ErrApiInvalidParam.New(fmt.Sprintf("fragment %s spreads itself", fragment.Name.Value))
+			}
+
+			c.visiting[fragment.Name.Value] = true
+			if err := c.walk(fragment.SelectionSet, depth); err != nil {
+				return err
+			}
+			c.visiting[fragment.Name.Value] = false
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package graphql
+
+import (
+	"context"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"sync"
+)
+
+// max number of transactions whose logs are loaded by one query
+const txLogsBatchSize = 20
+
+type txKey struct {
+	chainID int64
+	hash    string
+}
+
+type loaderCtxKey struct{}
+
+// txLogLoader batches the log lookups of the transactions of a request. Resolvers queue their transaction and return a thunk,
+// graphql resolves the thunks of a level after every resolver of the level ran, so the first thunk loads the whole level at once.
+type txLogLoader struct {
+	mu      sync.Mutex
+	pending map[int64][]string // queued tx hashes per chain
+	loaded  map[txKey][]*model.Log
+}
+
+func withTxLogLoader(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loaderCtxKey{}, &txLogLoader{
+		pending: make(map[int64][]string),
+		loaded:  make(map[txKey][]*model.Log),
+	})
+}
+
+// getTxLogLoader returns the loader of the request, a new one when the context has none
+func getTxLogLoader(ctx context.Context) *txLogLoader {
+	if l, ok := ctx.Value(loaderCtxKey{}).(*txLogLoader); ok {
+		return l
+	}
+	return getTxLogLoader(withTxLogLoader(ctx))
+}
+
+// prime stores the logs of a transaction loaded by another resolver
+func (l *txLogLoader) prime(chainID int64, hash string, logs []*model.Log) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.loaded[txKey{chainID, hash}] = logs
+}
+
+// queue adds a transaction to the next batch and returns a thunk of its logs
+func (l *txLogLoader) queue(ctx context.Context, chainID int64, hash string) func() (any, error) {
+	key := txKey{chainID, hash}
+
+	l.mu.Lock()
+	if _, ok := l.loaded[key]; !ok {
+		l.pending[chainID] = append(l.pending[chainID], hash)
+	}
+	l.mu.Unlock()
+
+	return func() (any, error) {
+		return l.load(ctx, key)
+	}
+}
+
+func (l *txLogLoader) load(ctx context.Context, key txKey) ([]*model.Log, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if logs, ok := l.loaded[key]; ok {
+		return logs, nil
+	}
+
+	for chainID, hashes := range l.pending {
+		delete(l.pending, chainID)
+
+		for len(hashes) > 0 {
+			batch := uniqueHashes(hashes[:min(len(hashes), txLogsBatchSize)], chainID, l.loaded)
+			hashes = hashes[min(len(hashes), txLogsBatchSize):]
+			if len(batch) == 0 {
+				continue
+			}
+
+			// capped per transaction, a transaction with many logs does not starve the others of the batch
+			logs, err := service.GetTxsLogs(ctx, chainID, batch, maxTxLogs)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, hash := range batch {
+				l.loaded[txKey{chainID, hash}] = nil
+			}
+			for _, log := range logs {
+				k := txKey{chainID, log.TxHash}
+				l.loaded[k] = append(l.loaded[k], log)
+			}
+		}
+	}
+
+	return l.loaded[key], nil
+}
+
+// uniqueHashes drops the duplicated and already loaded hashes
+func uniqueHashes(hashes []string, chainID int64, loaded map[txKey][]*model.Log) []string {
+	res := make([]string, 0, len(hashes))
+	seen := make(map[string]struct{}, len(hashes))
+	for _, hash := range hashes {
+		if _, ok := seen[hash]; ok {
+			continue
+		}
+		if _, ok := loaded[txKey{chainID, hash}]; ok {
+			continue
+		}
+		seen[hash] = struct{}{}
+		res = append(res, hash)
+	}
+	return res
+}
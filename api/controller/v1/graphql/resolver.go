@@ -0,0 +1,327 @@
+package graphql
+
+import (
+	"encoding/hex"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/decoder/provider"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	logRepo "evm_event_indexer/service/repo/eventlog"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	gql "github.com/graphql-go/graphql"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	// max number of logs returned for a transaction
+	maxTxLogs = 1000
+)
+
+// argOps are the filter suffixes of decoded arguments, e.g. valueGte or fromPrefix
+var argOps = []struct {
+	suffix string
+	op     model.ArgOp
+}{
+	{"", model.ArgOpEq},
+	{"Gt", model.ArgOpGt},
+	{"Gte", model.ArgOpGte},
+	{"Lt", model.ArgOpLt},
+	{"Lte", model.ArgOpLte},
+	{"Prefix", model.ArgOpPrefix},
+}
+
+// transaction is derived from its logs, only transactions that emitted indexed logs are known
+type transaction struct {
+	ChainID int64
+	Hash    string
+	Index   int32
+	log     *model.Log
+}
+
+func newTransaction(l *model.Log) *transaction {
+	return &transaction{
+		ChainID: l.ChainID,
+		Hash:    l.TxHash,
+		Index:   l.TxIndex,
+		log:     l,
+	}
+}
+
+func resolveLog(f func(l *model.Log) any) gql.FieldResolveFn {
+	return func(p gql.ResolveParams) (any, error) {
+		l, ok := p.Source.(*model.Log)
+		if !ok || l == nil {
+			return nil, nil
+		}
+		return f(l), nil
+	}
+}
+
+func resolveTx(f func(t *transaction) any) gql.FieldResolveFn {
+	return func(p gql.ResolveParams) (any, error) {
+		t, ok := p.Source.(*transaction)
+		if !ok || t == nil {
+			return nil, nil
+		}
+		return f(t), nil
+	}
+}
+
+// resolveArg returns a decoded argument, addresses decoded from topics are returned as 20 bytes
+func resolveArg(field provider.Field) gql.FieldResolveFn {
+	return resolveLog(func(l *model.Log) any {
+		if l.DecodedEvent == nil {
+			return nil
+		}
+
+		value, ok := l.DecodedEvent.EventData[field.Name]
+		if !ok {
+			return nil
+		}
+
+		if field.Type == provider.FieldAddress {
+			if address, err := field.Type.Normalize(value); err == nil {
+				return address
+			}
+		}
+
+		return value
+	})
+}
+
+func resolveTransaction(p gql.ResolveParams) (any, error) {
+	chainID, _ := p.Args["chainId"].(int)
+	hash, _ := p.Args["hash"].(string)
+
+	logs, err := getTxLogs(p, int64(chainID), hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(logs) == 0 {
+		return nil, nil
+	}
+
+	// the logs field of the transaction reuses the loaded logs
+	getTxLogLoader(p.Context).prime(logs[0].ChainID, logs[0].TxHash, logs)
+
+	return newTransaction(logs[0]), nil
+}
+
+// resolveTxLogs loads the logs of the transactions of a level in batches, instead of one query per transaction
+func resolveTxLogs(p gql.ResolveParams) (any, error) {
+	t, ok := p.Source.(*transaction)
+	if !ok || t == nil {
+		return nil, nil
+	}
+
+	return getTxLogLoader(p.Context).queue(p.Context, t.ChainID, t.Hash), nil
+}
+
+func getTxLogs(p gql.ResolveParams, chainID int64, hash string) ([]*model.Log, error) {
+	hash = strings.TrimSpace(hash)
+	if !isHex32Bytes(hash) {
+		return nil, errors.ErrApiInvalidParam.New("invalid hash, expected 32-byte hex")
+	}
+
+	return service.GetLogsWithoutTotal(p.Context, &logRepo.GetLogParam{
+		ChainID:  chainID,
+		TxHashes: []string{strings.ToLower(hash)},
+		OrderBy:  2,
+		Pagination: &model.Pagination{
+			Page: 1,
+			Size: maxTxLogs,
+		},
+	})
+}
+
+// resolveLogs lists logs, when an event is given only logs of the event are listed
+func resolveLogs(event *provider.Event) gql.FieldResolveFn {
+	return func(p gql.ResolveParams) (any, error) {
+		filter, _ := p.Args["filter"].(map[string]any)
+
+		param, err := newLogParam(filter, event)
+		if err != nil {
+			return nil, err
+		}
+
+		param.OrderBy, _ = p.Args["orderBy"].(int8)
+		param.Desc, _ = p.Args["desc"].(bool)
+
+		first, _ := p.Args["first"].(int)
+		if first < 1 || first > maxPageSize {
+			return nil, errors.ErrApiInvalidParam.New(fmt.Sprintf("first should be between 1 and %d", maxPageSize))
+		}
+		param.Pagination = &model.Pagination{
+			Page: 1,
+			Size: uint64(first),
+		}
+
+		if after, _ := p.Args["after"].(string); after != "" {
+			if param.OrderBy != 2 {
+				return nil, errors.ErrApiInvalidParam.New("after only supports ordering by block number")
+			}
+			if param.ChainID == 0 {
+				return nil, errors.ErrApiInvalidParam.New("chainId is required with after")
+			}
+
+			param.Cursor, err = model.DecodeLogCursor(after)
+			if err != nil {
+				return nil, errors.ErrApiInvalidParam.Wrap(err, "invalid after cursor")
+			}
+		}
+
+		// a query needs a time range, a block range or a tx hash
+		if err := service.CheckLogRange(param); err != nil {
+			return nil, err
+		}
+
+		logs, err := service.GetLogsWithoutTotal(p.Context, param)
+		if err != nil {
+			return nil, err
+		}
+
+		res := map[string]any{
+			"nodes":      logs,
+			"nextCursor": nil,
+		}
+
+		// a full page means there may be more logs after the last one
+		if param.OrderBy == 2 && param.ChainID != 0 && len(logs) == first {
+			res["nextCursor"] = model.NewLogCursor(logs[len(logs)-1]).Encode()
+		}
+
+		return res, nil
+	}
+}
+
+// newLogParam converts a filter input into the logs query
+func newLogParam(filter map[string]any, event *provider.Event) (*logRepo.GetLogParam, error) {
+	param := new(logRepo.GetLogParam)
+
+	if v, ok := filter["chainId"].(int); ok {
+		param.ChainID = int64(v)
+	}
+	if v, ok := filter["blockNumberGte"].(int); ok && v > 0 {
+		param.BlockNumberGTE = uint64(v)
+	}
+	if v, ok := filter["blockNumberLte"].(int); ok && v > 0 {
+		param.BlockNumberLTE = uint64(v)
+	}
+	if v, ok := filter["startTime"].(time.Time); ok {
+		param.StartTime = v
+	}
+	if v, ok := filter["endTime"].(time.Time); ok {
+		param.EndTime = v
+	}
+
+	var err error
+	if param.Addresses, err = listFilter(filter, "address", func(s string) (string, error) {
+		if !common.IsHexAddress(s) {
+			return "", errors.ErrApiInvalidParam.New("invalid address format")
+		}
+		return s, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if param.TxHashes, err = listFilter(filter, "txHash", func(s string) (string, error) {
+		if !isHex32Bytes(s) {
+			return "", errors.ErrApiInvalidParam.New("invalid txHash, expected 32-byte hex")
+		}
+		return strings.ToLower(s), nil
+	}); err != nil {
+		return nil, err
+	}
+
+	topics := []*[]string{&param.Topic0s, &param.Topic1s, &param.Topic2s, &param.Topic3s}
+	for i, topic := range topics {
+		if *topic, err = listFilter(filter, fmt.Sprintf("topic%d", i), normalizeTopic); err != nil {
+			return nil, err
+		}
+	}
+
+	if event == nil {
+		return param, nil
+	}
+
+	param.Topic0s = []string{event.Topic0.Hex()}
+
+	for _, field := range event.Fields {
+		for _, op := range argOps {
+			value, ok := filter[field.Name+op.suffix].(string)
+			if !ok || !field.Type.Supports(op.op) {
+				continue
+			}
+
+			if op.op == model.ArgOpPrefix {
+				value, err = field.Type.NormalizePrefix(value)
+			} else {
+				value, err = field.Type.Normalize(value)
+			}
+			if err != nil {
+				return nil, errors.ErrApiInvalidParam.Wrap(err, fmt.Sprintf("invalid value of %s%s", field.Name, op.suffix))
+			}
+
+			param.Args = append(param.Args, logRepo.ArgFilter{
+				Name:  field.Name,
+				Op:    op.op,
+				Value: value,
+			})
+		}
+	}
+
+	return param, nil
+}
+
+// listFilter normalizes the values of a list filter, empty values are dropped
+func listFilter(filter map[string]any, name string, normalize func(s string) (string, error)) ([]string, error) {
+	values, _ := filter[name].([]any)
+	if len(values) > config.Get().API.MaxFilterValues {
+		return nil, errors.ErrApiInvalidParam.New(fmt.Sprintf("%s should not have more than %d values", name, config.Get().API.MaxFilterValues))
+	}
+
+	res := make([]string, 0, len(values))
+	for _, v := range values {
+		s, _ := v.(string)
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		s, err := normalize(s)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, s)
+	}
+
+	return res, nil
+}
+
+// normalizeTopic accepts an address, padded to 32 bytes, or a 32-byte hex
+func normalizeTopic(s string) (string, error) {
+	if common.IsHexAddress(s) {
+		return common.BytesToHash(common.HexToAddress(s).Bytes()).Hex(), nil
+	}
+
+	if isHex32Bytes(s) {
+		return strings.ToLower(s), nil
+	}
+
+	return "", errors.ErrApiInvalidParam.New("invalid topic format, expected address or 32-byte hex")
+}
+
+func isHex32Bytes(s string) bool {
+	if len(s) != 66 || !strings.HasPrefix(s, "0x") {
+		return false
+	}
+	_, err := hex.DecodeString(s[2:])
+	return err == nil
+}
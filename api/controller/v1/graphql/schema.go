@@ -0,0 +1,281 @@
+package graphql
+
+import (
+	"encoding/hex"
+	"evm_event_indexer/internal/decoder/provider"
+	"evm_event_indexer/service/model"
+	"fmt"
+	"unicode"
+
+	gql "github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// scalars of decoded argument types, all serialized as strings
+var (
+	addressScalar = newStringScalar("Address", "20-byte hex address")
+	bigIntScalar  = newStringScalar("BigInt", "unsigned integer up to uint256, as a decimal string")
+	bytes32Scalar = newStringScalar("Bytes32", "32-byte hex value")
+)
+
+var orderByEnum = gql.NewEnum(gql.EnumConfig{
+	Name: "LogOrderBy",
+	Values: gql.EnumValueConfigMap{
+		"BLOCK_NUMBER":    &gql.EnumValueConfig{Value: int8(2)},
+		"BLOCK_TIMESTAMP": &gql.EnumValueConfig{Value: int8(1)},
+	},
+})
+
+// NewSchema builds the graphql schema, one query field and type per registered event.
+func NewSchema(p *provider.DecoderProvider) (gql.Schema, error) {
+	blockType := gql.NewObject(gql.ObjectConfig{
+		Name:        "Block",
+		Description: "block of an event log",
+		Fields: gql.Fields{
+			"number":        &gql.Field{Type: gql.Int, Resolve: resolveLog(func(l *model.Log) any { return l.BlockNumber })},
+			"hash":          &gql.Field{Type: gql.String, Resolve: resolveLog(func(l *model.Log) any { return l.BlockHash })},
+			"timestamp":     &gql.Field{Type: gql.DateTime, Resolve: resolveLog(func(l *model.Log) any { return l.BlockTimestamp })},
+			"l1BlockNumber": &gql.Field{Type: gql.Int, Description: "L1 origin of L2 chains", Resolve: resolveLog(func(l *model.Log) any { return nullable(l.L1BlockNumber) })},
+			"l1BlockHash":   &gql.Field{Type: gql.String, Description: "L1 origin of OP Stack chains", Resolve: resolveLog(func(l *model.Log) any { return nullable(l.L1BlockHash) })},
+		},
+	})
+
+	// log and transaction reference each other, fields are added once both exist
+	logType := gql.NewObject(gql.ObjectConfig{
+		Name:        "Log",
+		Description: "indexed event log",
+		Fields:      gql.Fields{},
+	})
+
+	txType := gql.NewObject(gql.ObjectConfig{
+		Name:        "Transaction",
+		Description: "transaction that emitted indexed event logs",
+		Fields: gql.Fields{
+			"chainId": &gql.Field{Type: gql.Int, Resolve: resolveTx(func(t *transaction) any { return t.ChainID })},
+			"hash":    &gql.Field{Type: gql.String, Resolve: resolveTx(func(t *transaction) any { return t.Hash })},
+			"index":   &gql.Field{Type: gql.Int, Resolve: resolveTx(func(t *transaction) any { return t.Index })},
+			"block":   &gql.Field{Type: blockType, Resolve: resolveTx(func(t *transaction) any { return t.log })},
+			"logs":    &gql.Field{Type: gql.NewList(logType), Resolve: resolveTxLogs},
+		},
+	})
+
+	for name, field := range logFields(blockType, txType) {
+		logType.AddFieldConfig(name, field)
+	}
+	logType.AddFieldConfig("eventName", &gql.Field{Type: gql.String, Resolve: resolveLog(func(l *model.Log) any {
+		if l.DecodedEvent == nil {
+			return nil
+		}
+		return nullable(l.DecodedEvent.EventName)
+	})})
+	logType.AddFieldConfig("topics", &gql.Field{Type: gql.NewList(gql.String), Resolve: resolveLog(func(l *model.Log) any { return topics(l) })})
+	logType.AddFieldConfig("data", &gql.Field{Type: gql.String, Resolve: resolveLog(func(l *model.Log) any { return hexData(l.Data) })})
+
+	logFilter := gql.NewInputObject(gql.InputObjectConfig{
+		Name:   "LogFilter",
+		Fields: withTopicFilters(commonFilterFields()),
+	})
+
+	query := gql.Fields{
+		"logs": &gql.Field{
+			Type:        connectionType("Log", logType),
+			Description: "event logs of any event",
+			Args:        listArgs(logFilter),
+			Resolve:     resolveLogs(nil),
+		},
+		"transaction": &gql.Field{
+			Type:        txType,
+			Description: "transaction with its indexed event logs",
+			Args: gql.FieldConfigArgument{
+				"chainId": &gql.ArgumentConfig{Type: gql.NewNonNull(gql.Int)},
+				"hash":    &gql.ArgumentConfig{Type: gql.NewNonNull(gql.String)},
+			},
+			Resolve: resolveTransaction,
+		},
+	}
+
+	for _, event := range p.Events() {
+		if _, ok := query[queryName(event.Name)]; ok {
+			return gql.Schema{}, fmt.Errorf("duplicated graphql query field of event %s", event.Name)
+		}
+
+		argFields := gql.Fields{}
+		for _, field := range event.Fields {
+			argFields[field.Name] = &gql.Field{Type: scalarOf(field.Type), Resolve: resolveArg(field)}
+		}
+
+		argsType := gql.NewObject(gql.ObjectConfig{
+			Name:   event.Name + "Args",
+			Fields: argFields,
+		})
+
+		fields := logFields(blockType, txType)
+		fields["args"] = &gql.Field{Type: argsType, Resolve: resolveLog(func(l *model.Log) any { return l })}
+		eventType := gql.NewObject(gql.ObjectConfig{
+			Name:        event.Name,
+			Description: fmt.Sprintf("decoded %s event log", event.Name),
+			Fields:      fields,
+		})
+
+		filterFields, err := withArgFilters(commonFilterFields(), event.Fields)
+		if err != nil {
+			return gql.Schema{}, fmt.Errorf("event %s: %w", event.Name, err)
+		}
+
+		filter := gql.NewInputObject(gql.InputObjectConfig{
+			Name:   event.Name + "Filter",
+			Fields: filterFields,
+		})
+
+		query[queryName(event.Name)] = &gql.Field{
+			Type:        connectionType(event.Name, eventType),
+			Description: fmt.Sprintf("decoded %s event logs", event.Name),
+			Args:        listArgs(filter),
+			Resolve:     resolveLogs(&event),
+		}
+	}
+
+	return gql.NewSchema(gql.SchemaConfig{
+		Query: gql.NewObject(gql.ObjectConfig{
+			Name:   "Query",
+			Fields: query,
+		}),
+	})
+}
+
+// logFields are the fields shared by the log type and every event type
+func logFields(blockType *gql.Object, txType *gql.Object) gql.Fields {
+	return gql.Fields{
+		"id":          &gql.Field{Type: gql.ID, Resolve: resolveLog(func(l *model.Log) any { return l.ID })},
+		"chainId":     &gql.Field{Type: gql.Int, Resolve: resolveLog(func(l *model.Log) any { return l.ChainID })},
+		"address":     &gql.Field{Type: addressScalar, Resolve: resolveLog(func(l *model.Log) any { return l.Address })},
+		"blockNumber": &gql.Field{Type: gql.Int, Resolve: resolveLog(func(l *model.Log) any { return l.BlockNumber })},
+		"txHash":      &gql.Field{Type: gql.String, Resolve: resolveLog(func(l *model.Log) any { return l.TxHash })},
+		"txIndex":     &gql.Field{Type: gql.Int, Resolve: resolveLog(func(l *model.Log) any { return l.TxIndex })},
+		"logIndex":    &gql.Field{Type: gql.Int, Resolve: resolveLog(func(l *model.Log) any { return l.LogIndex })},
+		"confirmed":   &gql.Field{Type: gql.Boolean, Resolve: resolveLog(func(l *model.Log) any { return l.Confirmed })},
+		"block":       &gql.Field{Type: blockType, Resolve: resolveLog(func(l *model.Log) any { return l })},
+		"transaction": &gql.Field{Type: txType, Resolve: resolveLog(func(l *model.Log) any { return newTransaction(l) })},
+	}
+}
+
+func commonFilterFields() gql.InputObjectConfigFieldMap {
+	return gql.InputObjectConfigFieldMap{
+		"chainId":        &gql.InputObjectFieldConfig{Type: gql.Int},
+		"address":        &gql.InputObjectFieldConfig{Type: gql.NewList(gql.String)},
+		"txHash":         &gql.InputObjectFieldConfig{Type: gql.NewList(gql.String)},
+		"blockNumberGte": &gql.InputObjectFieldConfig{Type: gql.Int},
+		"blockNumberLte": &gql.InputObjectFieldConfig{Type: gql.Int},
+		"startTime":      &gql.InputObjectFieldConfig{Type: gql.DateTime, Description: "RFC3339"},
+		"endTime":        &gql.InputObjectFieldConfig{Type: gql.DateTime, Description: "RFC3339"},
+	}
+}
+
+func withTopicFilters(fields gql.InputObjectConfigFieldMap) gql.InputObjectConfigFieldMap {
+	for i := range 4 {
+		fields[fmt.Sprintf("topic%d", i)] = &gql.InputObjectFieldConfig{Type: gql.NewList(gql.String)}
+	}
+	return fields
+}
+
+// withArgFilters adds a filter per queryable field and supported operator, e.g. value, valueGte, fromPrefix
+func withArgFilters(fields gql.InputObjectConfigFieldMap, eventFields []provider.Field) (gql.InputObjectConfigFieldMap, error) {
+	for _, field := range eventFields {
+		for _, op := range argOps {
+			if !field.Type.Supports(op.op) {
+				continue
+			}
+
+			name := field.Name + op.suffix
+			if _, ok := fields[name]; ok {
+				return nil, fmt.Errorf("duplicated graphql filter %s", name)
+			}
+			fields[name] = &gql.InputObjectFieldConfig{Type: gql.String}
+		}
+	}
+	return fields, nil
+}
+
+func listArgs(filter *gql.InputObject) gql.FieldConfigArgument {
+	return gql.FieldConfigArgument{
+		"filter":  &gql.ArgumentConfig{Type: gql.NewNonNull(filter)},
+		"orderBy": &gql.ArgumentConfig{Type: orderByEnum, DefaultValue: int8(2)},
+		"desc":    &gql.ArgumentConfig{Type: gql.Boolean, DefaultValue: false},
+		"first":   &gql.ArgumentConfig{Type: gql.Int, DefaultValue: defaultPageSize},
+		"after":   &gql.ArgumentConfig{Type: gql.String, Description: "nextCursor of the previous page"},
+	}
+}
+
+func connectionType(name string, nodeType *gql.Object) *gql.Object {
+	return gql.NewObject(gql.ObjectConfig{
+		Name: name + "Connection",
+		Fields: gql.Fields{
+			"nodes":      &gql.Field{Type: gql.NewList(nodeType)},
+			"nextCursor": &gql.Field{Type: gql.String, Description: "cursor of the next page, only when ordered by block number within a chain"},
+		},
+	})
+}
+
+func scalarOf(t provider.FieldType) *gql.Scalar {
+	switch t {
+	case provider.FieldAddress:
+		return addressScalar
+	case provider.FieldUint256:
+		return bigIntScalar
+	default:
+		return bytes32Scalar
+	}
+}
+
+func newStringScalar(name string, description string) *gql.Scalar {
+	serialize := func(value any) any {
+		if s, ok := value.(string); ok {
+			return s
+		}
+		return nil
+	}
+
+	return gql.NewScalar(gql.ScalarConfig{
+		Name:        name,
+		Description: description,
+		Serialize:   serialize,
+		ParseValue:  serialize,
+		ParseLiteral: func(valueAST ast.Value) any {
+			if v, ok := valueAST.(*ast.StringValue); ok {
+				return v.Value
+			}
+			return nil
+		},
+	})
+}
+
+// queryName is the query field of an event, e.g. Transfer -> transfers
+func queryName(event string) string {
+	r := []rune(event)
+	r[0] = unicode.ToLower(r[0])
+	return string(r) + "s"
+}
+
+// topics returns the non empty topics of a log
+func topics(l *model.Log) []string {
+	res := make([]string, 0, 4)
+	for _, topic := range []string{l.Topic0, l.Topic1, l.Topic2, l.Topic3} {
+		if topic != "" {
+			res = append(res, topic)
+		}
+	}
+	return res
+}
+
+// nullable returns nil for zero values, so optional fields resolve to null
+func nullable[T comparable](v T) any {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return v
+}
+
+func hexData(data []byte) string {
+	return "0x" + hex.EncodeToString(data)
+}
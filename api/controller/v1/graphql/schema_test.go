@@ -0,0 +1,69 @@
+package graphql_test
+
+import (
+	"context"
+	"evm_event_indexer/api/controller/v1/graphql"
+	"evm_event_indexer/internal/decoder/erc20"
+	"evm_event_indexer/internal/decoder/provider"
+	"evm_event_indexer/internal/testutil"
+	"testing"
+
+	gql "github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewSchema(t *testing.T) {
+	testutil.SetupTestConfig()
+
+	p := provider.NewDecoderProvider()
+	p.Register("Transfer(address,address,uint256)", &erc20.TransferDecoder{})
+	p.Register("Approval(address,address,uint256)", &erc20.ApprovalDecoder{})
+
+	schema, err := graphql.NewSchema(p)
+	assert.NoError(t, err)
+
+	query := schema.QueryType().Fields()
+	assert.Contains(t, query, "logs")
+	assert.Contains(t, query, "transaction")
+	assert.Contains(t, query, "transfers")
+	assert.Contains(t, query, "approvals")
+
+	// one typed filter per supported operator
+	filter, ok := schema.Type("TransferFilter").(*gql.InputObject)
+	assert.True(t, ok)
+	for _, name := range []string{"from", "fromPrefix", "to", "value", "valueGte", "valueLt"} {
+		assert.Contains(t, filter.Fields(), name)
+	}
+	assert.NotContains(t, filter.Fields(), "fromGte")
+	assert.NotContains(t, filter.Fields(), "valuePrefix")
+
+	args, ok := schema.Type("TransferArgs").(*gql.Object)
+	assert.True(t, ok)
+	assert.Equal(t, "Address", args.Fields()["from"].Type.Name())
+	assert.Equal(t, "BigInt", args.Fields()["value"].Type.Name())
+
+	// unknown fields are rejected by validation
+	res := gql.Do(gql.Params{
+		Schema:        schema,
+		RequestString: `{ transfers(filter: {chainId: 1, amount: "1"}) { nodes { txHash } } }`,
+		Context:       context.TODO(),
+	})
+	assert.NotEmpty(t, res.Errors)
+
+	// a query without a time range, block range or tx hash is rejected before reaching the database
+	res = gql.Do(gql.Params{
+		Schema:        schema,
+		RequestString: `{ transfers(filter: {chainId: 1, value: "1"}) { nodes { txHash args { from value } } } }`,
+		Context:       context.TODO(),
+	})
+	if assert.Len(t, res.Errors, 1) {
+		assert.Contains(t, res.Errors[0].Message, "time range, block range or tx hash")
+	}
+
+	res = gql.Do(gql.Params{
+		Schema:        schema,
+		RequestString: `{ logs(filter: {chainId: 1, blockNumberGte: 1, blockNumberLte: 2}, first: 1000) { nodes { id } } }`,
+		Context:       context.TODO(),
+	})
+	assert.NotEmpty(t, res.Errors)
+}
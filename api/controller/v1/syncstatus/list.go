@@ -0,0 +1,78 @@
+package syncstatus
+
+import (
+	"net/http"
+	"time"
+
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/internal/workerstate"
+	"evm_event_indexer/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	ListReq struct {
+		ChainID int64 `form:"chain_id" binding:"omitempty,min=1"` // 0 means all chains
+	}
+
+	ListRes struct {
+		Contracts []*SyncStatus `json:"contracts"`
+	}
+
+	SyncStatus struct {
+		ChainID        int64             `json:"chain_id"`
+		Chain          string            `json:"chain,omitempty"` // omitted when the chain is no longer configured
+		Address        string            `json:"address"`
+		LastSyncNumber uint64            `json:"last_sync_number"` // 0 until the first batch is synced
+		LastSyncHash   string            `json:"last_sync_hash"`
+		UpdatedAt      time.Time         `json:"updated_at,omitzero"`
+		HeadNumber     uint64            `json:"head_number"` // 0 until a scanner of the chain reads the head
+		HeadUpdatedAt  time.Time         `json:"head_updated_at,omitzero"`
+		LagBlocks      *uint64           `json:"lag_blocks"`  // null until the head is read
+		LagSeconds     *int64            `json:"lag_seconds"` // age of the last synced block, null until a batch is synced since the start
+		State          workerstate.State `json:"state"`       // starting, syncing, synced, failing, stopped or inactive
+		LastError      string            `json:"last_error,omitempty"`
+		LastErrorAt    time.Time         `json:"last_error_at,omitzero"`
+	}
+)
+
+// List returns the sync progress of every indexed contract, so clients can tell whether the latest results are complete
+func List(c *gin.Context) {
+	res := new(ListRes)
+	res.Contracts = make([]*SyncStatus, 0)
+	c.Set(middleware.CtxResponse, res)
+
+	var req ListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	statuses, err := service.GetSyncStatus(c.Request.Context(), req.ChainID, time.Now())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res.Contracts = make([]*SyncStatus, len(statuses))
+	for i, status := range statuses {
+		res.Contracts[i] = &SyncStatus{
+			ChainID:        status.ChainID,
+			Chain:          status.Chain,
+			Address:        status.Address,
+			LastSyncNumber: status.LastSyncNumber,
+			LastSyncHash:   status.LastSyncHash,
+			UpdatedAt:      status.UpdatedAt,
+			HeadNumber:     status.HeadNumber,
+			HeadUpdatedAt:  status.HeadUpdatedAt,
+			LagBlocks:      status.LagBlocks,
+			LagSeconds:     status.LagSeconds,
+			State:          status.State,
+			LastError:      status.LastError,
+			LastErrorAt:    status.LastErrorAt,
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,103 @@
+package tokens
+
+import (
+	"net/http"
+	"time"
+
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/tokenallowance"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	ListAllowanceUriReq struct {
+		Owner string `uri:"owner" binding:"required"`
+	}
+
+	ListAllowanceReq struct {
+		ChainID   int64  `form:"chain_id" binding:"required,min=1"`
+		Page      uint64 `form:"page" binding:"required,min=1"`
+		Size      uint64 `form:"size" binding:"required,min=1,max=100"`
+		Token     string `form:"token" binding:"omitempty"`
+		Unlimited bool   `form:"unlimited" binding:"omitempty"` // only unlimited approvals
+	}
+
+	ListAllowanceRes struct {
+		Allowances []*Allowance `json:"allowances"`
+		Total      int64        `json:"total"`
+	}
+
+	Allowance struct {
+		Token       string    `json:"token"`
+		Spender     string    `json:"spender"`
+		Allowance   string    `json:"allowance"` // decimal, in the smallest unit of the token
+		Unlimited   bool      `json:"unlimited"`
+		BlockNumber uint64    `json:"block_number"` // block of the latest Approval
+		TxHash      string    `json:"tx_hash"`      // tx of the latest Approval
+		UpdatedAt   time.Time `json:"updated_at"`
+	}
+)
+
+// ListAllowance lists the outstanding approvals of an owner derived from the Approval events, latest first
+func ListAllowance(c *gin.Context) {
+	res := &ListAllowanceRes{
+		Allowances: make([]*Allowance, 0),
+	}
+	c.Set(middleware.CtxResponse, res)
+
+	var uri = new(ListAllowanceUriReq)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req ListAllowanceReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	owner, err := normalizeAddress("owner", uri.Owner)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var token string
+	if req.Token != "" {
+		if token, err = normalizeAddress("token", req.Token); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	allowances, total, err := service.GetTokenAllowancesWithTotal(c.Request.Context(), &tokenallowance.GetAllowanceFilter{
+		ChainID:       req.ChainID,
+		Owner:         owner,
+		Token:         token,
+		UnlimitedOnly: req.Unlimited,
+		Pagination:    &model.Pagination{Page: req.Page, Size: req.Size},
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res.Total = total
+	for _, v := range allowances {
+		res.Allowances = append(res.Allowances, &Allowance{
+			Token:       v.Token,
+			Spender:     v.Spender,
+			Allowance:   v.Allowance.String(),
+			Unlimited:   v.Unlimited(),
+			BlockNumber: v.BlockNumber,
+			TxHash:      v.TxHash,
+			UpdatedAt:   v.UpdatedAt,
+		})
+	}
+
+	c.Status(http.StatusOK)
+}
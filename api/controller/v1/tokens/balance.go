@@ -0,0 +1,83 @@
+package tokens
+
+import (
+	"net/http"
+
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	GetBalanceUriReq struct {
+		Token  string `uri:"token" binding:"required"`
+		Holder string `uri:"holder" binding:"required"`
+	}
+
+	GetBalanceReq struct {
+		ChainID     int64  `form:"chain_id" binding:"required,min=1"`
+		BlockNumber uint64 `form:"block_number" binding:"omitempty"` // balance at the end of the block, 0 means the current balance
+	}
+
+	GetBalanceRes struct {
+		ChainID     int64  `json:"chain_id"`
+		Token       string `json:"token"`
+		Holder      string `json:"holder"`
+		Balance     string `json:"balance"`      // decimal, in the smallest unit of the token
+		BlockNumber uint64 `json:"block_number"` // block of the last balance change, 0 if the holder never held the token
+		SyncedBlock uint64 `json:"synced_block"` // last synced block of the token
+	}
+)
+
+// GetBalance returns the balance of a holder derived from the Transfer events, currently or at a block
+func GetBalance(c *gin.Context) {
+	res := new(GetBalanceRes)
+	c.Set(middleware.CtxResponse, res)
+
+	var uri = new(GetBalanceUriReq)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req GetBalanceReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	token, err := normalizeAddress("token", uri.Token)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	holder, err := normalizeAddress("holder", uri.Holder)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var balance *model.TokenBalance
+	var synced uint64
+	if req.BlockNumber > 0 {
+		balance, synced, err = service.GetTokenBalanceAt(c.Request.Context(), req.ChainID, token, holder, req.BlockNumber)
+	} else {
+		balance, synced, err = service.GetTokenBalance(c.Request.Context(), req.ChainID, token, holder)
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res.ChainID = req.ChainID
+	res.Token = token
+	res.Holder = holder
+	res.Balance = balance.Balance.String()
+	res.BlockNumber = balance.BlockNumber
+	res.SyncedBlock = synced
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,16 @@
+package tokens
+
+import (
+	"evm_event_indexer/internal/errors"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// normalizeAddress validates an address and converts it into the lowercase form holders are stored in
+func normalizeAddress(name string, address string) (string, error) {
+	if !common.IsHexAddress(address) {
+		return "", errors.ErrApiInvalidParam.New("invalid " + name + ": " + address)
+	}
+	return strings.ToLower(common.HexToAddress(address).Hex()), nil
+}
@@ -0,0 +1,82 @@
+package tokens
+
+import (
+	"net/http"
+
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/tokenbalance"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	ListHolderUriReq struct {
+		Token string `uri:"token" binding:"required"`
+	}
+
+	ListHolderReq struct {
+		ChainID int64  `form:"chain_id" binding:"required,min=1"`
+		Page    uint64 `form:"page" binding:"required,min=1"`
+		Size    uint64 `form:"size" binding:"required,min=1,max=100"`
+	}
+
+	ListHolderRes struct {
+		Holders []*Holder `json:"holders"`
+		Total   int64     `json:"total"`
+	}
+
+	Holder struct {
+		Holder      string `json:"holder"`
+		Balance     string `json:"balance"`
+		BlockNumber uint64 `json:"block_number"` // block of the last balance change
+	}
+)
+
+// ListHolder lists the holders of a token with a positive balance, largest first
+func ListHolder(c *gin.Context) {
+	res := &ListHolderRes{
+		Holders: make([]*Holder, 0),
+	}
+	c.Set(middleware.CtxResponse, res)
+
+	var uri = new(ListHolderUriReq)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req ListHolderReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	token, err := normalizeAddress("token", uri.Token)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	holders, total, err := service.GetTokenHoldersWithTotal(c.Request.Context(), &tokenbalance.GetHolderFilter{
+		ChainID:    req.ChainID,
+		Token:      token,
+		Pagination: &model.Pagination{Page: req.Page, Size: req.Size},
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res.Total = total
+	for _, v := range holders {
+		res.Holders = append(res.Holders, &Holder{
+			Holder:      v.Holder,
+			Balance:     v.Balance.String(),
+			BlockNumber: v.BlockNumber,
+		})
+	}
+
+	c.Status(http.StatusOK)
+}
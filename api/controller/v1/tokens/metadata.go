@@ -0,0 +1,71 @@
+package tokens
+
+import (
+	"net/http"
+	"time"
+
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	GetMetadataUriReq struct {
+		Token string `uri:"token" binding:"required"`
+	}
+
+	GetMetadataReq struct {
+		ChainID int64 `form:"chain_id" binding:"required,min=1"`
+	}
+
+	GetMetadataRes struct {
+		ChainID     int64     `json:"chain_id"`
+		Token       string    `json:"token"`
+		Name        string    `json:"name"`   // empty if the token does not implement name()
+		Symbol      string    `json:"symbol"` // empty if the token does not implement symbol()
+		Decimals    uint8     `json:"decimals"`
+		TotalSupply string    `json:"total_supply"` // decimal, in the smallest unit of the token
+		UpdatedAt   time.Time `json:"updated_at"`   // last refresh of the total supply
+	}
+)
+
+// GetMetadata returns the cached metadata of a token
+func GetMetadata(c *gin.Context) {
+	res := new(GetMetadataRes)
+	c.Set(middleware.CtxResponse, res)
+
+	var uri = new(GetMetadataUriReq)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req GetMetadataReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	token, err := normalizeAddress("token", uri.Token)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	metadata, err := service.GetTokenMetadata(c.Request.Context(), req.ChainID, token)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res.ChainID = metadata.ChainID
+	res.Token = metadata.Token
+	res.Name = metadata.Name
+	res.Symbol = metadata.Symbol
+	res.Decimals = metadata.Decimals
+	res.TotalSupply = metadata.TotalSupply.String()
+	res.UpdatedAt = metadata.UpdatedAt
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,63 @@
+package webhooks
+
+import (
+	"evm_event_indexer/api/controller/v1/contracts"
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/internal/enum"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	// FilterReq selects the delivered logs, with the same filters as the logs query except tx_hash
+	FilterReq struct {
+		contracts.LogFilter
+		Decoded map[string]string `json:"decoded"` // decoded argument filters, field to op:value, e.g. {"value": "gte:1e18"}
+	}
+
+	GetRes struct {
+		ID        int64                `json:"id"`
+		URL       string               `json:"url"`
+		Filter    *model.WebhookFilter `json:"filter"`
+		Status    enum.WebhookStatus   `json:"status"`
+		CreatedAt time.Time            `json:"created_at"`
+		UpdatedAt time.Time            `json:"updated_at"`
+	}
+)
+
+func getUserID(c *gin.Context) (int64, error) {
+	userID := c.GetInt64(middleware.CtxUserID)
+	if userID <= 0 {
+		return 0, errors.ErrInvalidCredentials.New("user id not found")
+	}
+	return userID, nil
+}
+
+// toFilter validates and normalizes the filter
+func (r *FilterReq) toFilter() (*model.WebhookFilter, error) {
+	if len(r.TxHash) > 0 {
+		return nil, errors.ErrApiInvalidParam.New("tx_hash is not supported by webhooks")
+	}
+
+	param, err := r.LogFilter.ToParam(r.Decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.NewWebhookFilter(param), nil
+}
+
+func newGetRes(webhook *model.Webhook) *GetRes {
+	return &GetRes{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Filter:    webhook.Filter,
+		Status:    webhook.Status,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+	}
+}
@@ -0,0 +1,52 @@
+package webhooks
+
+import (
+	"net/http"
+
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	CreateReq struct {
+		URL    string    `json:"url" binding:"required,url,max=2048"`
+		Secret string    `json:"secret" binding:"required,min=16,max=256"` // HMAC key of the payload signature, never returned
+		Filter FilterReq `json:"filter"`
+	}
+)
+
+// Create registers a webhook of the current user
+func Create(c *gin.Context) {
+	res := new(GetRes)
+	c.Set(middleware.CtxResponse, res)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req CreateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	filter, err := req.Filter.toFilter()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	webhook, err := service.CreateWebhook(c.Request.Context(), userID, req.URL, req.Secret, filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	*res = *newGetRes(webhook)
+
+	c.Status(http.StatusCreated)
+}
@@ -0,0 +1,38 @@
+package webhooks
+
+import (
+	"net/http"
+
+	"evm_event_indexer/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	DeleteReq struct {
+		WebhookID int64 `uri:"webhook_id" binding:"required,min=1"`
+	}
+)
+
+// Delete deletes a webhook of the current user with its delivery log
+func Delete(c *gin.Context) {
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req = new(DeleteReq)
+	if err := c.ShouldBindUri(req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := service.DeleteWebhook(c.Request.Context(), userID, req.WebhookID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,102 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/internal/enum"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	webhookRepo "evm_event_indexer/service/repo/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	ListDeliveryUriReq struct {
+		WebhookID int64 `uri:"webhook_id" binding:"required,min=1"`
+	}
+
+	ListDeliveryReq struct {
+		Page   uint64 `form:"page" binding:"required,min=1"`
+		Size   uint64 `form:"size" binding:"required,min=1,max=100"`
+		Status int8   `form:"status" binding:"omitempty,oneof=1 2 3 4"` // 1: pending, 2: succeeded, 3: failed, 4: cancelled
+	}
+
+	ListDeliveryRes struct {
+		Deliveries []*Delivery `json:"deliveries"`
+		Total      int64       `json:"total"`
+	}
+
+	Delivery struct {
+		ID             int64               `json:"id"`
+		Type           string              `json:"type"`
+		Payload        json.RawMessage     `json:"payload"`
+		Status         enum.DeliveryStatus `json:"status"`
+		Attempts       int32               `json:"attempts"`
+		NextAttemptAt  *time.Time          `json:"next_attempt_at,omitempty"` // only for pending deliveries
+		LastStatusCode int32               `json:"last_status_code"`
+		LastError      string              `json:"last_error"`
+		CreatedAt      time.Time           `json:"created_at"`
+		UpdatedAt      time.Time           `json:"updated_at"`
+	}
+)
+
+// ListDelivery lists the delivery log of a webhook of the current user, latest first
+func ListDelivery(c *gin.Context) {
+	res := &ListDeliveryRes{
+		Deliveries: make([]*Delivery, 0),
+	}
+	c.Set(middleware.CtxResponse, res)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var uri = new(ListDeliveryUriReq)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req ListDeliveryReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	deliveries, total, err := service.GetWebhookDeliveriesWithTotal(c.Request.Context(), userID, &webhookRepo.GetDeliveryFilter{
+		WebhookID:  uri.WebhookID,
+		Status:     enum.DeliveryStatus(req.Status),
+		Pagination: &model.Pagination{Page: req.Page, Size: req.Size},
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res.Total = total
+	for _, v := range deliveries {
+		delivery := &Delivery{
+			ID:             v.ID,
+			Type:           v.Type,
+			Payload:        v.Payload,
+			Status:         v.Status,
+			Attempts:       v.Attempts,
+			LastStatusCode: v.LastStatusCode,
+			LastError:      v.LastError,
+			CreatedAt:      v.CreatedAt,
+			UpdatedAt:      v.UpdatedAt,
+		}
+		if v.Status == enum.DeliveryStatusPending {
+			delivery.NextAttemptAt = &v.NextAttemptAt
+		}
+		res.Deliveries = append(res.Deliveries, delivery)
+	}
+
+	c.Status(http.StatusOK)
+}
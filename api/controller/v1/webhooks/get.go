@@ -0,0 +1,44 @@
+package webhooks
+
+import (
+	"net/http"
+
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	GetReq struct {
+		WebhookID int64 `uri:"webhook_id" binding:"required,min=1"`
+	}
+)
+
+// Get retrieves a webhook of the current user
+func Get(c *gin.Context) {
+	res := new(GetRes)
+	c.Set(middleware.CtxResponse, res)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req = new(GetReq)
+	if err := c.ShouldBindUri(req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	webhook, err := service.GetWebhook(c.Request.Context(), userID, req.WebhookID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	*res = *newGetRes(webhook)
+
+	c.Status(http.StatusOK)
+}
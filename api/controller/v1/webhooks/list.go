@@ -0,0 +1,56 @@
+package webhooks
+
+import (
+	"net/http"
+
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	ListReq struct {
+		Page uint64 `form:"page" binding:"required,min=1"`
+		Size uint64 `form:"size" binding:"required,min=1,max=100"`
+	}
+
+	ListRes struct {
+		Webhooks []*GetRes `json:"webhooks"`
+		Total    int64     `json:"total"`
+	}
+)
+
+// List lists the webhooks of the current user
+func List(c *gin.Context) {
+	res := &ListRes{
+		Webhooks: make([]*GetRes, 0),
+	}
+	c.Set(middleware.CtxResponse, res)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req ListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	webhooks, total, err := service.GetWebhooksWithTotal(c.Request.Context(), userID, &model.Pagination{Page: req.Page, Size: req.Size})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res.Total = total
+	for _, webhook := range webhooks {
+		res.Webhooks = append(res.Webhooks, newGetRes(webhook))
+	}
+
+	c.Status(http.StatusOK)
+}
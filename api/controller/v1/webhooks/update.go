@@ -0,0 +1,75 @@
+package webhooks
+
+import (
+	"net/http"
+
+	"evm_event_indexer/api/middleware"
+	"evm_event_indexer/internal/enum"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	UpdateUriReq struct {
+		WebhookID int64 `uri:"webhook_id" binding:"required,min=1"`
+	}
+
+	// UpdateReq updates the given fields only
+	UpdateReq struct {
+		URL    string     `json:"url" binding:"omitempty,url,max=2048"`
+		Secret string     `json:"secret" binding:"omitempty,min=16,max=256"`
+		Filter *FilterReq `json:"filter" binding:"omitempty"`
+		Status int8       `json:"status" binding:"omitempty,oneof=1 2"` // 1: enabled, 2: disabled
+	}
+)
+
+// Update updates a webhook of the current user
+func Update(c *gin.Context) {
+	res := new(GetRes)
+	c.Set(middleware.CtxResponse, res)
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var uri = new(UpdateUriReq)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req UpdateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	webhook := &model.Webhook{
+		ID:     uri.WebhookID,
+		UserID: userID,
+		URL:    req.URL,
+		Secret: req.Secret,
+		Status: enum.WebhookStatus(req.Status),
+	}
+
+	if req.Filter != nil {
+		if webhook.Filter, err = req.Filter.toFilter(); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	webhook, err = service.UpdateWebhook(c.Request.Context(), webhook)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	*res = *newGetRes(webhook)
+
+	c.Status(http.StatusOK)
+}
@@ -22,8 +22,20 @@ func (w bodyLogWriter) Body() *bytes.Buffer {
 	return w.body
 }
 
-func Interceptor() gin.HandlerFunc {
+// Interceptor keeps a copy of the response body, streaming routes are given as skip paths
+// so a long lived response is not buffered in memory.
+func Interceptor(skipPaths ...string) gin.HandlerFunc {
+	skip := make(map[string]struct{}, len(skipPaths))
+	for _, path := range skipPaths {
+		skip[path] = struct{}{}
+	}
+
 	return func(c *gin.Context) {
+		if _, ok := skip[c.FullPath()]; ok {
+			c.Next()
+			return
+		}
+
 		blw := &bodyLogWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
 		c.Writer = blw
 
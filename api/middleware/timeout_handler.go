@@ -10,12 +10,26 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// api timeout handler
-func TimeoutHandler() gin.HandlerFunc {
-	return timeout.New(
+// api timeout handler, long lived streaming routes are given as skip paths,
+// the timeout buffers the response and would hold back every streamed message.
+func TimeoutHandler(skipPaths ...string) gin.HandlerFunc {
+	handler := timeout.New(
 		timeout.WithTimeout(config.Get().API.Timeout),
 		timeout.WithResponse(timeoutRes),
 	)
+
+	skip := make(map[string]struct{}, len(skipPaths))
+	for _, path := range skipPaths {
+		skip[path] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		if _, ok := skip[c.FullPath()]; ok {
+			c.Next()
+			return
+		}
+		handler(c)
+	}
 }
 
 func timeoutRes(c *gin.Context) {
@@ -4,16 +4,30 @@ import (
 	"net/http"
 
 	adminAuthController "evm_event_indexer/api/controller/v1/admin/auth"
+	adminTokensController "evm_event_indexer/api/controller/v1/admin/tokens"
 	adminUsersController "evm_event_indexer/api/controller/v1/admin/users"
 
 	"github.com/gin-gonic/gin"
 
 	"evm_event_indexer/api/controller/v1/contracts"
+	exportsController "evm_event_indexer/api/controller/v1/exports"
+	"evm_event_indexer/api/controller/v1/graphql"
+	syncStatusController "evm_event_indexer/api/controller/v1/syncstatus"
+	tokensController "evm_event_indexer/api/controller/v1/tokens"
 	authController "evm_event_indexer/api/controller/v1/user/auth"
 	"evm_event_indexer/api/controller/v1/user/me"
+	webhooksController "evm_event_indexer/api/controller/v1/webhooks"
 	"evm_event_indexer/api/middleware"
 )
 
+// StreamPaths are the long lived streaming routes, they are not bounded by the api timeout
+var StreamPaths = []string{
+	"/api/v1/txn/logs/stream",
+	"/api/v1/txn/logs/ws",
+	"/api/v1/txn/logs/export",
+	"/api/v1/exports/:export_id/download",
+}
+
 func Routing(router *gin.Engine) {
 
 	api := router.Group("/api")
@@ -60,17 +74,71 @@ func Routing(router *gin.Engine) {
 					adminUsers.PUT("/:user_id", adminUsersController.Update)
 					adminUsers.DELETE("/:user_id", adminUsersController.Delete)
 				}
+
+				adminTokens := admin.Group("/tokens", middleware.AdminAuthorization())
+				{
+					adminTokens.GET("/reconcile", adminTokensController.ListReconcile)
+				}
+			}
+
+			webhooks := v1.Group("/webhooks", middleware.Authorization())
+			{
+				webhooks.POST("", webhooksController.Create)
+				webhooks.GET("", webhooksController.List)
+				webhooks.GET("/:webhook_id", webhooksController.Get)
+				webhooks.PUT("/:webhook_id", webhooksController.Update)
+				webhooks.DELETE("/:webhook_id", webhooksController.Delete)
+				webhooks.GET("/:webhook_id/deliveries", webhooksController.ListDelivery)
+			}
+
+			tokens := v1.Group("/tokens", middleware.Authorization())
+			{
+				tokens.GET("/:token", tokensController.GetMetadata)
+				tokens.GET("/:token/balances/:holder", tokensController.GetBalance)
+				tokens.GET("/:token/holders", tokensController.ListHolder)
 			}
 
+			addresses := v1.Group("/addresses", middleware.Authorization())
+			{
+				// logs involving an address across the indexed contracts
+				addresses.GET("/:address/logs", contracts.GetAddressLog)
+			}
+
+			allowances := v1.Group("/allowances", middleware.Authorization())
+			{
+				allowances.GET("/:owner", tokensController.ListAllowance)
+			}
+
+			exports := v1.Group("/exports")
+			{
+				exports.POST("", middleware.Authorization(), exportsController.Create)
+				exports.GET("/:export_id", middleware.Authorization(), exportsController.Get)
+				// authorized by the signed link, see StreamPaths
+				exports.GET("/:export_id/download", exportsController.Download)
+			}
+
+			// sync progress of the indexed contracts
+			v1.GET("/sync", middleware.Authorization(), syncStatusController.List)
+
+			// graphql responds in the graphql format instead of the common response
+			v1.POST("/graphql", middleware.Authorization(), graphql.Query)
+
 			log := v1.Group("/txn", middleware.Authorization())
 			{
 				// Add more routes here as needed
 				log.GET("/logs", contracts.GetLog)
+				// stream new logs, see StreamPaths
+				log.GET("/logs/stream", contracts.StreamLogSSE)
+				log.GET("/logs/ws", contracts.StreamLogWS)
+				// stream all matching logs as csv or ndjson, see StreamPaths
+				log.GET("/logs/export", contracts.ExportLog)
+				// a log or the logs of a transaction with their finality, cacheable by ETag
+				log.GET("/logs/:chain_id/:tx_hash/:log_index", contracts.GetLogDetail)
+				log.GET("/tx/:chain_id/:tx_hash", contracts.GetTx)
+				// time-bucketed log count and volume
+				log.GET("/stats", contracts.GetStats)
 				// get block
-				// get transaction
 				// get receipt
-				// get event log
-				// get event detail
 			}
 
 		}
@@ -27,7 +27,7 @@ func NewServer() *http.Server {
 	router.NoRoute(middleware.NotFoundHandler)
 
 	// use recovery middleware to avoid panic
-	router.Use(gin.Recovery(), middleware.Interceptor(), middleware.TimeoutHandler(), middleware.ResponseHandler())
+	router.Use(gin.Recovery(), middleware.Interceptor(StreamPaths...), middleware.TimeoutHandler(StreamPaths...), middleware.ResponseHandler())
 
 	// bind route
 	Routing(router)
@@ -0,0 +1,152 @@
+package background
+
+import (
+	"evm_event_indexer/internal/decoder"
+	"evm_event_indexer/internal/eth"
+	"evm_event_indexer/service/model"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// newLog converts an on chain log into an event log and decodes it,
+// if decode failed, only the raw data is kept.
+func newLog(chainID int64, v types.Log, now time.Time) *model.Log {
+	topics := make([]string, 4)
+	ti := 0
+	for _, t := range v.Topics {
+		topics[ti] = t.Hex()
+		ti++
+	}
+
+	log := &model.Log{
+		ChainID:        chainID,
+		Address:        v.Address.Hex(),
+		BlockHash:      v.BlockHash.Hex(),
+		BlockNumber:    v.BlockNumber,
+		Topic0:         topics[0],
+		Topic1:         topics[1],
+		Topic2:         topics[2],
+		Topic3:         topics[3],
+		TxIndex:        int32(v.TxIndex),
+		LogIndex:       int32(v.Index),
+		TxHash:         v.TxHash.Hex(),
+		Data:           v.Data,
+		BlockTimestamp: time.Unix(int64(v.BlockTimestamp), 0),
+		CreatedAt:      now,
+	}
+
+	name, args, err := decoder.Provider.Decode(log)
+	if err != nil {
+		slog.Error("decode event error",
+			slog.Any("error", err),
+			slog.Any("address", v.Address.Hex()),
+			slog.Any("blockNumber", v.BlockNumber),
+			slog.Any("txHash", v.TxHash.Hex()),
+			slog.Any("logIndex", v.Index),
+		)
+		return log
+	}
+
+	log.DecodedEvent = &model.DecodedEvent{
+		EventName: name,
+		EventData: args,
+	}
+
+	// queryable arguments, if normalize failed, the log is kept without them
+	log.Args, err = decoder.Provider.Args(log)
+	if err != nil {
+		slog.Error("index event args error",
+			slog.Any("error", err),
+			slog.Any("address", v.Address.Hex()),
+			slog.Any("txHash", v.TxHash.Hex()),
+			slog.Any("logIndex", v.Index),
+		)
+	}
+
+	return log
+}
+
+// fillBlockTimestamps sets BlockTimestamp on logs that come without one,
+// timestamps are looked up by block hash so all logs of the same block share one lookup.
+func fillBlockTimestamps(client *eth.Client, logs []types.Log) error {
+	hashes := make([]common.Hash, 0)
+	for _, v := range logs {
+		if v.BlockTimestamp == 0 {
+			hashes = append(hashes, v.BlockHash)
+		}
+	}
+
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	timestamps, err := client.GetBlockTimestamps(hashes)
+	if err != nil {
+		return err
+	}
+
+	for i := range logs {
+		if logs[i].BlockTimestamp != 0 {
+			continue
+		}
+
+		ts, ok := timestamps[logs[i].BlockHash]
+		if !ok || ts == 0 {
+			return fmt.Errorf("block timestamp not found, block hash: %s", logs[i].BlockHash.Hex())
+		}
+		logs[i].BlockTimestamp = ts
+	}
+
+	return nil
+}
+
+// fillL1Origins sets the L1 origin on logs of an L2 chain and returns the block headers to store,
+// the header of the last scanned block is included so every sync records its L1 origin.
+func fillL1Origins(client *eth.Client, chainType string, last *types.Header, logs []*model.Log, now time.Time) ([]*model.BlockHeader, error) {
+	hashes := []common.Hash{last.Hash()}
+	for _, v := range logs {
+		hashes = append(hashes, common.HexToHash(v.BlockHash))
+	}
+
+	headers, err := client.GetHeadersByHash(hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	origins, err := client.GetL1Origins(chainType, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range logs {
+		origin := origins[common.HexToHash(v.BlockHash)]
+		v.L1BlockNumber = origin.Number
+		if origin.Hash != (common.Hash{}) {
+			v.L1BlockHash = origin.Hash.Hex()
+		}
+	}
+
+	res := make([]*model.BlockHeader, 0, len(headers))
+	for hash, header := range headers {
+		origin := origins[hash]
+		bh := &model.BlockHeader{
+			ChainID:        client.GetChainID().Int64(),
+			BlockHash:      hash.Hex(),
+			BlockNumber:    header.Number.Uint64(),
+			ParentHash:     header.ParentHash.Hex(),
+			BlockTimestamp: time.Unix(int64(header.Time), 0),
+			L1BlockNumber:  origin.Number,
+			CreatedAt:      now,
+		}
+		if origin.Hash != (common.Hash{}) {
+			bh.L1BlockHash = origin.Hash.Hex()
+		}
+		res = append(res, bh)
+	}
+
+	return res, nil
+}
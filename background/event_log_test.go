@@ -0,0 +1,59 @@
+package background
+
+import (
+	"context"
+	"evm_event_indexer/internal/eth"
+	"evm_event_indexer/internal/testutil"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FillBlockTimestamps(t *testing.T) {
+	contract := common.HexToAddress("0x2001").Hex()
+	node := testutil.NewEthNode(t, testChainID)
+	newTestChain(node, contract)
+
+	client, err := eth.NewChainClient(context.TODO(), node.URL, testChainID)
+	require.NoError(t, err)
+	defer client.Close()
+
+	h1, h2, h3 := node.AddBlock(1, ""), node.AddBlock(2, ""), node.AddBlock(3, "")
+
+	// logs without a timestamp are filled from the header of their block, a log with one is left as is
+	logs := []types.Log{
+		newTransferLog(contract, h1, 0, 1),
+		newTransferLog(contract, h1, 1, 2),
+		newTransferLog(contract, h2, 0, 3),
+		newTransferLog(contract, h3, 0, 4),
+	}
+	for i := range logs[:3] {
+		logs[i].BlockTimestamp = 0
+	}
+	logs[3].BlockTimestamp = 42
+
+	require.NoError(t, fillBlockTimestamps(client, logs))
+	assert.Equal(t, h1.Time, logs[0].BlockTimestamp)
+	assert.Equal(t, h1.Time, logs[1].BlockTimestamp)
+	assert.Equal(t, h2.Time, logs[2].BlockTimestamp)
+	assert.Equal(t, uint64(42), logs[3].BlockTimestamp)
+
+	// the repeated hash of the first block is fetched once, the block of the timestamped log is not fetched
+	assert.Equal(t, 2, node.Calls("eth_getBlockByHash"))
+
+	// the headers are cached for the next lookups
+	logs = []types.Log{newTransferLog(contract, h2, 1, 5)}
+	logs[0].BlockTimestamp = 0
+	require.NoError(t, fillBlockTimestamps(client, logs))
+	assert.Equal(t, h2.Time, logs[0].BlockTimestamp)
+	assert.Equal(t, 2, node.Calls("eth_getBlockByHash"))
+
+	// a block unknown to the node is an error instead of a zero timestamp
+	logs = []types.Log{newTransferLog(contract, h3, 1, 6)}
+	logs[0].BlockTimestamp = 0
+	logs[0].BlockHash = common.HexToHash("0xdead")
+	assert.Error(t, fillBlockTimestamps(client, logs))
+}
@@ -0,0 +1,160 @@
+package background
+
+import (
+	"context"
+	"errors"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/decoder"
+	"evm_event_indexer/internal/enum"
+	"evm_event_indexer/internal/export"
+	"evm_event_indexer/internal/metrics"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var _ Worker = (*ExportWorker)(nil)
+
+// max length of the stored error of a failed job
+const maxExportError = 1024
+
+// number of expired jobs deleted per poll
+const exportCleanupBatch = 100
+
+// ExportWorker writes the queued export jobs to the export directory and deletes the expired ones.
+// Jobs are claimed one at a time, so several indexer instances sharing the directory can run the worker.
+type ExportWorker struct{}
+
+func NewExportWorker() *ExportWorker {
+	return &ExportWorker{}
+}
+
+func (w *ExportWorker) Run(ctx context.Context) error {
+	if err := os.MkdirAll(config.Get().Export.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create export dir: %w", err)
+	}
+
+	ticker := time.NewTicker(config.Get().Export.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := w.cleanup(ctx); err != nil {
+				slog.Error("export cleanup error", slog.Any("error", err))
+			}
+			if err := w.runPending(ctx); err != nil {
+				slog.Error("export job error", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// runPending runs the claimed jobs until there is none left
+func (w *ExportWorker) runPending(ctx context.Context) error {
+	for ctx.Err() == nil {
+		job, err := service.ClaimExportJob(ctx)
+		if err != nil {
+			return err
+		}
+		if job == nil {
+			return nil
+		}
+
+		w.run(ctx, job)
+	}
+
+	return nil
+}
+
+func (w *ExportWorker) run(ctx context.Context, job *model.ExportJob) {
+	// the job is claimed again by another worker after the timeout
+	runCtx, cancel := context.WithTimeout(ctx, config.Get().Export.Timeout)
+	defer cancel()
+
+	rows, err := w.write(runCtx, job)
+	if ctx.Err() != nil {
+		// shutting down, the job is claimed again after the timeout
+		return
+	}
+
+	job.Rows = rows
+	job.UpdatedAt = time.Now()
+	status := "success"
+	if err != nil {
+		status = "failure"
+		job.Status = enum.ExportStatusFailed
+		job.Error = truncate(err.Error(), maxExportError)
+		slog.Error("export job failed", slog.Any("error", err), slog.Int64("job", job.ID))
+	} else {
+		job.Status = enum.ExportStatusSucceeded
+		job.FileName = fmt.Sprintf("%d.%s", job.ID, job.Format)
+	}
+
+	metrics.ExportJobs.WithLabelValues(job.Format, status).Inc()
+
+	if err := service.SaveExportJobResult(ctx, job); err != nil {
+		slog.Error("save export job result error", slog.Any("error", err), slog.Int64("job", job.ID))
+	}
+}
+
+// write exports the logs to a temporary file and renames it to the file of the job when complete,
+// so a partial file is never served
+func (w *ExportWorker) write(ctx context.Context, job *model.ExportJob) (int64, error) {
+	dir := config.Get().Export.Dir
+	file, err := os.CreateTemp(dir, fmt.Sprintf("%d-*.tmp", job.ID))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	writer, err := export.NewWriter(export.Format(job.Format), file, decoder.Provider.Fields())
+	if err != nil {
+		file.Close()
+		return 0, err
+	}
+
+	rows, err := service.ExportLogs(ctx, job.Filter, writer)
+	if err == nil {
+		err = writer.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return rows, err
+	}
+
+	if err := os.Rename(file.Name(), filepath.Join(dir, fmt.Sprintf("%d.%s", job.ID, job.Format))); err != nil {
+		return rows, fmt.Errorf("failed to rename file: %w", err)
+	}
+
+	return rows, nil
+}
+
+// cleanup deletes the files and the jobs older than the retention
+func (w *ExportWorker) cleanup(ctx context.Context) error {
+	jobs, err := service.GetExpiredExportJobs(ctx, exportCleanupBatch)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0, len(jobs))
+	for _, job := range jobs {
+		if job.FileName != "" {
+			if err := os.Remove(service.ExportFilePath(job)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				slog.Error("remove export file error", slog.Any("error", err), slog.Int64("job", job.ID))
+				continue
+			}
+		}
+		ids = append(ids, job.ID)
+	}
+
+	return service.DeleteExportJobs(ctx, ids)
+}
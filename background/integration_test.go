@@ -0,0 +1,124 @@
+package background
+
+import (
+	"context"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/decoder"
+	"evm_event_indexer/internal/eth"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/internal/testutil"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"fmt"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+const testChainID = int64(31337)
+
+var testTransferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+func TestMain(m *testing.M) {
+	testutil.SetupTestConfig()
+	decoder.InitDecoder()
+	dbManager := storage.Forge()
+	if err := dbManager.Init(); err != nil {
+		panic(fmt.Sprintf("failed to init database: %s\n", err))
+	}
+
+	code := m.Run()
+	dbManager.Shutdown()
+	os.Exit(code)
+}
+
+// newTestChain returns the config of a chain served by the fake node, indexing the contract,
+// the header cache is reset so no header of another test is served
+func newTestChain(node *testutil.EthNode, contract string) *config.Chain {
+	eth.Headers = eth.NewHeaderCache(1024, false, 0)
+
+	return &config.Chain{
+		ChainID:     testChainID,
+		RpcHTTP:     node.URL,
+		RpcWS:       node.WS,
+		ReorgWindow: 10,
+		BatchSize:   100,
+		Addresses:   []config.ChainAddress{{Address: contract}},
+	}
+}
+
+// newTestContract returns an unused contract address, the rows of the contract are rolled back when the test ends
+func newTestContract(t *testing.T) string {
+	t.Helper()
+
+	address := common.HexToAddress(fmt.Sprintf("0x%040x", time.Now().UnixNano())).Hex()
+	t.Cleanup(func() {
+		_ = service.ReorgLog(context.TODO(), &service.ReorgLogParam{
+			ChainID:   testChainID,
+			Address:   address,
+			ReorgHash: common.Hash{}.Hex(),
+			Now:       time.Now(),
+		})
+	})
+
+	return address
+}
+
+// newTransferLog returns a Transfer log of the contract in the block
+func newTransferLog(contract string, header *types.Header, index uint, value int64) types.Log {
+	return types.Log{
+		Address: common.HexToAddress(contract),
+		Topics: []common.Hash{
+			testTransferTopic,
+			common.BytesToHash(common.HexToAddress("0x1001").Bytes()),
+			common.BytesToHash(common.HexToAddress("0x1002").Bytes()),
+		},
+		Data:           common.LeftPadBytes(big.NewInt(value).Bytes(), 32),
+		BlockNumber:    header.Number.Uint64(),
+		BlockHash:      header.Hash(),
+		BlockTimestamp: header.Time,
+		TxHash:         common.BigToHash(new(big.Int).SetUint64(header.Number.Uint64()*1000 + uint64(index))),
+		Index:          index,
+	}
+}
+
+// upsertScanned stores the logs of a scanned range as confirmed, as the scanner does
+func upsertScanned(t *testing.T, contract string, fromBN uint64, last *types.Header, logs ...types.Log) {
+	t.Helper()
+
+	now := time.Now()
+	res := make([]*model.Log, len(logs))
+	for i, v := range logs {
+		res[i] = newLog(testChainID, v, now)
+		res[i].Confirmed = true
+	}
+
+	require.NoError(t, service.UpsertLog(context.TODO(), &service.UpsertLogParam{
+		ChainID:        testChainID,
+		Address:        contract,
+		FromBlock:      fromBN,
+		LastSyncNumber: last.Number.Uint64(),
+		LastSyncHash:   last.Hash().Hex(),
+		Now:            now,
+		Logs:           res,
+	}))
+}
+
+// drainReorgs empties the reorg channel and returns the messages
+func drainReorgs() []*reorgMsg {
+	res := make([]*reorgMsg, 0)
+	for {
+		select {
+		case msg := <-reorgChan:
+			res = append(res, msg)
+		default:
+			return res
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package background
+
+import (
+	"context"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/metrics"
+	"evm_event_indexer/internal/outbox"
+	"evm_event_indexer/service"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var _ Worker = (*OutboxRelay)(nil)
+
+// OutboxRelay delivers the committed outbox messages to every registered consumer in id order, marks them delivered,
+// and deletes the delivered messages after the retention.
+// When a consumer fails, the batch is delivered again to the consumers that did not handle it yet,
+// a message may still be delivered more than once after a restart or by another instance.
+// Instances claim disjoint batches, so messages are only in id order within a batch when several instances relay.
+type OutboxRelay struct {
+	consumers []outbox.Consumer
+	handled   map[string]map[int64]struct{} // message ids handled per consumer of the batch being retried
+}
+
+func NewOutboxRelay() *OutboxRelay {
+	return &OutboxRelay{
+		handled: make(map[string]map[int64]struct{}),
+	}
+}
+
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	r.consumers = outbox.Consumers()
+
+	ticker := time.NewTicker(config.Get().Outbox.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if len(r.consumers) == 0 {
+				// messages are kept in the outbox until a consumer is registered
+				slog.Error("no outbox consumer registered, outbox messages are not delivered")
+				continue
+			}
+
+			if err := r.relay(ctx); err != nil {
+				slog.Error("outbox relay error", slog.Any("error", err))
+			}
+
+			if err := r.clean(ctx); err != nil {
+				slog.Error("outbox clean error", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// relay delivers batches until the outbox is drained
+func (r *OutboxRelay) relay(ctx context.Context) error {
+	cnf := config.Get().Outbox
+	for ctx.Err() == nil {
+		delivered, err := service.RelayOutbox(ctx, cnf.BatchSize, cnf.Lease, r.deliver)
+		if err != nil {
+			return err
+		}
+
+		if uint64(delivered) < cnf.BatchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (r *OutboxRelay) deliver(ctx context.Context, msgs []*outbox.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, config.Get().Timeout)
+	defer cancel()
+
+	for _, c := range r.consumers {
+		handled := r.handled[c.Name()]
+
+		pending := make([]*outbox.Message, 0, len(msgs))
+		for _, msg := range msgs {
+			if _, ok := handled[msg.ID]; !ok {
+				pending = append(pending, msg)
+			}
+		}
+
+		if len(pending) == 0 {
+			continue
+		}
+
+		if err := c.Consume(ctx, pending...); err != nil {
+			metrics.OutboxErrors.WithLabelValues(c.Name()).Inc()
+			return fmt.Errorf("failed to deliver to consumer %s: %w", c.Name(), err)
+		}
+
+		if handled == nil {
+			handled = make(map[int64]struct{}, len(pending))
+			r.handled[c.Name()] = handled
+		}
+		for _, msg := range pending {
+			handled[msg.ID] = struct{}{}
+			metrics.OutboxDelivered.WithLabelValues(c.Name(), msg.Type).Inc()
+		}
+	}
+
+	// every consumer handled the batch, it is marked delivered by the caller
+	clear(r.handled)
+	return nil
+}
+
+// clean deletes the messages delivered before the retention
+func (r *OutboxRelay) clean(ctx context.Context) error {
+	cnf := config.Get().Outbox
+	_, err := service.CleanOutbox(ctx, time.Now().Add(-cnf.Retention), cnf.BatchSize)
+	return err
+}
@@ -0,0 +1,136 @@
+package background
+
+import (
+	"context"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/erc20"
+	"evm_event_indexer/internal/eth"
+	"evm_event_indexer/internal/metrics"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var _ Worker = (*Reconciler)(nil)
+
+// Reconciler periodically compares sampled holder balances of the ledger with balanceOf at the synced block,
+// drift points at fee-on-transfer or rebasing tokens, or missed logs.
+// Results are saved for the admin report and the drifted holders are exported per token.
+type Reconciler struct {
+	chain   *config.Chain
+	chainID string // metrics label
+}
+
+func NewReconciler(chain *config.Chain) *Reconciler {
+	return &Reconciler{
+		chain:   chain,
+		chainID: strconv.FormatInt(chain.ChainID, 10),
+	}
+}
+
+func (r *Reconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(config.Get().Reconcile.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.reconcile(ctx); err != nil {
+				slog.Error("reconcile error", slog.String("chain", r.chain.String()), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+func (r *Reconciler) reconcile(ctx context.Context) error {
+	client, err := eth.NewChainClient(ctx, r.chain.RpcHTTP, r.chain.ChainID)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	defer client.Close()
+
+	// read-only calls need no key
+	token := erc20.NewERC20Service(client, "")
+	for _, address := range r.chain.Addresses {
+		if err := r.reconcileToken(ctx, token, address.Address); err != nil {
+			slog.Error("reconcile token error", slog.String("chain", r.chain.String()), slog.String("token", address.Address), slog.Any("error", err))
+		}
+	}
+
+	return nil
+}
+
+func (r *Reconciler) reconcileToken(ctx context.Context, token *erc20.ERC20Service, address string) error {
+	bs, err := service.GetBlockSync(ctx, r.chain.ChainID, address)
+	if err != nil {
+		return fmt.Errorf("failed to get block sync: %w", err)
+	}
+	if bs == nil {
+		return nil
+	}
+
+	holders, err := service.GetReconcileHolders(ctx, r.chain.ChainID, address, config.Get().Reconcile.SampleSize)
+	if err != nil {
+		return fmt.Errorf("failed to sample holders: %w", err)
+	}
+
+	if len(holders) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	block := new(big.Int).SetUint64(bs.LastSyncNumber)
+	results := make([]*model.TokenReconcile, 0, len(holders))
+	drifted := 0
+	for _, holder := range holders {
+		ledger, err := service.GetLedgerBalanceAt(ctx, r.chain.ChainID, address, holder, bs.LastSyncNumber)
+		if err != nil {
+			return fmt.Errorf("failed to get ledger balance: %w", err)
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, config.Get().Timeout)
+		onchain, err := token.GetBalanceOfAt(callCtx, common.HexToAddress(address), common.HexToAddress(holder), block)
+		cancel()
+		if err != nil {
+			// a failed call is not a drift, e.g. the node pruned the state of the block
+			metrics.TokenReconcileChecks.WithLabelValues(r.chainID, address, "error").Inc()
+			slog.Warn("balanceOf call failed", slog.String("token", address), slog.String("holder", holder), slog.Any("error", err))
+			continue
+		}
+
+		result := &model.TokenReconcile{
+			ChainID:       r.chain.ChainID,
+			Token:         address,
+			Holder:        holder,
+			BlockNumber:   bs.LastSyncNumber,
+			LedgerBalance: ledger,
+			ChainBalance:  onchain,
+			Drift:         new(big.Int).Sub(onchain, ledger),
+			CheckedAt:     now,
+		}
+		results = append(results, result)
+
+		if result.HasDrift() {
+			drifted++
+			metrics.TokenReconcileChecks.WithLabelValues(r.chainID, address, "drift").Inc()
+			continue
+		}
+		metrics.TokenReconcileChecks.WithLabelValues(r.chainID, address, "match").Inc()
+	}
+
+	metrics.TokenDriftHolders.WithLabelValues(r.chainID, address).Set(float64(drifted))
+
+	if drifted > 0 {
+		slog.Warn("token balance drift found", slog.String("chain", r.chain.String()), slog.String("token", address), slog.Int("holders", drifted), slog.Any("block", bs.LastSyncNumber))
+	}
+
+	return service.SaveReconcileResults(ctx, results...)
+}
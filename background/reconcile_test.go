@@ -0,0 +1,90 @@
+package background
+
+import (
+	"context"
+	"errors"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/erc20"
+	"evm_event_indexer/internal/eth"
+	"evm_event_indexer/internal/metrics"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/internal/testutil"
+	"evm_event_indexer/service/repo/tokenbalance"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getReconciles returns the saved reconciliation results of the token by holder
+func getReconciles(t *testing.T, token string) map[string]string {
+	t.Helper()
+
+	db, err := storage.GetMySQL(config.EventDBM)
+	require.NoError(t, err)
+
+	results, err := tokenbalance.GetReconciles(context.TODO(), db, &tokenbalance.GetReconcileFilter{ChainID: testChainID, Token: token})
+	require.NoError(t, err)
+
+	res := make(map[string]string, len(results))
+	for _, v := range results {
+		res[v.Holder] = v.Drift.String()
+	}
+	return res
+}
+
+func Test_Reconciler_ReconcileToken(t *testing.T) {
+	ctx := context.TODO()
+	node := testutil.NewEthNode(t, testChainID)
+	contract := newTestContract(t)
+	chain := newTestChain(node, contract)
+	setConfig(t, &config.Get().Reconcile.SampleSize, 10)
+
+	a, b := common.HexToAddress("0x1001"), common.HexToAddress("0x1002")
+
+	// a mint of 100 to a, then a transfer of 30 from a to b
+	mint := newTransferLog(contract, node.AddBlock(1, "a"), 0, 100)
+	mint.Topics[1] = common.Hash{}
+	mint.Topics[2] = common.BytesToHash(a.Bytes())
+	transfer := newTransferLog(contract, node.AddBlock(2, "a"), 0, 30)
+	upsertScanned(t, contract, 1, node.AddBlock(3, "a"), mint, transfer)
+
+	client, err := eth.NewChainClient(ctx, node.URL, testChainID)
+	require.NoError(t, err)
+	defer client.Close()
+
+	token := erc20.NewERC20Service(client, "")
+	r := NewReconciler(chain)
+
+	checks := func(result string) float64 {
+		return promtest.ToFloat64(metrics.TokenReconcileChecks.WithLabelValues(r.chainID, contract, result))
+	}
+	match, drift, failed := checks("match"), checks("drift"), checks("error")
+
+	// a matches the ledger, b holds one more token than the ledger knows of, e.g. a fee-on-transfer token
+	node.SetBalance(a, big.NewInt(70))
+	node.SetBalance(b, big.NewInt(31))
+
+	require.NoError(t, r.reconcileToken(ctx, token, contract))
+	assert.Equal(t, match+1, checks("match"))
+	assert.Equal(t, drift+1, checks("drift"))
+	assert.Equal(t, failed, checks("error"))
+	assert.Equal(t, float64(1), promtest.ToFloat64(metrics.TokenDriftHolders.WithLabelValues(r.chainID, contract)))
+
+	holderA, holderB := strings.ToLower(a.Hex()), strings.ToLower(b.Hex())
+	assert.Equal(t, map[string]string{holderA: "0", holderB: "1"}, getReconciles(t, contract))
+
+	// a failed balanceOf is counted as an error, not as a drift, and leaves the saved results
+	node.Fail("eth_call", errors.New("missing trie node"))
+
+	require.NoError(t, r.reconcileToken(ctx, token, contract))
+	assert.Equal(t, match+1, checks("match"))
+	assert.Equal(t, drift+1, checks("drift"))
+	assert.Equal(t, failed+2, checks("error"))
+	assert.Equal(t, float64(0), promtest.ToFloat64(metrics.TokenDriftHolders.WithLabelValues(r.chainID, contract)))
+	assert.Equal(t, map[string]string{holderA: "0", holderB: "1"}, getReconciles(t, contract))
+}
@@ -18,7 +18,7 @@ import (
 var _ Worker = (*ReorgConsumer)(nil)
 
 type reorgMsg struct {
-	RpcHttp         string
+	Chain           *config.Chain
 	ContractAddress string
 	Backoff         time.Duration
 	Log             types.Log
@@ -62,7 +62,7 @@ func (r *ReorgConsumer) Run(ctx context.Context) error {
 				continue
 			}
 
-			if err := r.reorgHandler(ctx, msg.RpcHttp, msg.Log, msg.ContractAddress); err != nil {
+			if err := r.reorgHandler(ctx, msg.Chain, msg.Log, msg.ContractAddress); err != nil {
 				slog.Error("failed to handle reorg", slog.Any("error", err))
 				select {
 				case <-ctx.Done():
@@ -86,11 +86,11 @@ func (r *ReorgConsumer) Run(ctx context.Context) error {
 // 1. check target log is same as on chain
 // 2. if not same, fallback to get window size logs to find the rollback checkpoint
 // 3. if still not found, fallback to start_block
-func (r *ReorgConsumer) reorgHandler(parentCtx context.Context, rpcHttp string, log types.Log, address string) error {
+func (r *ReorgConsumer) reorgHandler(parentCtx context.Context, chain *config.Chain, log types.Log, address string) error {
 	ctx, cancel := context.WithTimeout(parentCtx, config.Get().Timeout)
 	defer cancel()
 
-	client, err := eth.NewClient(ctx, rpcHttp)
+	client, err := eth.NewChainClient(ctx, chain.RpcHTTP, chain.ChainID)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
@@ -98,12 +98,19 @@ func (r *ReorgConsumer) reorgHandler(parentCtx context.Context, rpcHttp string,
 
 	checkpoint := log.BlockNumber
 	reorgHash := log.BlockHash.Hex()
-	window := uint64(config.Get().ReorgWindow * 2)
+	window := uint64(chain.GetReorgWindow() * 2)
 
-	// get the logs by block hash
+	// cached headers near the reorged block may belong to the old fork, drop them for the other readers of the cache,
+	// the hashes below are compared against headers fetched from the node since the window reaches further back
+	client.InvalidateHeaders(log.BlockNumber - min(log.BlockNumber, uint64(chain.GetReorgWindow())))
+
+	// only confirmed logs can be checkpoints, unconfirmed live logs are above the synced block,
+	// a checkpoint there would mark the blocks between as synced although the scanner never scanned them
 	blockLog, err := service.GetLogs(ctx, &eventlog.GetLogParam{
-		Address:   address,
-		BlockHash: log.BlockHash.Hex(),
+		ChainID:       chain.ChainID,
+		Addresses:     []string{address},
+		BlockHash:     log.BlockHash.Hex(),
+		ConfirmedOnly: true,
 		Pagination: &model.Pagination{ // only need to get one log
 			Page: 1,
 			Size: 1,
@@ -117,7 +124,7 @@ func (r *ReorgConsumer) reorgHandler(parentCtx context.Context, rpcHttp string,
 
 	for _, log := range blockLog {
 		// get the block on chain
-		header, err := client.GetHeaderByNumber(log.BlockNumber)
+		header, err := client.FetchHeaderByNumber(log.BlockNumber)
 		if err != nil {
 			return fmt.Errorf("failed to get header: %w", err)
 		}
@@ -133,10 +140,12 @@ func (r *ReorgConsumer) reorgHandler(parentCtx context.Context, rpcHttp string,
 	// if rollback header not found, fallback to get batch logs to find the rollback checkpoint
 	if rollbackHeader == nil {
 		logs, err := service.GetLogs(ctx, &eventlog.GetLogParam{
-			Address:        address,
+			ChainID:        chain.ChainID,
+			Addresses:      []string{address},
 			OrderBy:        2,
 			Desc:           true, // from latest to oldest
 			BlockNumberLTE: checkpoint,
+			ConfirmedOnly:  true,
 			Pagination: &model.Pagination{
 				Page: 1,
 				Size: window,
@@ -149,7 +158,7 @@ func (r *ReorgConsumer) reorgHandler(parentCtx context.Context, rpcHttp string,
 		// iterate the logs to find the checkpoint
 		for _, log := range logs {
 			// get the block on chain
-			header, err := client.GetHeaderByNumber(log.BlockNumber)
+			header, err := client.FetchHeaderByNumber(log.BlockNumber)
 			if err != nil {
 				return fmt.Errorf("failed to get header: %w", err)
 			}
@@ -172,15 +181,15 @@ func (r *ReorgConsumer) reorgHandler(parentCtx context.Context, rpcHttp string,
 		reorgHash = rollbackHeader.Hash().Hex()
 	} else {
 		// if rollback header not found in the batch logs, means reorg falls outside the window, fallback to start_block
-		checkpoint = config.Get().StartBlock
+		checkpoint = chain.GetStartBlock()
 		slog.Debug("reorg checkpoint not found within windowlimit, fallback to block start_block",
 			slog.Any("checkpoint", checkpoint),
 			slog.Any("window", window),
-			slog.Any("start_block", config.Get().StartBlock),
+			slog.Any("start_block", checkpoint),
 		)
 
 		// get the start_block header from chain
-		header, err := client.GetHeaderByNumber(checkpoint)
+		header, err := client.FetchHeaderByNumber(checkpoint)
 		if err != nil {
 			return fmt.Errorf("failed to get header: %w", err)
 		}
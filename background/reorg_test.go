@@ -0,0 +1,53 @@
+package background
+
+import (
+	"context"
+	"evm_event_indexer/internal/testutil"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ReorgHandler_SkipsLiveLogs(t *testing.T) {
+	ctx := context.TODO()
+	node := testutil.NewEthNode(t, testChainID)
+	contract := newTestContract(t)
+	chain := newTestChain(node, contract)
+
+	headers := make(map[uint64]*types.Header)
+	for bn := uint64(1); bn <= 10; bn++ {
+		headers[bn] = node.AddBlock(bn, "a")
+	}
+
+	// blocks up to 5 are scanned, block 8 only has a live log
+	upsertScanned(t, contract, 1, headers[5],
+		newTransferLog(contract, headers[2], 0, 1),
+		newTransferLog(contract, headers[4], 0, 2),
+	)
+
+	live := newLog(testChainID, newTransferLog(contract, headers[8], 0, 3), time.Now())
+	require.NoError(t, service.InsertLiveLog(ctx, &service.InsertLiveLogParam{
+		ChainID: testChainID,
+		Address: contract,
+		Logs:    []*model.Log{live},
+	}))
+
+	// a log of block 9 is removed, the live log of block 8 still matches the chain but is not a checkpoint
+	removed := newTransferLog(contract, headers[9], 0, 4)
+	removed.Removed = true
+	node.AddBlock(9, "b")
+
+	require.NoError(t, (&ReorgConsumer{}).reorgHandler(ctx, chain, removed, contract))
+
+	bs, err := service.GetBlockSync(ctx, testChainID, contract)
+	require.NoError(t, err)
+	if assert.NotNil(t, bs) {
+		assert.Equal(t, uint64(4), bs.LastSyncNumber)
+		assert.Equal(t, headers[4].Hash().Hex(), bs.LastSyncHash)
+	}
+}
@@ -3,10 +3,10 @@ package background
 import (
 	"context"
 	"evm_event_indexer/internal/config"
-	"evm_event_indexer/internal/decoder"
 	"evm_event_indexer/internal/eth"
 	"evm_event_indexer/internal/metrics"
 	"evm_event_indexer/internal/tools"
+	"evm_event_indexer/internal/workerstate"
 	"evm_event_indexer/service"
 	"fmt"
 
@@ -20,24 +20,28 @@ import (
 var _ Worker = (*Scanner)(nil)
 
 type Scanner struct {
-	rpcHTTP   string
-	Address   string
-	Topics    [][]common.Hash
-	BatchSize int32
+	chain   *config.Chain
+	Address string
+	Topics  [][]common.Hash
 }
 
-func NewScanner(rcpHttp string, address string, topics [][]common.Hash, batchSize int32) *Scanner {
+func NewScanner(chain *config.Chain, address string, topics [][]common.Hash) *Scanner {
 	return &Scanner{
-		rpcHTTP:   rcpHttp,
-		Address:   address,
-		Topics:    topics,
-		BatchSize: batchSize,
+		chain:   chain,
+		Address: address,
+		Topics:  topics,
 	}
 }
 
 // Runs a periodic log sync for a specific contract address.
-func (s *Scanner) Run(ctx context.Context) error {
-	client, err := eth.NewClient(ctx, s.rpcHTTP)
+func (s *Scanner) Run(ctx context.Context) (err error) {
+	// the state is reported to the sync status api
+	workerstate.Scanners.Register(s.chain.ChainID, s.Address)
+	defer func() {
+		workerstate.Scanners.Stopped(s.chain.ChainID, s.Address, err, time.Now())
+	}()
+
+	client, err := eth.NewChainClient(ctx, s.chain.RpcHTTP, s.chain.ChainID)
 	if err != nil {
 		return fmt.Errorf("failed to create eth client: %w", err)
 	}
@@ -53,7 +57,7 @@ func (s *Scanner) Run(ctx context.Context) error {
 
 func (s *Scanner) scan(ctx context.Context, client *eth.Client) error {
 
-	ticker := time.NewTicker(config.Get().LogScannerInterval)
+	ticker := time.NewTicker(s.chain.GetPollInterval())
 	defer ticker.Stop()
 
 	for {
@@ -72,8 +76,10 @@ func (s *Scanner) scan(ctx context.Context, client *eth.Client) error {
 
 				if err != nil {
 					status = "failure"
+					workerstate.Scanners.Failed(s.chain.ChainID, s.Address, err, time.Now())
 					slog.Error("syncLog error",
 						slog.Any("error", err),
+						slog.String("chain", s.chain.String()),
 						slog.String("address", s.Address),
 					)
 				}
@@ -90,7 +96,7 @@ func (s *Scanner) syncLog(ctx context.Context, client *eth.Client) (bool, error)
 	ctx, cancel := context.WithTimeout(ctx, config.Get().Timeout)
 	defer cancel()
 
-	slog.Info("syncing log...", slog.Any("chainID", client.GetChainID().Int64()), slog.Any("address", s.Address), slog.Any("topics", s.Topics))
+	slog.Info("syncing log...", slog.String("chain", s.chain.String()), slog.Any("chainID", client.GetChainID().Int64()), slog.Any("address", s.Address), slog.Any("topics", s.Topics))
 
 	bc, err := service.GetBlockSync(ctx, client.GetChainID().Int64(), s.Address)
 	if err != nil {
@@ -101,25 +107,32 @@ func (s *Scanner) syncLog(ctx context.Context, client *eth.Client) (bool, error)
 		bc = new(model.BlockSync)
 	}
 
-	// default start from block 0
-	syncBlock := uint64(config.Get().StartBlock)
+	// default start from the start block of the chain
+	syncBlock := s.chain.GetStartBlock()
 
 	// if there is no sync block, start from 0
 	if bc.LastSyncNumber > 0 {
 		syncBlock = bc.LastSyncNumber + 1
 	}
 
-	latestBlock, err := client.GetBlockNumber()
+	// L2 chains may follow the safe or finalized head instead of the sequencer head
+	latestBlock, err := client.GetHeadNumber(s.chain.GetHead())
 	if err != nil {
 		return false, fmt.Errorf("get current block number error for address %s: %w", s.Address, err)
 	}
+	workerstate.Scanners.SetHead(s.chain.ChainID, latestBlock, time.Now())
 
-	toBlock := min(syncBlock+uint64(s.BatchSize), latestBlock)
+	// only scan blocks with enough confirmations
+	latestBlock -= min(latestBlock, s.chain.Confirmations)
+
+	toBlock := min(syncBlock+uint64(s.chain.BatchSize), latestBlock)
 	if syncBlock >= toBlock {
 		slog.Info("no new blocks to scan",
+			slog.String("chain", s.chain.String()),
 			slog.Any("lastSyncNumber", bc.LastSyncNumber),
 			slog.Any("latestBlock", latestBlock),
 		)
+		workerstate.Scanners.Scanned(s.chain.ChainID, s.Address, time.Time{}, true, time.Now())
 		return false, nil
 	}
 
@@ -133,6 +146,13 @@ func (s *Scanner) syncLog(ctx context.Context, client *eth.Client) (bool, error)
 		return false, fmt.Errorf("get logs error for address %s from block %d to %d: %w", s.Address, syncBlock, toBlock, err)
 	}
 
+	// blockTimestamp is a recent extension of eth_getLogs, resolve it from block headers when the node omits it
+	if err := fillBlockTimestamps(client, eventLogs); err != nil {
+		return false, fmt.Errorf("fill block timestamps error for address %s: %w", s.Address, err)
+	}
+
+	// served from the header cache when the block was looked up before, e.g. by the timestamp lookup of its logs
+	// or by another instance through redis
 	header, err := client.GetHeaderByNumber(toBlock)
 	if err != nil {
 		return false, fmt.Errorf("get block header error for block %d: %w", toBlock, err)
@@ -142,61 +162,35 @@ func (s *Scanner) syncLog(ctx context.Context, client *eth.Client) (bool, error)
 	newSyncHash := header.Hash().Hex()
 	logs := make([]*model.Log, len(eventLogs))
 	for i, v := range eventLogs {
-		topics := make([]string, 4)
-		ti := 0
-		for _, t := range v.Topics {
-			topics[ti] = t.Hex()
-			ti++
-		}
-
-		logs[i] = &model.Log{
-			ChainID:        client.GetChainID().Int64(),
-			Address:        v.Address.Hex(),
-			BlockHash:      v.BlockHash.Hex(),
-			BlockNumber:    v.BlockNumber,
-			Topic0:         topics[0],
-			Topic1:         topics[1],
-			Topic2:         topics[2],
-			Topic3:         topics[3],
-			TxIndex:        int32(v.TxIndex),
-			LogIndex:       int32(v.Index),
-			TxHash:         v.TxHash.Hex(),
-			Data:           v.Data,
-			BlockTimestamp: time.Unix(int64(v.BlockTimestamp), 0),
-			CreatedAt:      now,
-		}
+		logs[i] = newLog(client.GetChainID().Int64(), v, now)
+		logs[i].Confirmed = true
+	}
 
-		// decode event, if decode failed, keep raw data only
-		name, args, err := decoder.Provider.Decode(logs[i])
+	var headers []*model.BlockHeader
+	if s.chain.IsL2() {
+		headers, err = fillL1Origins(client, s.chain.GetType(), header, logs, now)
 		if err != nil {
-			slog.Error("decode event error",
-				slog.Any("error", err),
-				slog.Any("address", s.Address),
-				slog.Any("blockNumber", v.BlockNumber),
-				slog.Any("txHash", v.TxHash.Hex()),
-				slog.Any("logIndex", v.Index),
-			)
-		} else {
-			logs[i].DecodedEvent = &model.DecodedEvent{
-				EventName: name,
-				EventData: args,
-			}
+			return false, fmt.Errorf("fill l1 origins error for address %s: %w", s.Address, err)
 		}
 	}
 
 	params := &service.UpsertLogParam{
 		ChainID:        client.GetChainID().Int64(),
 		Address:        s.Address,
+		FromBlock:      syncBlock,
 		LastSyncNumber: newSyncNumber,
 		LastSyncHash:   newSyncHash,
 		Now:            now,
 		Logs:           logs,
+		Headers:        headers,
 	}
 
 	if err := service.UpsertLog(ctx, params); err != nil {
 		return false, fmt.Errorf("upsert log error for address %s: %w", s.Address, err)
 	}
 
+	workerstate.Scanners.Scanned(s.chain.ChainID, s.Address, time.Unix(int64(header.Time), 0), toBlock == latestBlock, time.Now())
+
 	// matrics, latest block number
 	metrics.LatestSyncedBlock.WithLabelValues(client.GetChainID().String(), s.Address).Set(float64(toBlock))
 
@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/metrics"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/eventlog"
 
 	"evm_event_indexer/internal/eth"
 
 	"fmt"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -18,61 +23,60 @@ import (
 
 var _ Worker = (*Subscription)(nil)
 
+const (
+	// number of blocks fetched per eth_getLogs call when backfilling a disconnection gap
+	backfillBatchSize = 1000
+	// number of stored logs read per query when backfilling a disconnection gap
+	backfillPageSize = 1000
+)
+
+// Subscription keeps a websocket log subscription alive, it reconnects with backoff on error,
+// pings the node to detect dead connections and backfills the blocks missed while disconnected.
+// When live indexing is enabled, new logs are inserted as unconfirmed right away and confirmed later by the scanner.
 type Subscription struct {
-	rpcWS   string
-	rpcHTTP string
-	address []string
+	chain    *config.Chain
+	address  []string
+	topics   map[common.Address][]common.Hash // event signatures indexed per contract, empty means all
+	chainID  string                           // metrics label
+	lastSeen uint64                           // last block number known to be covered by the subscription
 }
 
-// for now, only handle removed log, new log will be handled by scanner
-func NewSubscription(rpcHTTP string, rpcWS string, address []string) *Subscription {
+// removed logs are always sent to the reorg consumer,
+// new logs are left to the scanner unless live indexing is enabled.
+func NewSubscription(chain *config.Chain, topics map[common.Address][]common.Hash) *Subscription {
+	address := make([]string, len(chain.Addresses))
+	for i, v := range chain.Addresses {
+		address[i] = v.Address
+	}
+
 	return &Subscription{
-		rpcHTTP: rpcHTTP,
-		rpcWS:   rpcWS,
+		chain:   chain,
 		address: address,
+		topics:  topics,
+		chainID: strconv.FormatInt(chain.ChainID, 10),
 	}
 }
 
 func (s *Subscription) Run(ctx context.Context) error {
+	if len(s.address) == 0 {
+		return errors.New("no contract addresses configured, skip subscription reorg check")
+	}
+
 	backoff := config.Get().Backoff
 	for {
 
-		err := func() error {
-			ch := make(chan types.Log)
-			client, err := eth.NewClient(ctx, s.rpcWS)
-			if err != nil {
-				return err
-			}
+		err := s.connect(ctx)
 
-			defer client.Close()
-
-			addresses := make([]common.Address, len(s.address))
-			for i := range s.address {
-				addresses[i] = common.HexToAddress(s.address[i])
-			}
-
-			sub, err := client.SubscribeFilterLogs(ch, ethereum.FilterQuery{
-				Addresses: addresses,
-			})
-			if err != nil {
-				return err
-			}
-
-			defer sub.Unsubscribe()
-
-			if err := s.subscription(ctx, sub, ch); err != nil {
-				return err
-			}
-
-			return nil
-		}()
+		metrics.SubscriptionConnected.WithLabelValues(s.chainID).Set(0)
 
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
 
+		metrics.SubscriptionReconnects.WithLabelValues(s.chainID).Inc()
+
 		if err != nil {
-			slog.Error("subscription error occurred, waiting to retry", slog.Any("eror", err))
+			slog.Error("subscription error occurred, waiting to retry", slog.String("chain", s.chain.String()), slog.Any("error", err), slog.Any("lastSeen", s.lastSeen))
 			time.Sleep(backoff)
 			backoff = min(backoff*2, config.Get().MaxBackoff)
 			continue
@@ -80,20 +84,69 @@ func (s *Subscription) Run(ctx context.Context) error {
 
 		// reset backoff
 		backoff = config.Get().Backoff
-
 	}
 }
 
-func (s *Subscription) subscription(ctx context.Context, sub ethereum.Subscription, ch chan types.Log) error {
+// connect dials the websocket endpoint, subscribes to the contract logs and
+// blocks until the subscription fails or the context is cancelled.
+func (s *Subscription) connect(ctx context.Context) error {
+	ch := make(chan types.Log)
+	client, err := eth.NewChainClient(ctx, s.chain.RpcWS, s.chain.ChainID)
+	if err != nil {
+		return err
+	}
 
-	if len(s.address) == 0 {
-		return errors.New("no contract addresses configured, skip subscription reorg check")
+	defer client.Close()
+
+	addresses := make([]common.Address, len(s.address))
+	for i := range s.address {
+		addresses[i] = common.HexToAddress(s.address[i])
+	}
+
+	sub, err := client.SubscribeFilterLogs(ch, ethereum.FilterQuery{
+		Addresses: addresses,
+	})
+	if err != nil {
+		return err
+	}
+
+	defer sub.Unsubscribe()
+
+	head, err := s.ping(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	// subscribe first, then backfill, so no block falls between the gap and the new subscription
+	if s.lastSeen > 0 {
+		if err := s.backfill(ctx, head); err != nil {
+			return fmt.Errorf("backfill error: %w", err)
+		}
 	}
 
+	s.lastSeen = max(s.lastSeen, head)
+	metrics.SubscriptionConnected.WithLabelValues(s.chainID).Set(1)
+	slog.Info("subscription connected", slog.String("chain", s.chain.String()), slog.Any("head", head))
+
+	return s.subscription(ctx, client, sub, ch)
+}
+
+func (s *Subscription) subscription(ctx context.Context, client *eth.Client, sub ethereum.Subscription, ch chan types.Log) error {
+
+	ticker := time.NewTicker(config.Get().Subscription.PingInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return nil // context done, exit the loop
+		case <-ticker.C:
+			// keepalive, a dead connection does not always surface as a subscription error
+			head, err := s.ping(ctx, client)
+			if err != nil {
+				return fmt.Errorf("subscription ping error, %w", err)
+			}
+			s.lastSeen = max(s.lastSeen, head)
 		case err := <-sub.Err():
 			slog.Error("subscription error", slog.Any("error", err))
 			return fmt.Errorf("subscription error, %w", err)
@@ -104,8 +157,22 @@ func (s *Subscription) subscription(ctx context.Context, sub ethereum.Subscripti
 				return fmt.Errorf("headers channel closed")
 			}
 
-			// skip new log, it will be handled by scanner
+			s.lastSeen = max(s.lastSeen, log.BlockNumber)
+
 			if !log.Removed {
+				// skip new log, it will be handled by scanner
+				if !s.chain.LiveIndexing {
+					continue
+				}
+
+				// a failed insert is not fatal, the scanner indexes the log anyway
+				if err := s.insertLive(ctx, client, log); err != nil {
+					slog.Error("insert live log error",
+						slog.Any("error", err),
+						slog.Any("block number", log.BlockNumber),
+						slog.Any("txhash", log.TxHash.Hex()),
+					)
+				}
 				continue
 			}
 
@@ -113,7 +180,7 @@ func (s *Subscription) subscription(ctx context.Context, sub ethereum.Subscripti
 				Log:             log,
 				Backoff:         config.Get().Backoff,
 				ContractAddress: log.Address.Hex(),
-				RpcHttp:         s.rpcHTTP,
+				Chain:           s.chain,
 				Retry:           0,
 			})
 
@@ -124,3 +191,189 @@ func (s *Subscription) subscription(ctx context.Context, sub ethereum.Subscripti
 		}
 	}
 }
+
+// insertLive decodes a new log and inserts it as unconfirmed
+func (s *Subscription) insertLive(ctx context.Context, client *eth.Client, log types.Log) error {
+	address, ok := s.match(log)
+	if !ok {
+		return nil
+	}
+
+	logs := []types.Log{log}
+	if err := fillBlockTimestamps(client, logs); err != nil {
+		return fmt.Errorf("fill block timestamps error: %w", err)
+	}
+
+	if err := service.InsertLiveLog(ctx, &service.InsertLiveLogParam{
+		ChainID: client.GetChainID().Int64(),
+		Address: address,
+		Logs:    []*model.Log{newLog(client.GetChainID().Int64(), logs[0], time.Now())},
+	}); err != nil {
+		return err
+	}
+
+	metrics.LiveLogsIndexed.WithLabelValues(s.chainID, address).Inc()
+	return nil
+}
+
+// match returns the configured address of the log, if the log is indexed by the scanner
+func (s *Subscription) match(log types.Log) (string, bool) {
+	for _, address := range s.address {
+		if common.HexToAddress(address) != log.Address {
+			continue
+		}
+
+		topics := s.topics[log.Address]
+		if len(topics) == 0 {
+			return address, true
+		}
+
+		if len(log.Topics) == 0 {
+			return "", false
+		}
+
+		for _, topic := range topics {
+			if topic == log.Topics[0] {
+				return address, true
+			}
+		}
+
+		return "", false
+	}
+
+	return "", false
+}
+
+func (s *Subscription) ping(ctx context.Context, client *eth.Client) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.Get().Timeout)
+	defer cancel()
+
+	return client.Client.BlockNumber(ctx)
+}
+
+// backfill compares the stored logs since the last seen block with the canonical logs,
+// a stored log missing on chain was removed while disconnected and is sent to the reorg consumer.
+// The gap is checked in batches with a timeout each, the last seen block moves after every batch
+// so a long gap makes progress across reconnects.
+func (s *Subscription) backfill(ctx context.Context, head uint64) error {
+	dialCtx, cancel := context.WithTimeout(ctx, config.Get().Timeout)
+	client, err := eth.NewChainClient(dialCtx, s.chain.RpcHTTP, s.chain.ChainID)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	defer client.Close()
+
+	// removed events could belong to any block within the reorg window of the last seen block
+	from := s.lastSeen - min(s.lastSeen, uint64(s.chain.GetReorgWindow()))
+	if from > head {
+		return nil
+	}
+
+	slog.Info("backfilling subscription gap", slog.String("chain", s.chain.String()), slog.Any("from", from), slog.Any("to", head))
+
+	for start := from; start <= head; start += backfillBatchSize {
+		end := min(start+backfillBatchSize-1, head)
+		if err := s.backfillBatch(ctx, client, start, end); err != nil {
+			return err
+		}
+		s.lastSeen = max(s.lastSeen, end)
+	}
+
+	return nil
+}
+
+// backfillBatch checks the stored logs of a block range against the canonical logs
+func (s *Subscription) backfillBatch(parentCtx context.Context, client *eth.Client, from uint64, to uint64) error {
+	ctx, cancel := context.WithTimeout(parentCtx, config.Get().Timeout)
+	defer cancel()
+
+	addresses := make([]common.Address, len(s.address))
+	for i := range s.address {
+		addresses[i] = common.HexToAddress(s.address[i])
+	}
+
+	logs, err := client.WithContext(ctx).GetLogs(eth.GetLogsParams{
+		FromBlock: from,
+		ToBlock:   to,
+		Addresses: addresses,
+	})
+	if err != nil {
+		return fmt.Errorf("get logs error from block %d: %w", from, err)
+	}
+
+	// canonical logs, keyed by block hash and log index
+	canonical := make(map[string]struct{}, len(logs))
+	for _, v := range logs {
+		canonical[logKey(v.BlockHash.Hex(), v.Index)] = struct{}{}
+	}
+
+	for _, address := range s.address {
+		removed, err := s.findRemoved(ctx, client.GetChainID().Int64(), address, from, to, canonical)
+		if err != nil {
+			return err
+		}
+
+		if removed == nil {
+			continue
+		}
+
+		ReorgProducer(&reorgMsg{
+			Log: types.Log{
+				Address:     common.HexToAddress(removed.Address),
+				BlockNumber: removed.BlockNumber,
+				BlockHash:   common.HexToHash(removed.BlockHash),
+				TxHash:      common.HexToHash(removed.TxHash),
+				TxIndex:     uint(removed.TxIndex),
+				Index:       uint(removed.LogIndex),
+				Removed:     true,
+			},
+			Backoff:         config.Get().Backoff,
+			ContractAddress: address,
+			Chain:           s.chain,
+			Retry:           0,
+		})
+
+		slog.Info("reorg found by backfill",
+			slog.Any("address", address),
+			slog.Any("block number", removed.BlockNumber),
+			slog.Any("txhash", removed.TxHash),
+		)
+	}
+
+	return nil
+}
+
+// findRemoved returns the earliest stored log of the address within the range that is no longer on chain
+func (s *Subscription) findRemoved(ctx context.Context, chainID int64, address string, from uint64, to uint64, canonical map[string]struct{}) (*model.Log, error) {
+	for page := uint64(1); ; page++ {
+		logs, err := service.GetLogs(ctx, &eventlog.GetLogParam{
+			ChainID:        chainID,
+			Addresses:      []string{address},
+			BlockNumberGTE: from,
+			BlockNumberLTE: to,
+			OrderBy:        2,
+			Pagination: &model.Pagination{
+				Page: page,
+				Size: backfillPageSize,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get logs: %w", err)
+		}
+
+		for _, log := range logs {
+			if _, ok := canonical[logKey(log.BlockHash, uint(log.LogIndex))]; !ok {
+				return log, nil
+			}
+		}
+
+		if len(logs) < backfillPageSize {
+			return nil, nil
+		}
+	}
+}
+
+func logKey(blockHash string, logIndex uint) string {
+	return fmt.Sprintf("%s:%d", blockHash, logIndex)
+}
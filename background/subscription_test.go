@@ -0,0 +1,142 @@
+package background
+
+import (
+	"context"
+	"errors"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/eth"
+	"evm_event_indexer/internal/metrics"
+	"evm_event_indexer/internal/testutil"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setConfig changes a config value for the test
+func setConfig[T any](t *testing.T, field *T, value T) {
+	old := *field
+	*field = value
+	t.Cleanup(func() { *field = old })
+}
+
+func Test_Subscription_Backfill(t *testing.T) {
+	ctx := context.TODO()
+	node := testutil.NewEthNode(t, testChainID)
+	contract := newTestContract(t)
+	chain := newTestChain(node, contract)
+
+	// the log of block 300 is still on chain, the log of block 1200 was removed while disconnected
+	kept := newTransferLog(contract, node.AddBlock(300, "a"), 0, 1)
+	removed := newTransferLog(contract, node.AddBlock(1200, "a"), 0, 2)
+	upsertScanned(t, contract, 1, node.AddBlock(1500, "a"), kept, removed)
+	node.AddLogs(kept)
+	node.AddBlock(1200, "b")
+	node.SetHead(2200)
+	drainReorgs()
+
+	s := NewSubscription(chain, nil)
+	s.lastSeen = 5
+
+	// the last batch fails, the batches before it are not checked again on the next reconnect
+	err := s.backfill(ctx, 2500)
+	assert.Error(t, err)
+	assert.Equal(t, uint64(1999), s.lastSeen)
+
+	reorgs := drainReorgs()
+	if assert.Len(t, reorgs, 1) {
+		assert.Equal(t, uint64(1200), reorgs[0].Log.BlockNumber)
+		assert.Equal(t, removed.BlockHash, reorgs[0].Log.BlockHash)
+		assert.True(t, reorgs[0].Log.Removed)
+	}
+
+	node.SetHead(2500)
+	require.NoError(t, s.backfill(ctx, 2500))
+	assert.Equal(t, uint64(2500), s.lastSeen)
+	assert.Empty(t, drainReorgs())
+}
+
+func Test_Subscription_Keepalive(t *testing.T) {
+	ctx := context.TODO()
+	node := testutil.NewEthNode(t, testChainID)
+	chain := newTestChain(node, newTestContract(t))
+	node.AddBlock(50, "a")
+	setConfig(t, &config.Get().Subscription.PingInterval, 20*time.Millisecond)
+
+	client, err := eth.NewChainClient(ctx, node.WS, testChainID)
+	require.NoError(t, err)
+	defer client.Close()
+
+	ch := make(chan types.Log)
+	sub, err := client.SubscribeFilterLogs(ch, ethereum.FilterQuery{})
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	s := NewSubscription(chain, nil)
+	done := make(chan error, 1)
+	go func() { done <- s.subscription(ctx, client, sub, ch) }()
+
+	// a connection that stops answering is given up although the subscription reports no error
+	require.Eventually(t, func() bool { return node.Calls("eth_blockNumber") >= 2 }, time.Second, 10*time.Millisecond)
+	node.Fail("eth_blockNumber", errors.New("connection lost"))
+
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "ping")
+	case <-time.After(time.Second):
+		t.Fatal("subscription did not end after the ping failed")
+	}
+
+	// the pings move the last seen block to the head
+	assert.Equal(t, uint64(50), s.lastSeen)
+}
+
+func Test_Subscription_Reconnect(t *testing.T) {
+	node := testutil.NewEthNode(t, testChainID)
+	contract := newTestContract(t)
+	chain := newTestChain(node, contract)
+	node.AddBlock(100, "a")
+	setConfig(t, &config.Get().Backoff, 10*time.Millisecond)
+	drainReorgs()
+
+	reconnects := promtest.ToFloat64(metrics.SubscriptionReconnects.WithLabelValues(strconv.FormatInt(testChainID, 10)))
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	s := NewSubscription(chain, nil)
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	require.Eventually(t, func() bool { return node.Calls("eth_subscribe") == 1 }, time.Second, 10*time.Millisecond)
+
+	// the connection drops while the chain moves on, the gap is backfilled on reconnect
+	node.AddBlock(200, "a")
+	node.Disconnect()
+
+	require.Eventually(t, func() bool { return node.Calls("eth_subscribe") == 2 }, 2*time.Second, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, node.Calls("eth_getLogs"), 1)
+	assert.Equal(t, reconnects+1, promtest.ToFloat64(metrics.SubscriptionReconnects.WithLabelValues(strconv.FormatInt(testChainID, 10))))
+
+	// removed logs of the new connection are sent to the reorg consumer
+	removed := newTransferLog(contract, node.AddBlock(150, "a"), 0, 1)
+	removed.Removed = true
+	node.Emit(removed)
+
+	select {
+	case msg := <-reorgChan:
+		assert.Equal(t, uint64(150), msg.Log.BlockNumber)
+		assert.Equal(t, contract, msg.ContractAddress)
+	case <-time.After(time.Second):
+		t.Fatal("removed log not sent to the reorg consumer")
+	}
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, uint64(200), s.lastSeen)
+}
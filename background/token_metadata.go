@@ -0,0 +1,100 @@
+package background
+
+import (
+	"context"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/erc20"
+	"evm_event_indexer/internal/eth"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var _ Worker = (*TokenRegistry)(nil)
+
+// TokenRegistry caches the metadata of the indexed tokens of a chain,
+// missing metadata is fetched on start and on each refresh, otherwise only the total supply is refreshed.
+type TokenRegistry struct {
+	chain *config.Chain
+}
+
+func NewTokenRegistry(chain *config.Chain) *TokenRegistry {
+	return &TokenRegistry{chain: chain}
+}
+
+func (r *TokenRegistry) Run(ctx context.Context) error {
+	ticker := time.NewTicker(config.Get().TokenMetadata.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.refresh(ctx); err != nil {
+			slog.Error("token metadata refresh error", slog.String("chain", r.chain.String()), slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *TokenRegistry) refresh(ctx context.Context) error {
+	client, err := eth.NewChainClient(ctx, r.chain.RpcHTTP, r.chain.ChainID)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	defer client.Close()
+
+	cached, err := service.GetChainTokenMetadata(ctx, r.chain.ChainID)
+	if err != nil {
+		return fmt.Errorf("failed to get token metadata: %w", err)
+	}
+
+	// read-only calls need no key
+	token := erc20.NewERC20Service(client, "")
+	for _, address := range r.chain.Addresses {
+		metadata := cached[model.NewTokenKey(r.chain.ChainID, address.Address)]
+		if err := r.refreshToken(ctx, token, address.Address, metadata); err != nil {
+			slog.Error("token metadata refresh error", slog.String("chain", r.chain.String()), slog.String("token", address.Address), slog.Any("error", err))
+		}
+	}
+
+	return nil
+}
+
+// refreshToken fetches the metadata of a token if not cached yet, otherwise refreshes the total supply
+func (r *TokenRegistry) refreshToken(ctx context.Context, token *erc20.ERC20Service, address string, cached *model.TokenMetadata) error {
+	callCtx, cancel := context.WithTimeout(ctx, config.Get().Timeout)
+	defer cancel()
+
+	now := time.Now()
+	if cached != nil {
+		supply, err := token.GetTotalSupply(callCtx, common.HexToAddress(address))
+		if err != nil {
+			return fmt.Errorf("failed to get total supply: %w", err)
+		}
+		return service.UpdateTokenSupply(ctx, r.chain.ChainID, address, supply, now)
+	}
+
+	metadata, err := token.GetMetadata(callCtx, common.HexToAddress(address))
+	if err != nil {
+		// not an ERC-20 contract, logs of the contract are returned unformatted
+		return fmt.Errorf("failed to get metadata: %w", err)
+	}
+
+	return service.SaveTokenMetadata(ctx, &model.TokenMetadata{
+		ChainID:     r.chain.ChainID,
+		Token:       address,
+		Name:        metadata.Name,
+		Symbol:      metadata.Symbol,
+		Decimals:    metadata.Decimals,
+		TotalSupply: metadata.TotalSupply,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+}
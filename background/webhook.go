@@ -0,0 +1,170 @@
+package background
+
+import (
+	"bytes"
+	"context"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/enum"
+	"evm_event_indexer/internal/metrics"
+	"evm_event_indexer/internal/safehttp"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/utils/hashing"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var _ Worker = (*WebhookWorker)(nil)
+
+// max length of the stored error of an attempt
+const maxDeliveryError = 1024
+
+// WebhookWorker posts the queued webhook deliveries, failed attempts are retried with exponential backoff.
+// Deliveries are leased while in flight, so several indexer instances can run the worker.
+type WebhookWorker struct {
+	client *http.Client
+}
+
+func NewWebhookWorker() *WebhookWorker {
+	return &WebhookWorker{
+		// only public addresses are dialed and redirects are not followed
+		client: safehttp.NewClient(config.Get().Webhook.Timeout),
+	}
+}
+
+func (w *WebhookWorker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(config.Get().Webhook.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := w.deliverBatch(ctx); err != nil {
+				slog.Error("webhook delivery error", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// deliverBatch claims the due deliveries and posts them concurrently
+func (w *WebhookWorker) deliverBatch(ctx context.Context) error {
+	// the lease outlives the attempts of the batch, an expired lease is claimed again
+	lease := 2 * config.Get().Webhook.Timeout
+	deliveries, webhooks, err := service.ClaimWebhookDeliveries(ctx, config.Get().Webhook.BatchSize, lease)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.deliver(ctx, delivery, webhooks[delivery.WebhookID])
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (w *WebhookWorker) deliver(ctx context.Context, delivery *model.WebhookDelivery, webhook *model.Webhook) {
+	now := time.Now()
+	delivery.UpdatedAt = now
+
+	switch {
+	case webhook == nil || webhook.Status != enum.WebhookStatusEnabled:
+		delivery.Status = enum.DeliveryStatusCancelled
+		delivery.LastError = "webhook disabled"
+	default:
+		var err error
+		delivery.Attempts++
+		delivery.LastStatusCode, err = w.post(ctx, delivery, webhook, now)
+		delivery.LastError = ""
+		status := "success"
+
+		switch {
+		case err == nil:
+			delivery.Status = enum.DeliveryStatusSucceeded
+		case delivery.Attempts >= config.Get().Webhook.MaxAttempts:
+			status = "failure"
+			delivery.Status = enum.DeliveryStatusFailed
+			delivery.LastError = truncate(err.Error(), maxDeliveryError)
+		default:
+			status = "failure"
+			delivery.NextAttemptAt = now.Add(webhookBackoff(delivery.Attempts))
+			delivery.LastError = truncate(err.Error(), maxDeliveryError)
+		}
+
+		metrics.WebhookDeliveries.WithLabelValues(delivery.Type, status).Inc()
+	}
+
+	if err := service.SaveWebhookDeliveryResult(ctx, delivery); err != nil {
+		slog.Error("save webhook delivery result error", slog.Any("error", err), slog.Int64("delivery", delivery.ID))
+	}
+}
+
+// post sends the signed payload, any 2xx response acknowledges the delivery
+func (w *WebhookWorker) post(ctx context.Context, delivery *model.WebhookDelivery, webhook *model.Webhook, now time.Time) (int32, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Id", strconv.FormatInt(webhook.ID, 10))
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Event", delivery.Type)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", SignWebhook(webhook.Secret, timestamp, delivery.Payload))
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// drain the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return int32(res.StatusCode), fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return int32(res.StatusCode), nil
+}
+
+// SignWebhook returns the signature header of a payload, HMAC-SHA256 of "<timestamp>.<body>" keyed by the webhook secret,
+// receivers recompute it and reject stale timestamps.
+func SignWebhook(secret string, timestamp string, payload []byte) string {
+	data := make([]byte, 0, len(timestamp)+1+len(payload))
+	data = append(data, timestamp...)
+	data = append(data, '.')
+	data = append(data, payload...)
+
+	return "sha256=" + hashing.HmacSha256([]byte(secret), data)
+}
+
+// webhookBackoff returns the retry delay after the given number of failed attempts
+func webhookBackoff(attempts int32) time.Duration {
+	backoff := config.Get().Webhook.Backoff
+	for i := int32(1); i < attempts && backoff < config.Get().Webhook.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, config.Get().Webhook.MaxBackoff)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
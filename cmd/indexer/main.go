@@ -5,8 +5,12 @@ import (
 	"evm_event_indexer/background"
 	"evm_event_indexer/internal/config"
 	"evm_event_indexer/internal/decoder"
+	"evm_event_indexer/internal/eth"
+	"evm_event_indexer/internal/outbox"
+	"evm_event_indexer/internal/sink"
 	"evm_event_indexer/internal/slog"
 	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/internal/stream"
 	"fmt"
 	"os"
 	"os/signal"
@@ -49,24 +53,49 @@ func main() {
 	// register reorg consumer
 	bgManager.AddWorker(background.NewReorgConsumer())
 
+	// register webhook delivery worker
+	bgManager.AddWorker(background.NewWebhookWorker())
+
+	// register export job worker
+	bgManager.AddWorker(background.NewExportWorker())
+
+	// register outbox relay, messages written without a consumer would never be delivered nor cleaned
+	if config.Get().Outbox.Enabled {
+		if len(outbox.Consumers()) == 0 {
+			panic("outbox.enabled requires at least one sink, set sink.redis_stream or register a broker")
+		}
+		bgManager.AddWorker(background.NewOutboxRelay())
+	}
+
 	// register scanners and subscriptions
-	for _, scan := range config.Get().Scanners {
+	for i := range config.Get().Scanners {
+		chain := &config.Get().Scanners[i]
 
-		addresses := []string{}
-		for _, address := range scan.Addresses {
-			addresses = append(addresses, address.Address)
+		addressTopics := map[common.Address][]common.Hash{}
+		for _, address := range chain.Addresses {
 			topics := []common.Hash{}
 
 			for _, topic := range address.Topics {
 				topics = append(topics, common.Hash(crypto.Keccak256([]byte(topic))))
 			}
+			addressTopics[common.HexToAddress(address.Address)] = topics
 
 			// register scanner, each contract has its own scanner
-			bgManager.AddWorker(background.NewScanner(scan.RpcHTTP, address.Address, [][]common.Hash{topics}, scan.BatchSize))
+			bgManager.AddWorker(background.NewScanner(chain, address.Address, [][]common.Hash{topics}))
 		}
 
 		// register subscription, addresses on the same chain share the same subscription
-		bgManager.AddWorker(background.NewSubscription(scan.RpcHTTP, scan.RpcWS, addresses))
+		bgManager.AddWorker(background.NewSubscription(chain, addressTopics))
+
+		// register balance reconciliation of the chain
+		if config.Get().Reconcile.Enabled {
+			bgManager.AddWorker(background.NewReconciler(chain))
+		}
+
+		// register token metadata cache of the chain
+		if config.Get().TokenMetadata.Enabled {
+			bgManager.AddWorker(background.NewTokenRegistry(chain))
+		}
 	}
 
 	// global context
@@ -93,4 +122,7 @@ func initInfra() {
 	config.LoadConfig("./config/config.yaml")
 	slog.InitSlog()
 	decoder.InitDecoder()
+	eth.InitHeaderCache()
+	stream.InitHub(config.Get().Stream.BufferSize)
+	sink.InitSink()
 }
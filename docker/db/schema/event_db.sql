@@ -19,14 +19,20 @@ CREATE TABLE `event_db`.`event_log` (
   `topic_3` varchar(128) COMMENT 'indexed parameter 3',
   `decoded_event` json NOT NULL COMMENT 'decoded event',
   `block_timestamp` timestamp NOT NULL COMMENT 'block timestamp',
+  `confirmed` tinyint unsigned NOT NULL DEFAULT 1 COMMENT 'confirmed by scanner (1: confirmed, 0: pushed by live subscription)',
+  `l1_block_number` bigint unsigned NOT NULL DEFAULT 0 COMMENT 'L1 origin block number of L2 chains (0: not an L2 chain)',
+  `l1_block_hash` varchar(128) NOT NULL DEFAULT '' COMMENT 'L1 origin block hash of OP Stack chains',
   `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'created at',
   PRIMARY KEY (`id`),
   UNIQUE KEY (`chain_id`, `address`, `block_number`, `tx_index`, `log_index`),
   KEY `idx_chainId_addr_bt` (`chain_id`, `address`, `block_timestamp`), -- for targeting contract
+  KEY `idx_chainId_addr_bn` (`chain_id`, `address`, `block_number`), -- for targeting contract by block range
+  KEY `idx_chainId_bn` (`chain_id`, `block_number`), -- for targeting block range
   KEY `idx_chainId_t0_bt` (`chain_id`, `topic_0`, `block_timestamp`), -- for targeting event signature
   KEY `idx_chainId_t0_t1_bt` (`chain_id`, `topic_0`, `topic_1`, `block_timestamp`), -- for targeting event signature and indexed parameter 1
   KEY `idx_chainId_t0_t2_bt` (`chain_id`, `topic_0`, `topic_2`, `block_timestamp`), -- for targeting event signature and indexed parameter 2
-  KEY `idx_chainId_txHash` (`chain_id`, `tx_hash`)  -- for targeting tx hash
+  KEY `idx_chainId_txHash` (`chain_id`, `tx_hash`), -- for targeting tx hash
+  KEY `idx_chainId_l1bn` (`chain_id`, `l1_block_number`) -- for joining L2 logs with L1 blocks
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='event log';
 
 -- block syncranization status
@@ -37,4 +43,220 @@ CREATE TABLE `event_db`.`block_sync` (
   `last_sync_hash` varchar(128) NOT NULL COMMENT 'last synced block hash',
   `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated at',
   PRIMARY KEY (`chain_id`, `address`)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='block syncranization status';
\ No newline at end of file
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='block syncranization status';
+-- block header of L2 chains with the L1 origin, one row per block hash so headers of reorged blocks are harmless
+CREATE TABLE `event_db`.`block_header` (
+  `chain_id` bigint unsigned NOT NULL COMMENT 'chain id',
+  `block_hash` varchar(128) NOT NULL COMMENT 'block hash',
+  `block_number` bigint unsigned NOT NULL COMMENT 'block number',
+  `parent_hash` varchar(128) NOT NULL COMMENT 'parent block hash',
+  `block_timestamp` timestamp NOT NULL COMMENT 'block timestamp',
+  `l1_block_number` bigint unsigned NOT NULL COMMENT 'L1 origin block number',
+  `l1_block_hash` varchar(128) NOT NULL DEFAULT '' COMMENT 'L1 origin block hash, empty on Arbitrum',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'created at',
+  PRIMARY KEY (`chain_id`, `block_hash`),
+  KEY `idx_chainId_bn` (`chain_id`, `block_number`), -- for targeting block number
+  KEY `idx_chainId_l1bn` (`chain_id`, `l1_block_number`) -- for joining L2 blocks with L1 blocks
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='L2 block header';
+
+-- queryable decoded event arguments, deleted together with the event log
+CREATE TABLE `event_db`.`event_arg` (
+  `chain_id` bigint unsigned NOT NULL COMMENT 'chain id',
+  `address` varchar(128) NOT NULL COMMENT 'contract address',
+  `block_number` bigint unsigned NOT NULL COMMENT 'block number',
+  `tx_index` bigint unsigned NOT NULL COMMENT 'tx index',
+  `log_index` bigint unsigned NOT NULL COMMENT 'log index',
+  `name` varchar(64) NOT NULL COMMENT 'argument name',
+  `value` varchar(128) NOT NULL COMMENT 'normalized value (uint256: zero padded to 78 digits, address: lowercase hex)',
+  PRIMARY KEY (`chain_id`, `address`, `block_number`, `tx_index`, `log_index`, `name`),
+  KEY `idx_chainId_name_value` (`chain_id`, `name`, `value`), -- for filtering by decoded argument
+  CONSTRAINT `fk_event_arg_event_log` FOREIGN KEY (`chain_id`, `address`, `block_number`, `tx_index`, `log_index`)
+    REFERENCES `event_db`.`event_log` (`chain_id`, `address`, `block_number`, `tx_index`, `log_index`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='decoded event argument';
+
+-- addresses involved in an event log (decoded address arguments, or address topics of undecoded logs), deleted together with the event log
+CREATE TABLE `event_db`.`log_participant` (
+  `chain_id` bigint unsigned NOT NULL COMMENT 'chain id',
+  `address` varchar(128) NOT NULL COMMENT 'contract address',
+  `block_number` bigint unsigned NOT NULL COMMENT 'block number',
+  `tx_index` bigint unsigned NOT NULL COMMENT 'tx index',
+  `log_index` bigint unsigned NOT NULL COMMENT 'log index',
+  `participant` varchar(128) NOT NULL COMMENT 'participant address (lowercase hex)',
+  PRIMARY KEY (`chain_id`, `address`, `block_number`, `tx_index`, `log_index`, `participant`),
+  KEY `idx_chainId_participant_bn` (`chain_id`, `participant`, `block_number`, `tx_index`, `log_index`), -- for the latest logs of an address
+  CONSTRAINT `fk_log_participant_event_log` FOREIGN KEY (`chain_id`, `address`, `block_number`, `tx_index`, `log_index`)
+    REFERENCES `event_db`.`event_log` (`chain_id`, `address`, `block_number`, `tx_index`, `log_index`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='event log participant';
+
+-- webhook registered by a user, receives the logs matching the filter
+CREATE TABLE `event_db`.`webhook` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT 'webhook id',
+  `user_id` bigint unsigned NOT NULL COMMENT 'owner user id',
+  `url` varchar(2048) NOT NULL COMMENT 'delivery url',
+  `secret` varchar(256) NOT NULL COMMENT 'HMAC key of the payload signature',
+  `filter` json NOT NULL COMMENT 'normalized log filter',
+  `status` tinyint unsigned NOT NULL COMMENT 'status (1: enabled, 2: disabled)',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'created at',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated at',
+  PRIMARY KEY (`id`),
+  KEY `idx_userId` (`user_id`), -- for listing the webhooks of a user
+  KEY `idx_status` (`status`) -- for matching enabled webhooks
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='webhook';
+
+-- delivery queue and log of webhook payloads, deleted together with the webhook
+CREATE TABLE `event_db`.`webhook_delivery` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT 'delivery id',
+  `webhook_id` bigint unsigned NOT NULL COMMENT 'webhook id',
+  `type` varchar(16) NOT NULL COMMENT 'event type (log, removed)',
+  `chain_id` bigint unsigned NOT NULL COMMENT 'chain id',
+  `address` varchar(128) NOT NULL COMMENT 'contract address',
+  `block_number` bigint unsigned NOT NULL COMMENT 'block number of the log, or the block after which logs were removed',
+  `tx_index` bigint unsigned NOT NULL DEFAULT 0 COMMENT 'tx index',
+  `log_index` bigint unsigned NOT NULL DEFAULT 0 COMMENT 'log index',
+  `payload` json NOT NULL COMMENT 'signed json body',
+  `status` tinyint unsigned NOT NULL COMMENT 'status (1: pending, 2: succeeded, 3: failed, 4: cancelled)',
+  `attempts` int unsigned NOT NULL DEFAULT 0 COMMENT 'number of attempts',
+  `next_attempt_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'next attempt time of a pending delivery',
+  `last_status_code` int unsigned NOT NULL DEFAULT 0 COMMENT 'http status of the last attempt',
+  `last_error` varchar(1024) NOT NULL DEFAULT '' COMMENT 'error of the last attempt',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'created at',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated at',
+  PRIMARY KEY (`id`),
+  KEY `idx_status_nextAttemptAt` (`status`, `next_attempt_at`), -- for claiming due deliveries
+  KEY `idx_webhookId` (`webhook_id`, `id`), -- for listing the deliveries of a webhook
+  KEY `idx_chainId_addr_bn` (`chain_id`, `address`, `block_number`), -- for retracting the deliveries of removed logs
+  CONSTRAINT `fk_webhook_delivery_webhook` FOREIGN KEY (`webhook_id`) REFERENCES `event_db`.`webhook` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='webhook delivery';
+
+-- current ERC-20 balance of each holder, derived from the Transfer logs in the same transaction
+CREATE TABLE `event_db`.`token_balance` (
+  `chain_id` bigint unsigned NOT NULL COMMENT 'chain id',
+  `token` varchar(128) NOT NULL COMMENT 'token contract address',
+  `holder` varchar(128) NOT NULL COMMENT 'holder address (lowercase hex)',
+  `balance` varchar(80) NOT NULL COMMENT 'balance zero padded to 78 digits, negative balances are prefixed with -',
+  `block_number` bigint unsigned NOT NULL COMMENT 'block number of the last balance change',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated at',
+  PRIMARY KEY (`chain_id`, `token`, `holder`),
+  KEY `idx_chainId_token_balance` (`chain_id`, `token`, `balance`), -- for listing the top holders
+  KEY `idx_chainId_token_bn` (`chain_id`, `token`, `block_number`) -- for sampling the latest changed holders
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='token balance';
+
+-- latest ERC-20 approval of each owner and spender, derived from the Approval logs in the same transaction
+CREATE TABLE `event_db`.`token_allowance` (
+  `chain_id` bigint unsigned NOT NULL COMMENT 'chain id',
+  `token` varchar(128) NOT NULL COMMENT 'token contract address',
+  `owner` varchar(128) NOT NULL COMMENT 'owner address (lowercase hex)',
+  `spender` varchar(128) NOT NULL COMMENT 'spender address (lowercase hex)',
+  `allowance` varchar(80) NOT NULL COMMENT 'allowance zero padded to 78 digits, revoked allowances are kept with zero',
+  `unlimited` tinyint unsigned NOT NULL COMMENT 'allowance of at least the max uint96 (1: unlimited, 0: limited)',
+  `block_number` bigint unsigned NOT NULL COMMENT 'block number of the latest Approval',
+  `tx_hash` varchar(128) NOT NULL COMMENT 'tx hash of the latest Approval',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated at',
+  PRIMARY KEY (`chain_id`, `token`, `owner`, `spender`),
+  KEY `idx_chainId_owner_unlimited` (`chain_id`, `owner`, `unlimited`), -- for listing the approvals of an owner
+  KEY `idx_chainId_token_bn` (`chain_id`, `token`, `block_number`) -- for reverting the reorged approvals
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='token allowance';
+
+-- cached ERC-20 metadata of each indexed token, the total supply is refreshed periodically
+CREATE TABLE `event_db`.`token_metadata` (
+  `chain_id` bigint unsigned NOT NULL COMMENT 'chain id',
+  `token` varchar(128) NOT NULL COMMENT 'token contract address',
+  `name` varchar(255) NOT NULL DEFAULT '' COMMENT 'token name, empty if not implemented',
+  `symbol` varchar(64) NOT NULL DEFAULT '' COMMENT 'token symbol, empty if not implemented',
+  `decimals` tinyint unsigned NOT NULL COMMENT 'token decimals',
+  `total_supply` varchar(80) NOT NULL COMMENT 'decimal total supply',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'created at',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'last refresh of the total supply',
+  PRIMARY KEY (`chain_id`, `token`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='token metadata';
+
+-- last comparison of each sampled holder balance with balanceOf on chain
+CREATE TABLE `event_db`.`token_reconcile` (
+  `chain_id` bigint unsigned NOT NULL COMMENT 'chain id',
+  `token` varchar(128) NOT NULL COMMENT 'token contract address',
+  `holder` varchar(128) NOT NULL COMMENT 'holder address (lowercase hex)',
+  `block_number` bigint unsigned NOT NULL COMMENT 'synced block both balances are read at',
+  `ledger_balance` varchar(80) NOT NULL COMMENT 'signed decimal balance derived from the Transfer logs',
+  `chain_balance` varchar(80) NOT NULL COMMENT 'decimal balanceOf at the block',
+  `drift` varchar(80) NOT NULL COMMENT 'signed decimal chain balance minus ledger balance',
+  `drifted` tinyint unsigned NOT NULL COMMENT 'ledger does not match the chain (1: drifted, 0: matched)',
+  `checked_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'checked at',
+  PRIMARY KEY (`chain_id`, `token`, `holder`),
+  KEY `idx_drifted_checkedAt` (`drifted`, `checked_at`) -- for listing the drifted holders
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='token balance reconciliation';
+
+-- balance change of a holder by each Transfer log, for balances at a block and reverting reorged changes
+CREATE TABLE `event_db`.`token_balance_history` (
+  `chain_id` bigint unsigned NOT NULL COMMENT 'chain id',
+  `token` varchar(128) NOT NULL COMMENT 'token contract address',
+  `holder` varchar(128) NOT NULL COMMENT 'holder address (lowercase hex)',
+  `block_number` bigint unsigned NOT NULL COMMENT 'block number',
+  `tx_index` bigint unsigned NOT NULL COMMENT 'tx index',
+  `log_index` bigint unsigned NOT NULL COMMENT 'log index',
+  `delta` varchar(80) NOT NULL COMMENT 'signed decimal balance change',
+  `balance` varchar(80) NOT NULL COMMENT 'balance after the change, same form as token_balance.balance',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'created at',
+  PRIMARY KEY (`chain_id`, `token`, `holder`, `block_number`, `tx_index`, `log_index`), -- for balances at a block
+  KEY `idx_chainId_token_bn` (`chain_id`, `token`, `block_number`) -- for reverting the changes after a block
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='token balance history';
+
+-- log count, volume and unique senders of each event per hour and day, maintained in the same transaction as the logs
+CREATE TABLE `event_db`.`event_rollup` (
+  `chain_id` bigint unsigned NOT NULL COMMENT 'chain id',
+  `address` varchar(128) NOT NULL COMMENT 'contract address',
+  `topic_0` varchar(128) NOT NULL COMMENT 'event signature',
+  `bucket` varchar(8) NOT NULL COMMENT 'bucket size (hour, day)',
+  `bucket_start` timestamp NOT NULL COMMENT 'bucket start (UTC)',
+  `log_count` bigint unsigned NOT NULL COMMENT 'number of confirmed logs',
+  `volume` varchar(100) NOT NULL COMMENT 'decimal sum of the first uint256 decoded field',
+  `senders` bigint unsigned NOT NULL COMMENT 'unique values of the first address decoded field',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'updated at',
+  PRIMARY KEY (`chain_id`, `address`, `bucket`, `bucket_start`, `topic_0`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='event rollup';
+
+-- log count of each sender per rollup bucket, for maintaining the unique senders
+CREATE TABLE `event_db`.`event_rollup_sender` (
+  `chain_id` bigint unsigned NOT NULL COMMENT 'chain id',
+  `address` varchar(128) NOT NULL COMMENT 'contract address',
+  `topic_0` varchar(128) NOT NULL COMMENT 'event signature',
+  `bucket` varchar(8) NOT NULL COMMENT 'bucket size (hour, day)',
+  `bucket_start` timestamp NOT NULL COMMENT 'bucket start (UTC)',
+  `sender` varchar(128) NOT NULL COMMENT 'sender address (lowercase hex)',
+  `log_count` bigint NOT NULL COMMENT 'number of confirmed logs of the sender',
+  PRIMARY KEY (`chain_id`, `address`, `bucket`, `bucket_start`, `topic_0`, `sender`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='event rollup sender';
+
+-- outbox of log inserts and deletions, written in the same transaction as the logs and delivered to the consumers after commit
+CREATE TABLE `event_db`.`event_outbox` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT 'message id',
+  `type` varchar(16) NOT NULL COMMENT 'message type (log, tombstone)',
+  `chain_id` bigint unsigned NOT NULL COMMENT 'chain id',
+  `address` varchar(128) NOT NULL COMMENT 'contract address',
+  `block_number` bigint unsigned NOT NULL COMMENT 'block number',
+  `tx_index` bigint unsigned NOT NULL COMMENT 'tx index',
+  `log_index` bigint unsigned NOT NULL COMMENT 'log index',
+  `message_key` varchar(256) NOT NULL COMMENT 'log identity (chain_id:address:block_number:tx_index:log_index)',
+  `payload` json COMMENT 'json encoded log, null for tombstones',
+  `claimed_at` timestamp NULL DEFAULT NULL COMMENT 'claimed by a relay at, claimed again after the lease when not delivered',
+  `delivered_at` timestamp NULL DEFAULT NULL COMMENT 'delivered to every consumer at, null until delivered',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'created at',
+  PRIMARY KEY (`id`),
+  KEY `idx_deliveredAt_id` (`delivered_at`, `id`) -- for claiming undelivered messages and deleting delivered ones
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='event outbox';
+
+-- export jobs of the logs matching a filter, the files are written to the export directory and deleted with the job after the retention
+CREATE TABLE `event_db`.`export_job` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT 'export id',
+  `user_id` bigint unsigned NOT NULL COMMENT 'owner user id',
+  `format` varchar(16) NOT NULL COMMENT 'file format (csv, ndjson, parquet)',
+  `filter` json NOT NULL COMMENT 'normalized log filter and range',
+  `status` tinyint unsigned NOT NULL COMMENT 'status (1: pending, 2: running, 3: succeeded, 4: failed)',
+  `file_name` varchar(256) NOT NULL DEFAULT '' COMMENT 'file in the export directory, set when succeeded',
+  `row_count` bigint unsigned NOT NULL DEFAULT 0 COMMENT 'number of exported logs',
+  `error` varchar(1024) NOT NULL DEFAULT '' COMMENT 'error of a failed job',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'created at',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated at',
+  PRIMARY KEY (`id`),
+  KEY `idx_status_updatedAt` (`status`, `updated_at`), -- for claiming pending jobs and deleting expired ones
+  KEY `idx_userId` (`user_id`) -- for the jobs of a user
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='export job';
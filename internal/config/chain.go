@@ -0,0 +1,170 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// chain types, L2 chains have their blocks derived from an L1 origin block
+const (
+	ChainTypeEthereum = "ethereum"
+	ChainTypeOptimism = "optimism" // OP Stack chains, e.g. Optimism, Base
+	ChainTypeArbitrum = "arbitrum" // Arbitrum Nitro chains
+)
+
+// head block tags, L2 sequencer blocks are "latest", blocks derived from L1 data are "safe",
+// blocks derived from finalized L1 data are "finalized"
+const (
+	HeadLatest    = "latest"
+	HeadSafe      = "safe"
+	HeadFinalized = "finalized"
+)
+
+type (
+	// Chain is the scanner config of a chain, loaded from the scanner json file.
+	// Tuning fields left empty fall back to the global config.
+	Chain struct {
+		Name          string         `json:"name"`
+		ChainID       int64          `json:"chain_id"` // expected chain id, checked against eth_chainId
+		Type          string         `json:"type"`     // ethereum (default), optimism or arbitrum
+		Head          string         `json:"head"`     // block tag the scanner follows, latest (default), safe or finalized
+		RpcHTTP       string         `json:"rpc_http"`
+		RpcWS         string         `json:"rpc_ws"`
+		BlockTime     Duration       `json:"block_time"`    // average block time
+		Confirmations uint64         `json:"confirmations"` // blocks behind head the scanner stops at
+		StartBlock    uint64         `json:"start_block"`   // first block scanned for a new contract
+		ReorgWindow   int32          `json:"reorg_window"`
+		BatchSize     int32          `json:"batch_size"`
+		PollInterval  Duration       `json:"poll_interval"`
+		LiveIndexing  bool           `json:"live_indexing"` // insert new logs from the subscription before the scanner confirms them
+		Addresses     []ChainAddress `json:"addresses"`
+	}
+
+	ChainAddress struct {
+		Address string   `json:"address"`
+		Topics  []string `json:"topics"`
+	}
+
+	// Duration is a time.Duration written as a duration string in json, e.g. "12s"
+	Duration time.Duration
+)
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration should be a string, e.g. \"12s\": %w", err)
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// String returns the chain name, falls back to the chain id
+func (c *Chain) String() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return fmt.Sprintf("chain-%d", c.ChainID)
+}
+
+// GetType returns the chain type, defaults to ethereum
+func (c *Chain) GetType() string {
+	if c.Type == "" {
+		return ChainTypeEthereum
+	}
+	return c.Type
+}
+
+// GetHead returns the head block tag the scanner follows, defaults to latest
+func (c *Chain) GetHead() string {
+	if c.Head == "" {
+		return HeadLatest
+	}
+	return c.Head
+}
+
+// IsL2 reports whether blocks of the chain have an L1 origin
+func (c *Chain) IsL2() bool {
+	return c.GetType() != ChainTypeEthereum
+}
+
+// GetReorgWindow returns the reorg window of the chain, falls back to reorg_window
+func (c *Chain) GetReorgWindow() int32 {
+	if c.ReorgWindow > 0 {
+		return c.ReorgWindow
+	}
+	return Get().ReorgWindow
+}
+
+// GetStartBlock returns the first block scanned of the chain, falls back to start_block
+func (c *Chain) GetStartBlock() uint64 {
+	if c.StartBlock > 0 {
+		return c.StartBlock
+	}
+	return Get().StartBlock
+}
+
+// GetPollInterval returns the scanner interval of the chain,
+// falls back to the block time, then log_scanner_interval
+func (c *Chain) GetPollInterval() time.Duration {
+	if c.PollInterval > 0 {
+		return time.Duration(c.PollInterval)
+	}
+	if c.BlockTime > 0 {
+		return time.Duration(c.BlockTime)
+	}
+	return Get().LogScannerInterval
+}
+
+func (c *Chain) Validate() error {
+	if c.ChainID == 0 {
+		return fmt.Errorf("scanner.chain_id is required")
+	}
+	if c.RpcHTTP == "" {
+		return fmt.Errorf("scanner.rpc_http is required, chain: %s", c)
+	}
+	if c.RpcWS == "" {
+		return fmt.Errorf("scanner.rpc_ws is required, chain: %s", c)
+	}
+	if len(c.Addresses) == 0 {
+		return fmt.Errorf("scanner.address is required, chain: %s", c)
+	}
+
+	for _, address := range c.Addresses {
+		if address.Address == "" {
+			return fmt.Errorf("scanner.address is required, chain: %s", c)
+		}
+	}
+
+	if c.BatchSize == 0 {
+		return fmt.Errorf("scanner.batch_size is required, chain: %s", c)
+	}
+
+	switch c.GetType() {
+	case ChainTypeEthereum, ChainTypeOptimism, ChainTypeArbitrum:
+	default:
+		return fmt.Errorf("scanner.type %s is not supported, chain: %s", c.Type, c)
+	}
+
+	switch c.GetHead() {
+	case HeadLatest, HeadSafe, HeadFinalized:
+	default:
+		return fmt.Errorf("scanner.head %s is not supported, chain: %s", c.Head, c)
+	}
+
+	if c.ReorgWindow < 0 {
+		return fmt.Errorf("scanner.reorg_window should not be negative, chain: %s", c)
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package config_test
+
+import (
+	"encoding/json"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/testutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChain(t *testing.T) {
+	testutil.SetupTestConfig()
+
+	chain := new(config.Chain)
+	err := json.Unmarshal([]byte(`{"name":"optimism","chain_id":10,"block_time":"2s","reorg_window":30,"start_block":105235063}`), chain)
+	assert.NoError(t, err)
+	assert.Equal(t, "optimism", chain.String())
+	assert.Equal(t, int32(30), chain.GetReorgWindow())
+	assert.Equal(t, uint64(105235063), chain.GetStartBlock())
+	// poll interval falls back to the block time
+	assert.Equal(t, 2*time.Second, chain.GetPollInterval())
+
+	// empty tuning falls back to the global config
+	chain = &config.Chain{ChainID: 1}
+	assert.Equal(t, "chain-1", chain.String())
+	assert.Equal(t, config.Get().ReorgWindow, chain.GetReorgWindow())
+	assert.Equal(t, config.Get().StartBlock, chain.GetStartBlock())
+	assert.Equal(t, config.Get().LogScannerInterval, chain.GetPollInterval())
+
+	err = json.Unmarshal([]byte(`{"poll_interval":12}`), chain)
+	assert.Error(t, err)
+
+	// chain type and head default to an L1 chain following latest
+	assert.Equal(t, config.ChainTypeEthereum, chain.GetType())
+	assert.Equal(t, config.HeadLatest, chain.GetHead())
+	assert.False(t, chain.IsL2())
+
+	chain = &config.Chain{ChainID: 10, RpcHTTP: "http", RpcWS: "ws", BatchSize: 1, Addresses: []config.ChainAddress{{Address: "0x1"}}, Type: config.ChainTypeOptimism, Head: config.HeadSafe}
+	assert.True(t, chain.IsL2())
+	assert.NoError(t, chain.Validate())
+
+	chain.Head = "pending"
+	assert.Error(t, chain.Validate())
+
+	// scanner file is loaded into chains
+	assert.NotEmpty(t, config.Get().Scanners)
+	assert.NoError(t, config.Get().Scanners[0].Validate())
+}
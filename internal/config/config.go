@@ -9,16 +9,8 @@ type Config struct {
 	ScannerPath  string        `yaml:"scanner_path"`
 	StartBlock   uint64        `yaml:"start_block"`
 	WaitForStart time.Duration `yaml:"wait_for_start"`
-	Scanners     []struct {    // json file, should located in the same directory as the config file
-		RpcHTTP   string `json:"rpc_http"`
-		RpcWS     string `json:"rpc_ws"`
-		BatchSize int32  `json:"batch_size"`
-		Addresses []struct {
-			Address string   `json:"address"`
-			Topics  []string `json:"topics"`
-		} `json:"addresses"`
-	}
-	Decoders []struct {
+	Scanners     []Chain       // json file, should located in the same directory as the config file
+	Decoders     []struct {
 		Name      string `yaml:"name"`
 		Signature string `yaml:"signature"`
 	} `yaml:"decoders"`
@@ -30,12 +22,66 @@ type Config struct {
 	Backoff            time.Duration `yaml:"backoff"`
 	MaxBackoff         time.Duration `yaml:"max_backoff"`
 	API                struct {
-		Port    string        `yaml:"port"`
-		Timeout time.Duration `yaml:"timeout"`
+		Port            string        `yaml:"port"`
+		Timeout         time.Duration `yaml:"timeout"`
+		MaxTimeSpan     time.Duration `yaml:"max_time_span"`     // maximum time range of a logs query
+		MaxBlockSpan    uint64        `yaml:"max_block_span"`    // maximum block range of a logs query
+		MaxFilterValues int           `yaml:"max_filter_values"` // maximum number of values of a list filter of a logs query
+		MaxStatsBuckets int           `yaml:"max_stats_buckets"` // maximum number of buckets of a stats query
 	}
 	Metrics struct {
 		Port string `yaml:"port"`
 	} `yaml:"metrics"`
+	Subscription struct {
+		PingInterval time.Duration `yaml:"ping_interval"` // keepalive interval of websocket subscriptions
+	} `yaml:"subscription"`
+	HeaderCache struct {
+		Size  int           `yaml:"size"`  // number of headers kept in memory
+		Redis bool          `yaml:"redis"` // share headers through redis
+		TTL   time.Duration `yaml:"ttl"`   // ttl of headers stored in redis
+	} `yaml:"header_cache"`
+	Stream struct {
+		BufferSize   int           `yaml:"buffer_size"`   // number of events buffered per stream client before it is dropped
+		PingInterval time.Duration `yaml:"ping_interval"` // keepalive interval of stream clients
+		MaxReplay    int           `yaml:"max_replay"`    // maximum number of stored logs replayed when resuming from a cursor
+	} `yaml:"stream"`
+	Webhook struct {
+		Interval     time.Duration `yaml:"interval"`     // polling interval of due deliveries
+		BatchSize    uint64        `yaml:"batch_size"`   // number of deliveries claimed per poll
+		Timeout      time.Duration `yaml:"timeout"`      // timeout of a delivery request
+		MaxAttempts  int32         `yaml:"max_attempts"` // a delivery is failed after the max attempts
+		Backoff      time.Duration `yaml:"backoff"`      // retry delay after the first failure, doubled on each failure
+		MaxBackoff   time.Duration `yaml:"max_backoff"`
+		RequireHTTPS bool          `yaml:"require_https"` // reject webhook urls with the http scheme
+	} `yaml:"webhook"`
+	Reconcile struct {
+		Enabled    bool          `yaml:"enabled"`     // compare sampled ledger balances with balanceOf on chain
+		Interval   time.Duration `yaml:"interval"`    // interval between reconciliation runs
+		SampleSize uint64        `yaml:"sample_size"` // number of holders sampled per token and run
+	} `yaml:"reconcile"`
+	TokenMetadata struct {
+		Enabled         bool          `yaml:"enabled"`          // cache name, symbol, decimals and total supply of the indexed tokens
+		RefreshInterval time.Duration `yaml:"refresh_interval"` // interval between total supply refreshes, missing metadata is fetched on each refresh
+	} `yaml:"token_metadata"`
+	Outbox struct {
+		Enabled   bool          `yaml:"enabled"`    // write log inserts and deletions to the outbox and deliver them to the consumers
+		Interval  time.Duration `yaml:"interval"`   // polling interval of the outbox relay
+		BatchSize uint64        `yaml:"batch_size"` // number of messages delivered per batch
+		Lease     time.Duration `yaml:"lease"`      // claimed messages not marked delivered within the lease are claimed again
+		Retention time.Duration `yaml:"retention"`  // delivered messages are deleted from the outbox after the retention
+	} `yaml:"outbox"`
+	Export struct {
+		Dir       string        `yaml:"dir"`        // directory of the finished export files
+		Secret    string        `yaml:"secret"`     // HMAC key of the download links
+		BatchSize uint64        `yaml:"batch_size"` // number of logs read per query while exporting
+		Interval  time.Duration `yaml:"interval"`   // polling interval of pending export jobs
+		Timeout   time.Duration `yaml:"timeout"`    // a running job is abandoned after the timeout and claimed again
+		Retention time.Duration `yaml:"retention"`  // finished jobs and their files are deleted after the retention
+	} `yaml:"export"`
+	Sink struct {
+		RedisStream string `yaml:"redis_stream"`  // redis stream on the cache db, empty disables the redis sink
+		RedisMaxLen int64  `yaml:"redis_max_len"` // approximate max length of the redis stream, 0 means unlimited
+	} `yaml:"sink"`
 	Argon2 struct {
 		Time    uint32 `yaml:"time"`
 		Memory  uint32 `yaml:"memory"`
@@ -96,26 +142,16 @@ func (c Config) Validate() error {
 		return fmt.Errorf("scanner is required")
 	}
 
+	chainIDs := make(map[int64]struct{}, len(c.Scanners))
 	for _, scanner := range c.Scanners {
-		if scanner.RpcHTTP == "" {
-			return fmt.Errorf("scanner.rpc_http is required")
-		}
-		if scanner.RpcWS == "" {
-			return fmt.Errorf("scanner.rpc_ws is required")
-		}
-		if len(scanner.Addresses) == 0 {
-			return fmt.Errorf("scanner.address is required")
-		}
-
-		for _, address := range scanner.Addresses {
-			if address.Address == "" {
-				return fmt.Errorf("scanner.address is required")
-			}
+		if err := scanner.Validate(); err != nil {
+			return err
 		}
 
-		if scanner.BatchSize == 0 {
-			return fmt.Errorf("scanner.batch_size is required")
+		if _, ok := chainIDs[scanner.ChainID]; ok {
+			return fmt.Errorf("scanner.chain_id %d is duplicated", scanner.ChainID)
 		}
+		chainIDs[scanner.ChainID] = struct{}{}
 	}
 
 	if c.LogScannerInterval == 0 {
@@ -154,6 +190,120 @@ func (c Config) Validate() error {
 		return fmt.Errorf("api.timeout is required")
 	}
 
+	if c.API.MaxTimeSpan == 0 {
+		return fmt.Errorf("api.max_time_span is required")
+	}
+
+	if c.API.MaxBlockSpan == 0 {
+		return fmt.Errorf("api.max_block_span is required")
+	}
+
+	if c.API.MaxStatsBuckets == 0 {
+		return fmt.Errorf("api.max_stats_buckets is required")
+	}
+
+	if c.API.MaxFilterValues <= 0 {
+		return fmt.Errorf("api.max_filter_values is required")
+	}
+
+	if c.Subscription.PingInterval == 0 {
+		return fmt.Errorf("subscription.ping_interval is required")
+	}
+
+	if c.Stream.BufferSize <= 0 {
+		return fmt.Errorf("stream.buffer_size is required")
+	}
+
+	if c.Stream.PingInterval == 0 {
+		return fmt.Errorf("stream.ping_interval is required")
+	}
+
+	if c.Stream.MaxReplay <= 0 {
+		return fmt.Errorf("stream.max_replay is required")
+	}
+
+	if c.Webhook.Interval == 0 {
+		return fmt.Errorf("webhook.interval is required")
+	}
+
+	if c.Webhook.BatchSize == 0 {
+		return fmt.Errorf("webhook.batch_size is required")
+	}
+
+	if c.Webhook.Timeout == 0 {
+		return fmt.Errorf("webhook.timeout is required")
+	}
+
+	if c.Webhook.MaxAttempts <= 0 {
+		return fmt.Errorf("webhook.max_attempts is required")
+	}
+
+	if c.Webhook.Backoff == 0 || c.Webhook.MaxBackoff == 0 {
+		return fmt.Errorf("webhook.backoff and webhook.max_backoff are required")
+	}
+
+	if c.Reconcile.Enabled {
+		if c.Reconcile.Interval == 0 {
+			return fmt.Errorf("reconcile.interval is required")
+		}
+		if c.Reconcile.SampleSize == 0 {
+			return fmt.Errorf("reconcile.sample_size is required")
+		}
+	}
+
+	if c.TokenMetadata.Enabled && c.TokenMetadata.RefreshInterval == 0 {
+		return fmt.Errorf("token_metadata.refresh_interval is required")
+	}
+
+	if c.Outbox.Enabled {
+		if c.Outbox.Interval == 0 {
+			return fmt.Errorf("outbox.interval is required")
+		}
+		if c.Outbox.BatchSize == 0 {
+			return fmt.Errorf("outbox.batch_size is required")
+		}
+		if c.Outbox.Retention == 0 {
+			return fmt.Errorf("outbox.retention is required")
+		}
+		if c.Outbox.Lease <= c.Timeout {
+			return fmt.Errorf("outbox.lease must be greater than timeout")
+		}
+		if c.Sink.RedisStream != "" {
+			if _, ok := c.Redis.DBs[RedisCache]; !ok {
+				return fmt.Errorf("redis.databases.%s is required when sink.redis_stream is set", RedisCache)
+			}
+		}
+	}
+
+	if c.Export.Dir == "" {
+		return fmt.Errorf("export.dir is required")
+	}
+
+	if c.Export.Secret == "" {
+		return fmt.Errorf("export.secret is required")
+	}
+
+	if c.Export.BatchSize == 0 {
+		return fmt.Errorf("export.batch_size is required")
+	}
+
+	if c.Export.Interval == 0 || c.Export.Timeout == 0 || c.Export.Retention == 0 {
+		return fmt.Errorf("export.interval, export.timeout and export.retention are required")
+	}
+
+	if c.HeaderCache.Size <= 0 {
+		return fmt.Errorf("header_cache.size is required")
+	}
+
+	if c.HeaderCache.Redis {
+		if c.HeaderCache.TTL == 0 {
+			return fmt.Errorf("header_cache.ttl is required")
+		}
+		if _, ok := c.Redis.DBs[RedisCache]; !ok {
+			return fmt.Errorf("redis.databases.%s is required when header_cache.redis is enabled", RedisCache)
+		}
+	}
+
 	if c.MySQL.MaxOpenConns == 0 {
 		return fmt.Errorf("mysql.max_open_conns is required")
 	}
@@ -247,3 +397,14 @@ func (c *Config) GetMySQL(name string) (*MySQL, error) {
 
 	return nil, fmt.Errorf("mysql %s not found", name)
 }
+
+// GetScanner returns the scanner config of a chain, false if the chain is not scanned
+func (c *Config) GetScanner(chainID int64) (*Chain, bool) {
+	for i := range c.Scanners {
+		if c.Scanners[i].ChainID == chainID {
+			return &c.Scanners[i], true
+		}
+	}
+
+	return nil, false
+}
@@ -8,5 +8,6 @@ const (
 	AccountDBS = "account_dbs"
 
 	// Redis
-	RedisCert = "cert" // certificate
+	RedisCert  = "cert"  // certificate
+	RedisCache = "cache" // shared cache
 )
@@ -36,3 +36,77 @@ func Test_Decoder(t *testing.T) {
 	assert.Equal(t, "0x0000000000000000000000005fbdb2315678afecb367f032d93f642f64180aa3", args["to"])
 	assert.Equal(t, big.NewInt(1).String(), args["value"])
 }
+
+func Test_Decoder_Args(t *testing.T) {
+	decoder := provider.NewDecoderProvider()
+	decoder.Register("Transfer(address,address,uint256)", &erc20.TransferDecoder{})
+
+	fields := decoder.EventFields("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	if assert.Len(t, fields, 3) {
+		assert.Equal(t, provider.Field{Name: "value", Type: provider.FieldUint256}, fields[2])
+	}
+	assert.Nil(t, decoder.EventFields("0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"))
+
+	log := &model.Log{
+		ChainID: 31337,
+		Topic0:  "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+		DecodedEvent: &model.DecodedEvent{
+			EventName: "Transfer",
+			EventData: map[string]string{
+				"from":  "0x000000000000000000000000f39fd6e51aad88f6f4ce6ab8827279cfffb92266",
+				"to":    "0x0000000000000000000000005fbdb2315678afecb367f032d93f642f64180aa3",
+				"value": "1000",
+			},
+		},
+	}
+
+	args, err := decoder.Args(log)
+	assert.NoError(t, err)
+	assert.Len(t, args, 3)
+	assert.Equal(t, "0xf39fd6e51aad88f6f4ce6ab8827279cfffb92266", args[0].Value)
+	assert.Equal(t, "0x5fbdb2315678afecb367f032d93f642f64180aa3", args[1].Value)
+	assert.Len(t, args[2].Value, 78)
+
+	// padded uint256 values compare as strings in numeric order
+	small, err := provider.FieldUint256.Normalize("999")
+	assert.NoError(t, err)
+	large, err := provider.FieldUint256.Normalize("1e18")
+	assert.NoError(t, err)
+	assert.Less(t, small, args[2].Value)
+	assert.Less(t, args[2].Value, large)
+
+	_, err = provider.FieldUint256.Normalize("-1")
+	assert.Error(t, err)
+	_, err = provider.FieldUint256.Normalize("1.5")
+	assert.Error(t, err)
+
+	// exponents beyond uint256 are rejected before they are expanded
+	top, err := provider.FieldUint256.Normalize("1e77")
+	assert.NoError(t, err)
+	assert.Len(t, top, 78)
+	_, err = provider.FieldUint256.Normalize("1e78")
+	assert.Error(t, err)
+	start := time.Now()
+	_, err = provider.FieldUint256.Normalize("1e600000000")
+	assert.Error(t, err)
+	_, err = provider.FieldUint256.Topic("1e600000000")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	assert.False(t, provider.FieldAddress.Supports(model.ArgOpGt))
+	assert.True(t, provider.FieldAddress.Supports(model.ArgOpPrefix))
+}
+
+func Test_Decoder_Fields(t *testing.T) {
+	decoder := provider.NewDecoderProvider()
+	decoder.Register("Transfer(address,address,uint256)", &erc20.TransferDecoder{})
+	decoder.Register("Approval(address,address,uint256)", &erc20.ApprovalDecoder{})
+
+	names := make([]string, 0)
+	for _, field := range decoder.Fields() {
+		names = append(names, field.Name)
+	}
+
+	// value is declared by both events and listed once
+	assert.Equal(t, []string{"from", "owner", "spender", "to", "value"}, names)
+}
@@ -17,6 +17,14 @@ func (d *ApprovalDecoder) EventName() string {
 	return "Approval"
 }
 
+func (d *ApprovalDecoder) Fields() []provider.Field {
+	return []provider.Field{
+		{Name: "owner", Type: provider.FieldAddress, Topic: 1},
+		{Name: "spender", Type: provider.FieldAddress, Topic: 2},
+		{Name: "value", Type: provider.FieldUint256},
+	}
+}
+
 func (d *ApprovalDecoder) Decode(log *model.Log) (map[string]string, error) {
 
 	// Approval(address indexed owner, address indexed spender, uint256 value)
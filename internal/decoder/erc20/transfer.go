@@ -17,6 +17,14 @@ func (d *TransferDecoder) EventName() string {
 	return "Transfer"
 }
 
+func (d *TransferDecoder) Fields() []provider.Field {
+	return []provider.Field{
+		{Name: "from", Type: provider.FieldAddress, Topic: 1},
+		{Name: "to", Type: provider.FieldAddress, Topic: 2},
+		{Name: "value", Type: provider.FieldUint256},
+	}
+}
+
 func (d *TransferDecoder) Decode(log *model.Log) (map[string]string, error) {
 
 	// Transfer(address indexed from, address indexed to, uint256 value)
@@ -3,6 +3,8 @@ package provider
 import (
 	"evm_event_indexer/service/model"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -11,6 +13,15 @@ import (
 type EventDecoder interface {
 	EventName() string
 	Decode(data *model.Log) (map[string]string, error)
+	// Fields declares the decoded arguments that can be filtered on
+	Fields() []Field
+}
+
+// Event is a registered event, used to build typed APIs from the decoders
+type Event struct {
+	Name   string
+	Topic0 common.Hash
+	Fields []Field
 }
 
 type DecoderProvider struct {
@@ -45,3 +56,99 @@ func (p *DecoderProvider) Decode(log *model.Log) (name string, args map[string]s
 
 	return decoder.EventName(), args, nil
 }
+
+// Events returns the registered events ordered by name.
+func (p *DecoderProvider) Events() []Event {
+	events := make([]Event, 0, len(p.decoders))
+	for topic0, decoder := range p.decoders {
+		events = append(events, Event{
+			Name:   decoder.EventName(),
+			Topic0: topic0,
+			Fields: decoder.Fields(),
+		})
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Name < events[j].Name
+	})
+
+	return events
+}
+
+// EventsByName returns the registered events of a name, ignoring case. Events of the same name may differ in signature.
+func (p *DecoderProvider) EventsByName(name string) []Event {
+	events := make([]Event, 0)
+	for _, event := range p.Events() {
+		if strings.EqualFold(event.Name, name) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// EventFields returns the queryable fields of the event of a topic0, nil if no decoder is registered for it.
+func (p *DecoderProvider) EventFields(topic0 string) []Field {
+	decoder, ok := p.decoders[common.HexToHash(topic0)]
+	if !ok {
+		return nil
+	}
+	return decoder.Fields()
+}
+
+// Args returns the queryable arguments of a decoded log.
+func (p *DecoderProvider) Args(log *model.Log) ([]*model.EventArg, error) {
+	if log == nil || log.DecodedEvent == nil {
+		return nil, nil
+	}
+
+	decoder, ok := p.decoders[common.HexToHash(log.Topic0)]
+	if !ok {
+		return nil, nil
+	}
+
+	args := make([]*model.EventArg, 0)
+	for _, field := range decoder.Fields() {
+		raw, ok := log.DecodedEvent.EventData[field.Name]
+		if !ok {
+			continue
+		}
+
+		value, err := field.Type.Normalize(raw)
+		if err != nil {
+			return nil, fmt.Errorf("normalize field %s failed: %w", field.Name, err)
+		}
+
+		args = append(args, &model.EventArg{
+			ChainID:     log.ChainID,
+			Address:     log.Address,
+			BlockNumber: log.BlockNumber,
+			TxIndex:     log.TxIndex,
+			LogIndex:    log.LogIndex,
+			Name:        field.Name,
+			Value:       value,
+		})
+	}
+
+	return args, nil
+}
+
+// Fields returns the queryable fields of all registered events ordered by name, fields of the same name are listed once.
+func (p *DecoderProvider) Fields() []Field {
+	seen := make(map[string]bool)
+	fields := make([]Field, 0)
+	for _, decoder := range p.decoders {
+		for _, field := range decoder.Fields() {
+			if seen[field.Name] {
+				continue
+			}
+			seen[field.Name] = true
+			fields = append(fields, field)
+		}
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+
+	return fields
+}
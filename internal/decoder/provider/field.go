@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"evm_event_indexer/service/model"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// queryable field types, values are normalized so that string comparison in mysql matches the type
+const (
+	FieldAddress FieldType = "address" // lowercase 20-byte hex
+	FieldUint256 FieldType = "uint256" // decimal, zero padded to 78 digits
+	FieldBytes32 FieldType = "bytes32" // lowercase 32-byte hex
+)
+
+var maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+type (
+	FieldType string
+
+	// Field is a decoded argument that can be filtered on
+	Field struct {
+		Name  string
+		Type  FieldType
+		Topic int // topic position of an indexed argument (1 to 3), 0 for an argument in the data
+	}
+)
+
+// Supports reports whether the filter operator can be used on the type
+func (t FieldType) Supports(op model.ArgOp) bool {
+	switch op {
+	case model.ArgOpEq:
+		return true
+	case model.ArgOpGt, model.ArgOpGte, model.ArgOpLt, model.ArgOpLte:
+		return t == FieldUint256
+	case model.ArgOpPrefix:
+		return t == FieldAddress || t == FieldBytes32
+	default:
+		return false
+	}
+}
+
+// Normalize converts a decoded or user given value into its stored form
+func (t FieldType) Normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	switch t {
+	case FieldAddress:
+		// decoders return indexed addresses as 32-byte topics
+		if common.IsHexAddress(value) {
+			return strings.ToLower(common.HexToAddress(value).Hex()), nil
+		}
+		if isHex(value) && len(value) == 66 {
+			return strings.ToLower(common.BytesToAddress(common.FromHex(value)).Hex()), nil
+		}
+		return "", fmt.Errorf("invalid address: %s", value)
+	case FieldBytes32:
+		if isHex(value) && len(value) == 66 {
+			return strings.ToLower(value), nil
+		}
+		return "", fmt.Errorf("invalid bytes32: %s", value)
+	case FieldUint256:
+		n, ok := parseUint256(value)
+		if !ok {
+			return "", fmt.Errorf("invalid uint256: %s", value)
+		}
+		return fmt.Sprintf("%0*s", model.Uint256Digits, n.String()), nil
+	default:
+		return "", fmt.Errorf("unknown field type: %s", t)
+	}
+}
+
+// NormalizePrefix converts a user given prefix of a hex value into its stored form
+func (t FieldType) NormalizePrefix(prefix string) (string, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if !t.Supports(model.ArgOpPrefix) {
+		return "", fmt.Errorf("prefix is not supported on %s", t)
+	}
+	if !isHex(prefix) || len(prefix) <= 2 {
+		return "", fmt.Errorf("invalid hex prefix: %s", prefix)
+	}
+	return prefix, nil
+}
+
+// Topic converts a user given value into the 32-byte topic of an indexed argument of the type
+func (t FieldType) Topic(value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	switch t {
+	case FieldAddress:
+		if !common.IsHexAddress(value) {
+			return "", fmt.Errorf("invalid address: %s", value)
+		}
+		return common.BytesToHash(common.HexToAddress(value).Bytes()).Hex(), nil
+	case FieldUint256:
+		n, ok := parseUint256(value)
+		if !ok {
+			return "", fmt.Errorf("invalid uint256: %s", value)
+		}
+		return common.BigToHash(n).Hex(), nil
+	default:
+		return t.Normalize(value)
+	}
+}
+
+// parseUint256 parses a decimal integer, scientific notation such as 1e18 is accepted
+func parseUint256(s string) (*big.Int, bool) {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		f, _, err := big.ParseFloat(s, 10, 512, big.ToNearestEven)
+		// reject a huge exponent before it is expanded, e.g. 1e600000000 would allocate hundreds of MB
+		if err != nil || !f.IsInt() || f.Sign() < 0 || f.MantExp(nil) > 256 {
+			return nil, false
+		}
+		n, _ = f.Int(nil)
+	}
+
+	if n.Sign() < 0 || n.Cmp(maxUint256) > 0 {
+		return nil, false
+	}
+	return n, true
+}
+
+func isHex(s string) bool {
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return false
+	}
+	for _, c := range s[2:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,26 @@
+package enum
+
+type ExportStatus int8
+
+const (
+	_ ExportStatus = iota
+	ExportStatusPending
+	ExportStatusRunning
+	ExportStatusSucceeded
+	ExportStatusFailed
+)
+
+func (s ExportStatus) String() string {
+	switch s {
+	case ExportStatusPending:
+		return "pending"
+	case ExportStatusRunning:
+		return "running"
+	case ExportStatusSucceeded:
+		return "succeeded"
+	case ExportStatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
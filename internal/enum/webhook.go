@@ -0,0 +1,45 @@
+package enum
+
+type WebhookStatus int8
+
+const (
+	_ WebhookStatus = iota
+	WebhookStatusEnabled
+	WebhookStatusDisabled
+)
+
+func (s WebhookStatus) String() string {
+	switch s {
+	case WebhookStatusEnabled:
+		return "enabled"
+	case WebhookStatusDisabled:
+		return "disabled"
+	default:
+		return "unknown"
+	}
+}
+
+type DeliveryStatus int8
+
+const (
+	_ DeliveryStatus = iota
+	DeliveryStatusPending
+	DeliveryStatusSucceeded
+	DeliveryStatusFailed    // retries exhausted
+	DeliveryStatusCancelled // the log was removed by a reorg before delivery
+)
+
+func (s DeliveryStatus) String() string {
+	switch s {
+	case DeliveryStatusPending:
+		return "pending"
+	case DeliveryStatusSucceeded:
+		return "succeeded"
+	case DeliveryStatusFailed:
+		return "failed"
+	case DeliveryStatusCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
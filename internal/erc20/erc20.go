@@ -8,9 +8,11 @@ import (
 	"fmt"
 	"math/big"
 
+	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 type (
@@ -23,8 +25,19 @@ type (
 		Address common.Address
 		Tx      *types.Transaction
 	}
+
+	// Metadata of a token, name and symbol are optional in ERC-20 and left empty when not implemented
+	Metadata struct {
+		Name        string
+		Symbol      string
+		Decimals    uint8
+		TotalSupply *big.Int
+	}
 )
 
+// selector of decimals(), the generated binding only exposes the DECIMALS constant of the test token
+var decimalsSelector = crypto.Keccak256([]byte("decimals()"))[:4]
+
 func NewERC20Service(client *eth.Client, privateKey string) *ERC20Service {
 	return &ERC20Service{c: client, privateKey: privateKey}
 }
@@ -68,12 +81,17 @@ func (i *ERC20Service) GetBalance(ctx context.Context, contractAddr common.Addre
 }
 
 func (i *ERC20Service) GetBalanceOf(ctx context.Context, contractAddr common.Address, ownerAddr common.Address) (*big.Int, error) {
+	return i.GetBalanceOfAt(ctx, contractAddr, ownerAddr, nil)
+}
+
+// GetBalanceOfAt returns the balance at the end of the block, nil means the latest block
+func (i *ERC20Service) GetBalanceOfAt(ctx context.Context, contractAddr common.Address, ownerAddr common.Address, blockNumber *big.Int) (*big.Int, error) {
 	contract, err := eth.NewERC20(contractAddr, i.c.Client)
 	if err != nil {
 		return nil, fmt.Errorf("get balance failed: %w", err)
 	}
 
-	return contract.Instance.BalanceOf(&bind.CallOpts{Context: ctx}, ownerAddr)
+	return contract.Instance.BalanceOf(&bind.CallOpts{Context: ctx, BlockNumber: blockNumber}, ownerAddr)
 }
 
 func (i *ERC20Service) Transfer(ctx context.Context, contractAddr common.Address, to common.Address, amount *big.Int) (*types.Transaction, error) {
@@ -137,3 +155,56 @@ func (i *ERC20Service) GetAllowanceOf(ctx context.Context, contractAddr common.A
 
 	return contract.Instance.Allowance(&bind.CallOpts{Context: ctx}, owner, spender)
 }
+
+// GetMetadata returns the name, symbol, decimals and total supply of a token at the latest block
+func (i *ERC20Service) GetMetadata(ctx context.Context, contractAddr common.Address) (*Metadata, error) {
+	contract, err := eth.NewERC20(contractAddr, i.c.Client)
+	if err != nil {
+		return nil, fmt.Errorf("new contract failed: %w", err)
+	}
+
+	opts := &bind.CallOpts{Context: ctx}
+	decimals, err := i.getDecimals(ctx, contract)
+	if err != nil {
+		return nil, fmt.Errorf("get decimals failed: %w", err)
+	}
+
+	supply, err := contract.Instance.TotalSupply(opts)
+	if err != nil {
+		return nil, fmt.Errorf("get total supply failed: %w", err)
+	}
+
+	// optional methods, a failed call leaves them empty
+	name, _ := contract.Instance.Name(opts)
+	symbol, _ := contract.Instance.Symbol(opts)
+
+	return &Metadata{
+		Name:        name,
+		Symbol:      symbol,
+		Decimals:    decimals,
+		TotalSupply: supply,
+	}, nil
+}
+
+// GetTotalSupply returns the total supply of a token at the latest block
+func (i *ERC20Service) GetTotalSupply(ctx context.Context, contractAddr common.Address) (*big.Int, error) {
+	contract, err := eth.NewERC20(contractAddr, i.c.Client)
+	if err != nil {
+		return nil, fmt.Errorf("new contract failed: %w", err)
+	}
+
+	return contract.Instance.TotalSupply(&bind.CallOpts{Context: ctx})
+}
+
+// getDecimals calls decimals() and falls back to the DECIMALS constant of the binding
+func (i *ERC20Service) getDecimals(ctx context.Context, contract *eth.ERC20Contract) (uint8, error) {
+	out, err := i.c.Client.CallContract(ctx, ethereum.CallMsg{To: &contract.Address, Data: decimalsSelector}, nil)
+	if err == nil && len(out) == 32 {
+		value := new(big.Int).SetBytes(out)
+		if value.IsUint64() && value.Uint64() <= 255 {
+			return uint8(value.Uint64()), nil
+		}
+	}
+
+	return contract.Instance.DECIMALS(&bind.CallOpts{Context: ctx})
+}
@@ -71,6 +71,20 @@ var (
 	ErrUserNotFound         = Err{HTTPCode: http.StatusNotFound, ErrorCode: 2003, Message: "user not found"}
 	ErrPermissionDenied     = Err{HTTPCode: http.StatusForbidden, ErrorCode: 2004, Message: "permission denied"}
 
+	// webhook error
+	ErrWebhookNotFound = Err{HTTPCode: http.StatusNotFound, ErrorCode: 4000, Message: "webhook not found"}
+
+	// token error
+	ErrTokenNotIndexed       = Err{HTTPCode: http.StatusNotFound, ErrorCode: 5000, Message: "token not indexed"}
+	ErrTokenMetadataNotFound = Err{HTTPCode: http.StatusNotFound, ErrorCode: 5001, Message: "token metadata not found"}
+
+	// export error
+	ErrExportNotFound = Err{HTTPCode: http.StatusNotFound, ErrorCode: 6000, Message: "export not found"}
+
+	// log error
+	ErrLogNotFound = Err{HTTPCode: http.StatusNotFound, ErrorCode: 7000, Message: "log not found"}
+	ErrTxNotFound  = Err{HTTPCode: http.StatusNotFound, ErrorCode: 7001, Message: "transaction not found"}
+
 	// server error
 	ErrInternalServerError = Err{HTTPCode: http.StatusInternalServerError, ErrorCode: 3000, Message: "something went wrong"}
 )
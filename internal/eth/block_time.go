@@ -0,0 +1,22 @@
+package eth
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// GetBlockTimestamps resolves block timestamps by block hash through the header cache.
+func (i Client) GetBlockTimestamps(hashes []common.Hash) (map[common.Hash]uint64, error) {
+	headers, err := i.GetHeadersByHash(hashes)
+	if err != nil {
+		return nil, fmt.Errorf("get block timestamps: %w", err)
+	}
+
+	res := make(map[common.Hash]uint64, len(headers))
+	for hash, header := range headers {
+		res[hash] = header.Time
+	}
+
+	return res, nil
+}
@@ -2,6 +2,7 @@ package eth
 
 import (
 	"context"
+	"evm_event_indexer/internal/config"
 	"evm_event_indexer/internal/tools"
 	"fmt"
 	"math/big"
@@ -11,8 +12,12 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
+// maximum number of calls sent in a single json-rpc batch, most providers reject larger batches
+const maxBatchSize = 100
+
 type (
 	Client struct {
 		Client  *ethclient.Client
@@ -36,9 +41,11 @@ func NewClient(ctx context.Context, rpcUrl string) (*Client, error) {
 		return nil, fmt.Errorf("dial rpc: %w", err)
 	}
 
-	chainID, err := client.NetworkID(ctx)
+	// network id differs from the chain id on some networks, use eth_chainId
+	chainID, err := client.ChainID(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("network id: %w", err)
+		client.Close()
+		return nil, fmt.Errorf("chain id: %w", err)
 	}
 
 	return &Client{
@@ -48,6 +55,28 @@ func NewClient(ctx context.Context, rpcUrl string) (*Client, error) {
 	}, nil
 }
 
+// NewChainClient connects to the rpc endpoint and checks that it serves the expected chain.
+func NewChainClient(ctx context.Context, rpcUrl string, chainID int64) (*Client, error) {
+	client, err := NewClient(ctx, rpcUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.chainID.Cmp(big.NewInt(chainID)) != 0 {
+		client.Close()
+		return nil, fmt.Errorf("chain id mismatch, rpc serves chain %s, expected %d", client.chainID, chainID)
+	}
+
+	return client, nil
+}
+
+// WithContext returns a client sharing the connection whose calls are bound to the context,
+// e.g. to give each batch of a long job its own timeout
+func (i Client) WithContext(ctx context.Context) *Client {
+	i.ctx = ctx
+	return &i
+}
+
 func (i *Client) Close() {
 	i.Client.Close()
 }
@@ -88,16 +117,62 @@ func (i Client) Subscribe(headers chan<- *types.Header) (ethereum.Subscription,
 	return sub, nil
 }
 
+// GetHeaderByNumber gets a block header by number, served from the header cache when possible
 func (i Client) GetHeaderByNumber(number uint64) (*types.Header, error) {
 
+	if header, ok := Headers.GetByNumber(i.ctx, i.chainID.Int64(), number); ok {
+		return header, nil
+	}
+
+	return i.FetchHeaderByNumber(number)
+}
+
+// FetchHeaderByNumber gets a block header by number from the node and refreshes the header cache with it,
+// the cached header of a number may belong to a replaced fork, so hash comparisons must not be served from the cache
+func (i Client) FetchHeaderByNumber(number uint64) (*types.Header, error) {
+
+	start := time.Now()
 	header, err := i.Client.HeaderByNumber(i.ctx, big.NewInt(int64(number)))
+	tools.ObserveRPC("HeaderByNumber", start, err)
 	if err != nil {
 		return nil, fmt.Errorf("header by number: %w", err)
 	}
 
+	Headers.Add(i.ctx, i.chainID.Int64(), header)
+
 	return header, nil
 }
 
+// GetHeadNumber gets the block number of a head block tag, latest, safe or finalized
+func (i Client) GetHeadNumber(head string) (uint64, error) {
+
+	var number rpc.BlockNumber
+	switch head {
+	case "", config.HeadLatest:
+		return i.GetBlockNumber()
+	case config.HeadSafe:
+		number = rpc.SafeBlockNumber
+	case config.HeadFinalized:
+		number = rpc.FinalizedBlockNumber
+	default:
+		return 0, fmt.Errorf("unsupported head block tag: %s", head)
+	}
+
+	start := time.Now()
+	header, err := i.Client.HeaderByNumber(i.ctx, big.NewInt(number.Int64()))
+	tools.ObserveRPC("HeaderByTag", start, err)
+	if err != nil {
+		return 0, fmt.Errorf("header by tag %s: %w", head, err)
+	}
+
+	return header.Number.Uint64(), nil
+}
+
+// InvalidateHeaders drops cached block numbers at or above the given number, used when a reorg is detected
+func (i Client) InvalidateHeaders(from uint64) {
+	Headers.InvalidateFrom(i.ctx, i.chainID.Int64(), from)
+}
+
 func (i Client) SubscribeFilterLogs(log chan<- types.Log, filter ethereum.FilterQuery) (ethereum.Subscription, error) {
 
 	sub, err := i.Client.SubscribeFilterLogs(i.ctx, filter, log)
@@ -107,3 +182,55 @@ func (i Client) SubscribeFilterLogs(log chan<- types.Log, filter ethereum.Filter
 
 	return sub, nil
 }
+
+// GetHeadersByHash gets block headers by hash, headers not in the header cache are fetched using json-rpc batch requests
+func (i Client) GetHeadersByHash(hashes []common.Hash) (map[common.Hash]*types.Header, error) {
+
+	res := make(map[common.Hash]*types.Header, len(hashes))
+	missing := make([]common.Hash, 0)
+
+	for _, hash := range hashes {
+		if _, ok := res[hash]; ok {
+			continue
+		}
+		if header, ok := Headers.GetByHash(i.ctx, i.chainID.Int64(), hash); ok {
+			res[hash] = header
+			continue
+		}
+		res[hash] = nil
+		missing = append(missing, hash)
+	}
+
+	for from := 0; from < len(missing); from += maxBatchSize {
+		chunk := missing[from:min(from+maxBatchSize, len(missing))]
+		headers := make([]*types.Header, len(chunk))
+		batch := make([]rpc.BatchElem, len(chunk))
+		for idx, hash := range chunk {
+			batch[idx] = rpc.BatchElem{
+				Method: "eth_getBlockByHash",
+				Args:   []any{hash, false},
+				Result: &headers[idx],
+			}
+		}
+
+		start := time.Now()
+		err := i.Client.Client().BatchCallContext(i.ctx, batch)
+		tools.ObserveRPC("BatchHeaderByHash", start, err)
+		if err != nil {
+			return nil, fmt.Errorf("batch header by hash: %w", err)
+		}
+
+		for idx, elem := range batch {
+			if elem.Error != nil {
+				return nil, fmt.Errorf("header by hash %s: %w", chunk[idx].Hex(), elem.Error)
+			}
+			if headers[idx] == nil {
+				return nil, fmt.Errorf("header by hash %s: %w", chunk[idx].Hex(), ethereum.NotFound)
+			}
+			Headers.Add(i.ctx, i.chainID.Int64(), headers[idx])
+			res[chunk[idx]] = headers[idx]
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,48 @@
+package eth_test
+
+import (
+	"context"
+	"evm_event_indexer/internal/eth"
+	"evm_event_indexer/internal/testutil"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Client_HeaderByNumber(t *testing.T) {
+	chainID := int64(31337)
+	node := testutil.NewEthNode(t, chainID)
+	eth.Headers = eth.NewHeaderCache(16, false, 0)
+
+	client, err := eth.NewChainClient(context.TODO(), node.URL, chainID)
+	require.NoError(t, err)
+	defer client.Close()
+
+	old := node.AddBlock(10, "a")
+
+	// a header looked up by hash also serves the lookups by number
+	_, err = client.GetHeadersByHash([]common.Hash{old.Hash()})
+	require.NoError(t, err)
+	header, err := client.GetHeaderByNumber(10)
+	require.NoError(t, err)
+	assert.Equal(t, old.Hash(), header.Hash())
+	assert.Equal(t, 0, node.Calls("eth_getBlockByNumber"))
+
+	// after a reorg the cache still serves the replaced header, a fetch asks the node and refreshes the cache
+	replaced := node.AddBlock(10, "b")
+	header, err = client.GetHeaderByNumber(10)
+	require.NoError(t, err)
+	assert.Equal(t, old.Hash(), header.Hash())
+
+	header, err = client.FetchHeaderByNumber(10)
+	require.NoError(t, err)
+	assert.Equal(t, replaced.Hash(), header.Hash())
+	assert.Equal(t, 1, node.Calls("eth_getBlockByNumber"))
+
+	header, err = client.GetHeaderByNumber(10)
+	require.NoError(t, err)
+	assert.Equal(t, replaced.Hash(), header.Hash())
+	assert.Equal(t, 1, node.Calls("eth_getBlockByNumber"))
+}
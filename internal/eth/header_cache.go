@@ -0,0 +1,299 @@
+package eth
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/storage"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/redis/go-redis/v9"
+)
+
+// default number of headers kept in memory before InitHeaderCache is called
+const defaultHeaderCacheSize = 4096
+
+// Headers is the process wide header cache shared by every client.
+var Headers = NewHeaderCache(defaultHeaderCacheSize, false, 0)
+
+// InitHeaderCache replaces the shared header cache with the configured one.
+func InitHeaderCache() {
+	cnf := config.Get().HeaderCache
+	Headers = NewHeaderCache(cnf.Size, cnf.Redis, cnf.TTL)
+}
+
+type (
+	// HeaderCache is an LRU cache of block headers keyed by (chain, hash) with a (chain, number) index.
+	// A header looked up by hash never changes, while the number index is dropped on reorg.
+	// When redis is enabled, headers are also shared through redis between indexer instances.
+	HeaderCache struct {
+		mu       sync.Mutex
+		size     int
+		ll       *list.List // most recently used at front
+		byHash   map[hashKey]*list.Element
+		byNumber map[numberKey]common.Hash
+		redis    bool
+		ttl      time.Duration
+	}
+
+	hashKey struct {
+		chainID int64
+		hash    common.Hash
+	}
+
+	numberKey struct {
+		chainID int64
+		number  uint64
+	}
+
+	headerEntry struct {
+		key    hashKey
+		header *types.Header
+	}
+)
+
+func NewHeaderCache(size int, useRedis bool, ttl time.Duration) *HeaderCache {
+	return &HeaderCache{
+		size:     size,
+		ll:       list.New(),
+		byHash:   make(map[hashKey]*list.Element, size),
+		byNumber: make(map[numberKey]common.Hash, size),
+		redis:    useRedis,
+		ttl:      ttl,
+	}
+}
+
+// GetByHash returns the cached header of the given block hash.
+func (c *HeaderCache) GetByHash(ctx context.Context, chainID int64, hash common.Hash) (*types.Header, bool) {
+	if header, ok := c.getByHash(chainID, hash); ok {
+		return header, true
+	}
+
+	if !c.redis {
+		return nil, false
+	}
+
+	header, err := redisGetHeader(ctx, chainID, hash)
+	if err != nil {
+		slog.Warn("get header from redis failed", slog.Any("error", err))
+		return nil, false
+	}
+	if header == nil {
+		return nil, false
+	}
+
+	c.add(chainID, header)
+	return header, true
+}
+
+// GetByNumber returns the cached header of the given block number.
+func (c *HeaderCache) GetByNumber(ctx context.Context, chainID int64, number uint64) (*types.Header, bool) {
+	c.mu.Lock()
+	hash, ok := c.byNumber[numberKey{chainID, number}]
+	c.mu.Unlock()
+
+	if ok {
+		return c.getByHash(chainID, hash)
+	}
+
+	if !c.redis {
+		return nil, false
+	}
+
+	hash, ok, err := redisGetHash(ctx, chainID, number)
+	if err != nil {
+		slog.Warn("get header hash from redis failed", slog.Any("error", err))
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+
+	return c.GetByHash(ctx, chainID, hash)
+}
+
+// Add stores a header, if another hash is already cached for the same number,
+// the chain has reorganized and every number index from that height is dropped.
+func (c *HeaderCache) Add(ctx context.Context, chainID int64, header *types.Header) {
+	c.add(chainID, header)
+
+	if !c.redis {
+		return
+	}
+
+	if err := redisAddHeader(ctx, chainID, header, c.size, c.ttl); err != nil {
+		slog.Warn("add header into redis failed", slog.Any("error", err))
+	}
+}
+
+// InvalidateFrom drops the number index of every header at or above the given number.
+func (c *HeaderCache) InvalidateFrom(ctx context.Context, chainID int64, number uint64) {
+	c.mu.Lock()
+	c.invalidateFrom(chainID, number)
+	c.mu.Unlock()
+
+	if !c.redis {
+		return
+	}
+
+	if err := redisInvalidateFrom(ctx, chainID, number); err != nil {
+		slog.Warn("invalidate headers in redis failed", slog.Any("error", err))
+	}
+}
+
+func (c *HeaderCache) getByHash(chainID int64, hash common.Hash) (*types.Header, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.byHash[hashKey{chainID, hash}]
+	if !ok {
+		return nil, false
+	}
+
+	c.ll.MoveToFront(elem)
+	return elem.Value.(*headerEntry).header, true
+}
+
+func (c *HeaderCache) add(chainID int64, header *types.Header) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hash := header.Hash()
+	number := header.Number.Uint64()
+
+	// a different block at the same height means a reorg happened
+	if cached, ok := c.byNumber[numberKey{chainID, number}]; ok && cached != hash {
+		c.invalidateFrom(chainID, number)
+	}
+	c.byNumber[numberKey{chainID, number}] = hash
+
+	key := hashKey{chainID, hash}
+	if elem, ok := c.byHash[key]; ok {
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.byHash[key] = c.ll.PushFront(&headerEntry{key: key, header: header})
+
+	// evict the least recently used header
+	for c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		entry := oldest.Value.(*headerEntry)
+		c.ll.Remove(oldest)
+		delete(c.byHash, entry.key)
+
+		nk := numberKey{entry.key.chainID, entry.header.Number.Uint64()}
+		if c.byNumber[nk] == entry.key.hash {
+			delete(c.byNumber, nk)
+		}
+	}
+}
+
+// caller must hold the lock
+func (c *HeaderCache) invalidateFrom(chainID int64, number uint64) {
+	for k := range c.byNumber {
+		if k.chainID == chainID && k.number >= number {
+			delete(c.byNumber, k)
+		}
+	}
+}
+
+// newHeaderKey is the key of a header, value is the json encoded header
+func newHeaderKey(chainID int64, hash common.Hash) string {
+	return fmt.Sprintf("header:%d:%s", chainID, hash.Hex())
+}
+
+// newHeaderNumberKey is the sorted set of block hashes of a chain, scored by block number
+func newHeaderNumberKey(chainID int64) string {
+	return fmt.Sprintf("header_number:%d", chainID)
+}
+
+func redisGetHeader(ctx context.Context, chainID int64, hash common.Hash) (*types.Header, error) {
+	client, err := storage.GetRedis(config.RedisCache)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := client.Get(ctx, newHeaderKey(chainID, hash)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := new(types.Header)
+	if err := json.Unmarshal(raw, header); err != nil {
+		return nil, err
+	}
+
+	return header, nil
+}
+
+func redisGetHash(ctx context.Context, chainID int64, number uint64) (common.Hash, bool, error) {
+	client, err := storage.GetRedis(config.RedisCache)
+	if err != nil {
+		return common.Hash{}, false, err
+	}
+
+	score := strconv.FormatUint(number, 10)
+	hashes, err := client.ZRangeByScore(ctx, newHeaderNumberKey(chainID), &redis.ZRangeBy{Min: score, Max: score}).Result()
+	if err != nil {
+		return common.Hash{}, false, err
+	}
+
+	if len(hashes) != 1 {
+		return common.Hash{}, false, nil
+	}
+
+	return common.HexToHash(hashes[0]), true, nil
+}
+
+func redisAddHeader(ctx context.Context, chainID int64, header *types.Header, size int, ttl time.Duration) error {
+	client, err := storage.GetRedis(config.RedisCache)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	number := header.Number.Uint64()
+	cached, ok, err := redisGetHash(ctx, chainID, number)
+	if err != nil {
+		return err
+	}
+
+	pipe := client.TxPipeline()
+
+	// a different block at the same height means a reorg happened
+	if ok && cached != header.Hash() {
+		pipe.ZRemRangeByScore(ctx, newHeaderNumberKey(chainID), strconv.FormatUint(number, 10), "+inf")
+	}
+
+	pipe.Set(ctx, newHeaderKey(chainID, header.Hash()), raw, ttl)
+	pipe.ZAdd(ctx, newHeaderNumberKey(chainID), redis.Z{Score: float64(number), Member: header.Hash().Hex()})
+	// keep only the latest headers in the number index
+	pipe.ZRemRangeByRank(ctx, newHeaderNumberKey(chainID), 0, int64(-size-1))
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func redisInvalidateFrom(ctx context.Context, chainID int64, number uint64) error {
+	client, err := storage.GetRedis(config.RedisCache)
+	if err != nil {
+		return err
+	}
+
+	return client.ZRemRangeByScore(ctx, newHeaderNumberKey(chainID), strconv.FormatUint(number, 10), "+inf").Err()
+}
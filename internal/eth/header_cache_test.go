@@ -0,0 +1,83 @@
+package eth_test
+
+import (
+	"context"
+	"evm_event_indexer/internal/eth"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newHeader(number int64, extra string) *types.Header {
+	return &types.Header{
+		Number:     big.NewInt(number),
+		Difficulty: big.NewInt(0),
+		Extra:      []byte(extra),
+	}
+}
+
+func Test_HeaderCache(t *testing.T) {
+	ctx := context.TODO()
+	chainID := int64(31337)
+	cache := eth.NewHeaderCache(2, false, 0)
+
+	h1 := newHeader(1, "a")
+	h2 := newHeader(2, "a")
+	cache.Add(ctx, chainID, h1)
+	cache.Add(ctx, chainID, h2)
+
+	got, ok := cache.GetByNumber(ctx, chainID, 1)
+	assert.True(t, ok)
+	assert.Equal(t, h1.Hash(), got.Hash())
+
+	got, ok = cache.GetByHash(ctx, chainID, h2.Hash())
+	assert.True(t, ok)
+	assert.Equal(t, h2.Hash(), got.Hash())
+
+	// same number on another chain is a different entry
+	_, ok = cache.GetByNumber(ctx, 1, 1)
+	assert.False(t, ok)
+
+	// least recently used header (h1 was read before h2) is evicted
+	cache.Add(ctx, chainID, newHeader(3, "a"))
+	_, ok = cache.GetByHash(ctx, chainID, h1.Hash())
+	assert.False(t, ok)
+	_, ok = cache.GetByNumber(ctx, chainID, 1)
+	assert.False(t, ok)
+	_, ok = cache.GetByHash(ctx, chainID, h2.Hash())
+	assert.True(t, ok)
+}
+
+func Test_HeaderCache_Reorg(t *testing.T) {
+	ctx := context.TODO()
+	chainID := int64(31337)
+	cache := eth.NewHeaderCache(10, false, 0)
+
+	for i := int64(1); i <= 5; i++ {
+		cache.Add(ctx, chainID, newHeader(i, "a"))
+	}
+
+	// a new block at height 3 drops the number index from height 3
+	forked := newHeader(3, "b")
+	cache.Add(ctx, chainID, forked)
+
+	got, ok := cache.GetByNumber(ctx, chainID, 3)
+	assert.True(t, ok)
+	assert.Equal(t, forked.Hash(), got.Hash())
+
+	_, ok = cache.GetByNumber(ctx, chainID, 4)
+	assert.False(t, ok)
+
+	_, ok = cache.GetByNumber(ctx, chainID, 2)
+	assert.True(t, ok)
+
+	// headers stay reachable by hash
+	_, ok = cache.GetByHash(ctx, chainID, newHeader(4, "a").Hash())
+	assert.True(t, ok)
+
+	cache.InvalidateFrom(ctx, chainID, 1)
+	_, ok = cache.GetByNumber(ctx, chainID, 1)
+	assert.False(t, ok)
+}
@@ -0,0 +1,155 @@
+package eth
+
+import (
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/tools"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// L1Block predeploy of OP Stack chains, holds the L1 origin of the current L2 block
+	opL1BlockAddress = common.HexToAddress("0x4200000000000000000000000000000000000015")
+	// selector of number()
+	opL1BlockNumberSelector = hexutil.Bytes{0x83, 0x81, 0xf5, 0x8a}
+	// selector of hash()
+	opL1BlockHashSelector = hexutil.Bytes{0x09, 0xbd, 0x5a, 0x60}
+)
+
+// L1Origin is the L1 block an L2 block was derived from.
+// Arbitrum only exposes the L1 block number, the hash is left empty.
+type L1Origin struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// GetL1Origins resolves the L1 origin of L2 blocks by block hash
+func (i Client) GetL1Origins(chainType string, hashes []common.Hash) (map[common.Hash]*L1Origin, error) {
+	switch chainType {
+	case config.ChainTypeOptimism:
+		return i.getOptimismL1Origins(hashes)
+	case config.ChainTypeArbitrum:
+		return i.getArbitrumL1Origins(hashes)
+	default:
+		return nil, fmt.Errorf("chain type %s has no l1 origin", chainType)
+	}
+}
+
+// getOptimismL1Origins reads number() and hash() of the L1Block predeploy at each L2 block
+func (i Client) getOptimismL1Origins(hashes []common.Hash) (map[common.Hash]*L1Origin, error) {
+	hashes = uniqueHashes(hashes)
+	res := make(map[common.Hash]*L1Origin, len(hashes))
+
+	// two calls per block
+	size := maxBatchSize / 2
+	for from := 0; from < len(hashes); from += size {
+		chunk := hashes[from:min(from+size, len(hashes))]
+		numbers := make([]hexutil.Bytes, len(chunk))
+		l1Hashes := make([]hexutil.Bytes, len(chunk))
+		batch := make([]rpc.BatchElem, 0, len(chunk)*2)
+		for idx, hash := range chunk {
+			// EIP-1898 block parameter
+			block := map[string]any{"blockHash": hash, "requireCanonical": true}
+			batch = append(batch,
+				rpc.BatchElem{
+					Method: "eth_call",
+					Args:   []any{map[string]any{"to": opL1BlockAddress, "data": opL1BlockNumberSelector}, block},
+					Result: &numbers[idx],
+				},
+				rpc.BatchElem{
+					Method: "eth_call",
+					Args:   []any{map[string]any{"to": opL1BlockAddress, "data": opL1BlockHashSelector}, block},
+					Result: &l1Hashes[idx],
+				},
+			)
+		}
+
+		start := time.Now()
+		err := i.Client.Client().BatchCallContext(i.ctx, batch)
+		tools.ObserveRPC("BatchL1Origin", start, err)
+		if err != nil {
+			return nil, fmt.Errorf("batch l1 origin: %w", err)
+		}
+
+		for idx, hash := range chunk {
+			for _, elem := range batch[idx*2 : idx*2+2] {
+				if elem.Error != nil {
+					return nil, fmt.Errorf("l1 origin of block %s: %w", hash.Hex(), elem.Error)
+				}
+			}
+			if len(numbers[idx]) != 32 || len(l1Hashes[idx]) != 32 {
+				return nil, fmt.Errorf("l1 origin of block %s: unexpected L1Block response", hash.Hex())
+			}
+
+			res[hash] = &L1Origin{
+				Number: new(big.Int).SetBytes(numbers[idx]).Uint64(),
+				Hash:   common.BytesToHash(l1Hashes[idx]),
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// getArbitrumL1Origins reads the l1BlockNumber field of Arbitrum blocks
+func (i Client) getArbitrumL1Origins(hashes []common.Hash) (map[common.Hash]*L1Origin, error) {
+	hashes = uniqueHashes(hashes)
+	res := make(map[common.Hash]*L1Origin, len(hashes))
+
+	type arbBlock struct {
+		L1BlockNumber *hexutil.Uint64 `json:"l1BlockNumber"`
+	}
+
+	for from := 0; from < len(hashes); from += maxBatchSize {
+		chunk := hashes[from:min(from+maxBatchSize, len(hashes))]
+		blocks := make([]*arbBlock, len(chunk))
+		batch := make([]rpc.BatchElem, len(chunk))
+		for idx, hash := range chunk {
+			batch[idx] = rpc.BatchElem{
+				Method: "eth_getBlockByHash",
+				Args:   []any{hash, false},
+				Result: &blocks[idx],
+			}
+		}
+
+		start := time.Now()
+		err := i.Client.Client().BatchCallContext(i.ctx, batch)
+		tools.ObserveRPC("BatchL1Origin", start, err)
+		if err != nil {
+			return nil, fmt.Errorf("batch l1 origin: %w", err)
+		}
+
+		for idx, elem := range batch {
+			if elem.Error != nil {
+				return nil, fmt.Errorf("l1 origin of block %s: %w", chunk[idx].Hex(), elem.Error)
+			}
+			if blocks[idx] == nil || blocks[idx].L1BlockNumber == nil {
+				return nil, fmt.Errorf("l1 origin of block %s: l1BlockNumber not found", chunk[idx].Hex())
+			}
+
+			res[chunk[idx]] = &L1Origin{
+				Number: uint64(*blocks[idx].L1BlockNumber),
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func uniqueHashes(hashes []common.Hash) []common.Hash {
+	seen := make(map[common.Hash]struct{}, len(hashes))
+	res := make([]common.Hash, 0, len(hashes))
+	for _, hash := range hashes {
+		if _, ok := seen[hash]; ok {
+			continue
+		}
+		seen[hash] = struct{}{}
+		res = append(res, hash)
+	}
+	return res
+}
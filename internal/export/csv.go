@@ -0,0 +1,57 @@
+package export
+
+import (
+	"encoding/csv"
+	"evm_event_indexer/internal/decoder/provider"
+	"evm_event_indexer/service/model"
+	"io"
+)
+
+// csvWriter writes a header line followed by a line per log
+type csvWriter struct {
+	w       *csv.Writer
+	columns []column
+	fields  []provider.Field
+	started bool
+}
+
+func newCSVWriter(w io.Writer, columns []column, fields []provider.Field) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w), columns: columns, fields: fields}
+}
+
+func (w *csvWriter) Write(log *model.Log) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	r := newRow(log, w.fields)
+	record := make([]string, len(r))
+	for i, v := range r {
+		record[i] = formatValue(v)
+	}
+
+	return w.w.Write(record)
+}
+
+func (w *csvWriter) Close() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// writeHeader writes the header once, an export without logs still has the header
+func (w *csvWriter) writeHeader() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+
+	header := make([]string, len(w.columns))
+	for i, c := range w.columns {
+		header[i] = c.name
+	}
+	return w.w.Write(header)
+}
@@ -0,0 +1,158 @@
+package export
+
+import (
+	"encoding/hex"
+	"evm_event_indexer/internal/decoder/provider"
+	"evm_event_indexer/service/model"
+	"fmt"
+	"io"
+	"time"
+)
+
+// export file formats
+const (
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+// column kinds, written as the matching type of each format
+const (
+	kindString kind = iota
+	kindInt64
+	kindTimestamp
+)
+
+type (
+	Format string
+
+	kind int
+
+	// Writer writes event logs as the rows of an export file
+	Writer interface {
+		Write(log *model.Log) error
+		// Close flushes the buffered rows, the underlying writer is not closed
+		Close() error
+	}
+
+	column struct {
+		name string
+		kind kind
+	}
+
+	// row is a log flattened into the values of the columns, string, int64 or time.Time by the column kind
+	row []any
+)
+
+// Valid reports whether the format is supported
+func (f Format) Valid() bool {
+	return f == FormatCSV || f == FormatNDJSON || f == FormatParquet
+}
+
+// ContentType returns the media type of a file of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// NewWriter returns a writer of the format, the decoded fields are flattened into decoded_<name> columns
+func NewWriter(format Format, w io.Writer, fields []provider.Field) (Writer, error) {
+	columns := newColumns(fields)
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns, fields), nil
+	case FormatNDJSON:
+		return newNDJSONWriter(w, columns, fields), nil
+	case FormatParquet:
+		return newParquetWriter(w, columns, fields), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+func newColumns(fields []provider.Field) []column {
+	columns := []column{
+		{name: "chain_id", kind: kindInt64},
+		{name: "block_number", kind: kindInt64},
+		{name: "block_hash", kind: kindString},
+		{name: "block_timestamp", kind: kindTimestamp},
+		{name: "tx_hash", kind: kindString},
+		{name: "tx_index", kind: kindInt64},
+		{name: "log_index", kind: kindInt64},
+		{name: "address", kind: kindString},
+		{name: "topic_0", kind: kindString},
+		{name: "topic_1", kind: kindString},
+		{name: "topic_2", kind: kindString},
+		{name: "topic_3", kind: kindString},
+		{name: "data", kind: kindString},
+		{name: "event_name", kind: kindString},
+	}
+
+	for _, field := range fields {
+		columns = append(columns, column{name: "decoded_" + field.Name, kind: kindString})
+	}
+
+	return columns
+}
+
+// newRow flattens a log into the values of the columns, missing decoded fields are empty
+func newRow(log *model.Log, fields []provider.Field) row {
+	r := row{
+		log.ChainID,
+		int64(log.BlockNumber),
+		log.BlockHash,
+		log.BlockTimestamp,
+		log.TxHash,
+		int64(log.TxIndex),
+		int64(log.LogIndex),
+		log.Address,
+		log.Topic0,
+		log.Topic1,
+		log.Topic2,
+		log.Topic3,
+		"0x" + hex.EncodeToString(log.Data),
+		"",
+	}
+
+	var data map[string]string
+	if log.DecodedEvent != nil {
+		r[13] = log.DecodedEvent.EventName
+		data = log.DecodedEvent.EventData
+	}
+
+	for _, field := range fields {
+		r = append(r, decodedValue(field, data[field.Name]))
+	}
+
+	return r
+}
+
+// decodedValue returns a decoded value in its exported form, indexed addresses are shortened from 32-byte topics
+func decodedValue(field provider.Field, value string) string {
+	if value == "" || field.Type != provider.FieldAddress {
+		return value
+	}
+
+	if normalized, err := field.Type.Normalize(value); err == nil {
+		return normalized
+	}
+	return value
+}
+
+// formatValue returns the text form of a value
+func formatValue(v any) string {
+	switch t := v.(type) {
+	case int64:
+		return fmt.Sprintf("%d", t)
+	case time.Time:
+		return t.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(t)
+	}
+}
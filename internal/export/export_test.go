@@ -0,0 +1,274 @@
+package export_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"evm_event_indexer/internal/decoder/provider"
+	"evm_event_indexer/internal/export"
+	"evm_event_indexer/service/model"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fields = []provider.Field{
+	{Name: "from", Type: provider.FieldAddress},
+	{Name: "value", Type: provider.FieldUint256},
+}
+
+func newLog(logIndex int32) *model.Log {
+	return &model.Log{
+		ChainID:        1,
+		Address:        "0x1111111111111111111111111111111111111111",
+		BlockHash:      "0xaa",
+		BlockNumber:    100,
+		Topic0:         "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+		Topic1:         "0x0000000000000000000000002222222222222222222222222222222222222222",
+		TxIndex:        1,
+		LogIndex:       logIndex,
+		TxHash:         "0xbb",
+		Data:           []byte{0x01},
+		BlockTimestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		DecodedEvent: &model.DecodedEvent{
+			EventName: "Transfer",
+			EventData: map[string]string{
+				"from":  "0x0000000000000000000000002222222222222222222222222222222222222222",
+				"value": "1000",
+			},
+		},
+	}
+}
+
+func Test_Format(t *testing.T) {
+	assert.True(t, export.FormatCSV.Valid())
+	assert.True(t, export.FormatParquet.Valid())
+	assert.False(t, export.Format("xlsx").Valid())
+
+	_, err := export.NewWriter("xlsx", new(bytes.Buffer), fields)
+	assert.Error(t, err)
+}
+
+func Test_CSV(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w, err := export.NewWriter(export.FormatCSV, buf, fields)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(newLog(0)))
+
+	undecoded := newLog(1)
+	undecoded.DecodedEvent = nil
+	require.NoError(t, w.Write(undecoded))
+	require.NoError(t, w.Close())
+
+	records, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	header := records[0]
+	assert.Equal(t, "chain_id", header[0])
+	assert.Equal(t, []string{"event_name", "decoded_from", "decoded_value"}, header[len(header)-3:])
+
+	row := records[1]
+	assert.Equal(t, "100", row[1])
+	assert.Equal(t, "2024-01-01T00:00:00Z", row[3])
+	assert.Equal(t, "0x01", row[12])
+	assert.Equal(t, []string{"Transfer", "0x2222222222222222222222222222222222222222", "1000"}, row[len(row)-3:])
+
+	row = records[2]
+	assert.Equal(t, []string{"", "", ""}, row[len(row)-3:])
+}
+
+func Test_CSV_Empty(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w, err := export.NewWriter(export.FormatCSV, buf, nil)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	records, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+}
+
+func Test_NDJSON(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w, err := export.NewWriter(export.FormatNDJSON, buf, fields)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(newLog(0)))
+
+	undecoded := newLog(1)
+	undecoded.DecodedEvent = nil
+	require.NoError(t, w.Write(undecoded))
+	require.NoError(t, w.Close())
+
+	scanner := bufio.NewScanner(buf)
+	rows := make([]map[string]any, 0)
+	for scanner.Scan() {
+		row := make(map[string]any)
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &row))
+		rows = append(rows, row)
+	}
+	require.Len(t, rows, 2)
+
+	assert.Equal(t, float64(100), rows[0]["block_number"])
+	assert.Equal(t, "0x2222222222222222222222222222222222222222", rows[0]["decoded_from"])
+	assert.Equal(t, "1000", rows[0]["decoded_value"])
+	assert.NotContains(t, rows[1], "decoded_from")
+}
+
+func Test_Parquet(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w, err := export.NewWriter(export.FormatParquet, buf, fields)
+	require.NoError(t, err)
+	for i := range 3 {
+		require.NoError(t, w.Write(newLog(int32(i))))
+	}
+	require.NoError(t, w.Close())
+
+	file := buf.Bytes()
+	require.Greater(t, len(file), 12)
+	assert.Equal(t, "PAR1", string(file[:4]))
+	assert.Equal(t, "PAR1", string(file[len(file)-4:]))
+
+	size := int(binary.LittleEndian.Uint32(file[len(file)-8 : len(file)-4]))
+	footer := file[len(file)-8-size : len(file)-8]
+
+	// the footer is a single FileMetaData struct, num_rows is field 3
+	r := &compactReader{buf: footer}
+	values := r.readStruct()
+	assert.Equal(t, len(footer), r.pos)
+	assert.Equal(t, int64(3), values[3])
+	assert.Equal(t, int64(1), values[1])
+}
+
+// Test_Parquet_Pyarrow reads the written file with pyarrow, skipped when python3 with pyarrow is not installed
+func Test_Parquet_Pyarrow(t *testing.T) {
+	if err := exec.Command("python3", "-c", "import pyarrow.parquet").Run(); err != nil {
+		t.Skip("python3 with pyarrow is required: pip install pyarrow")
+	}
+
+	path := filepath.Join(t.TempDir(), "logs.parquet")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+
+	// one more row than a row group holds, so the file has two row groups
+	w, err := export.NewWriter(export.FormatParquet, file, fields)
+	require.NoError(t, err)
+	for i := range 10001 {
+		log := newLog(int32(i))
+		if i > 0 {
+			log.DecodedEvent = nil
+		}
+		require.NoError(t, w.Write(log))
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, file.Close())
+
+	out, err := exec.Command("python3", "testdata/read_parquet.py", path).Output()
+	require.NoError(t, err)
+
+	var res struct {
+		NumRows      int64             `json:"num_rows"`
+		NumRowGroups int64             `json:"num_row_groups"`
+		Schema       map[string]string `json:"schema"`
+		First        map[string]any    `json:"first"`
+		Last         map[string]any    `json:"last"`
+	}
+	require.NoError(t, json.Unmarshal(out, &res))
+
+	assert.Equal(t, int64(10001), res.NumRows)
+	assert.Equal(t, int64(2), res.NumRowGroups)
+	assert.Equal(t, "int64", res.Schema["block_number"])
+	assert.Equal(t, "timestamp[ms]", res.Schema["block_timestamp"])
+	assert.Equal(t, "string", res.Schema["decoded_value"])
+
+	assert.Equal(t, float64(100), res.First["block_number"])
+	assert.Equal(t, "2024-01-01 00:00:00", res.First["block_timestamp"])
+	assert.Equal(t, "0x01", res.First["data"])
+	assert.Equal(t, "Transfer", res.First["event_name"])
+	assert.Equal(t, "0x2222222222222222222222222222222222222222", res.First["decoded_from"])
+	assert.Equal(t, "1000", res.First["decoded_value"])
+
+	assert.Equal(t, float64(10000), res.Last["log_index"])
+	assert.Equal(t, "", res.Last["decoded_value"])
+}
+
+func Test_Parquet_Empty(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w, err := export.NewWriter(export.FormatParquet, buf, fields)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	file := buf.Bytes()
+	assert.Equal(t, "PAR1", string(file[:4]))
+	assert.Equal(t, "PAR1", string(file[len(file)-4:]))
+}
+
+// compactReader walks a thrift compact struct, returning the integer fields of the top level
+type compactReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *compactReader) readStruct() map[int16]int64 {
+	values := make(map[int16]int64)
+	var id int16
+	for {
+		b := r.buf[r.pos]
+		r.pos++
+		if b == 0 {
+			return values
+		}
+
+		typ := b & 0x0f
+		if delta := int16(b >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.zigzag())
+		}
+
+		if v, ok := r.readValue(typ); ok {
+			values[id] = v
+		}
+	}
+}
+
+func (r *compactReader) readValue(typ byte) (int64, bool) {
+	switch typ {
+	case 5, 6:
+		return r.zigzag(), true
+	case 8:
+		n := int(r.varint())
+		r.pos += n
+	case 9:
+		b := r.buf[r.pos]
+		r.pos++
+		size := int(b >> 4)
+		if size == 15 {
+			size = int(r.varint())
+		}
+		for range size {
+			r.readValue(b & 0x0f)
+		}
+	case 12:
+		r.readStruct()
+	}
+	return 0, false
+}
+
+func (r *compactReader) varint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *compactReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
@@ -0,0 +1,39 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"evm_event_indexer/internal/decoder/provider"
+	"evm_event_indexer/service/model"
+	"io"
+)
+
+// ndjsonWriter writes a json object per line, empty decoded fields are omitted
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	enc     *json.Encoder
+	columns []column
+	fields  []provider.Field
+}
+
+func newNDJSONWriter(w io.Writer, columns []column, fields []provider.Field) *ndjsonWriter {
+	buf := bufio.NewWriter(w)
+	return &ndjsonWriter{w: buf, enc: json.NewEncoder(buf), columns: columns, fields: fields}
+}
+
+func (w *ndjsonWriter) Write(log *model.Log) error {
+	r := newRow(log, w.fields)
+	object := make(map[string]any, len(r))
+	for i, v := range r {
+		if s, ok := v.(string); ok && s == "" && i >= len(w.columns)-len(w.fields) {
+			continue
+		}
+		object[w.columns[i].name] = v
+	}
+
+	return w.enc.Encode(object)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,334 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"evm_event_indexer/internal/decoder/provider"
+	"evm_event_indexer/service/model"
+	"io"
+	"time"
+)
+
+// parquet writes a minimal parquet file: required columns, plain encoding, no compression
+// and a single data page per column chunk, which is readable by any parquet reader.
+
+// rows buffered in memory before a row group is written
+const parquetRowGroupSize = 10000
+
+const parquetMagic = "PAR1"
+
+// parquet physical types
+const (
+	parquetInt64     int32 = 2
+	parquetByteArray int32 = 6
+)
+
+// parquet converted types
+const (
+	parquetUTF8            int32 = 0
+	parquetTimestampMillis int32 = 9
+)
+
+const (
+	parquetRequired     int32 = 0
+	parquetPlain        int32 = 0
+	parquetRLE          int32 = 3
+	parquetUncompressed int32 = 0
+	parquetDataPage     int32 = 0
+)
+
+// thrift compact protocol types
+const (
+	thriftI32    byte = 5
+	thriftI64    byte = 6
+	thriftBinary byte = 8
+	thriftList   byte = 9
+	thriftStruct byte = 12
+)
+
+type (
+	parquetWriter struct {
+		w         *countingWriter
+		columns   []column
+		fields    []provider.Field
+		values    []bytes.Buffer // plain encoded values of the buffered rows, per column
+		rows      int64          // buffered rows
+		total     int64
+		rowGroups []parquetRowGroup
+		started   bool
+	}
+
+	parquetRowGroup struct {
+		rows   int64
+		size   int64
+		chunks []parquetChunk
+	}
+
+	parquetChunk struct {
+		offset int64
+		size   int64
+	}
+
+	countingWriter struct {
+		w *bufio.Writer
+		n int64
+	}
+)
+
+func newParquetWriter(w io.Writer, columns []column, fields []provider.Field) *parquetWriter {
+	return &parquetWriter{
+		w:       &countingWriter{w: bufio.NewWriter(w)},
+		columns: columns,
+		fields:  fields,
+		values:  make([]bytes.Buffer, len(columns)),
+	}
+}
+
+func (w *parquetWriter) Write(log *model.Log) error {
+	for i, v := range newRow(log, w.fields) {
+		buf := &w.values[i]
+		switch t := v.(type) {
+		case int64:
+			binary.Write(buf, binary.LittleEndian, t)
+		case time.Time:
+			binary.Write(buf, binary.LittleEndian, t.UnixMilli())
+		case string:
+			binary.Write(buf, binary.LittleEndian, uint32(len(t)))
+			buf.WriteString(t)
+		}
+	}
+
+	w.rows++
+	if w.rows >= parquetRowGroupSize {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *parquetWriter) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	if err := w.writeMagic(); err != nil {
+		return err
+	}
+
+	footer := w.footer()
+	if _, err := w.w.Write(footer); err != nil {
+		return err
+	}
+	if err := binary.Write(w.w, binary.LittleEndian, uint32(len(footer))); err != nil {
+		return err
+	}
+	if _, err := w.w.Write([]byte(parquetMagic)); err != nil {
+		return err
+	}
+	return w.w.w.Flush()
+}
+
+// flush writes the buffered rows as a row group
+func (w *parquetWriter) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	if err := w.writeMagic(); err != nil {
+		return err
+	}
+
+	group := parquetRowGroup{rows: w.rows, chunks: make([]parquetChunk, len(w.columns))}
+	for i := range w.columns {
+		page := w.values[i].Bytes()
+
+		t := new(thriftWriter)
+		t.structBegin()
+		t.i32(1, parquetDataPage)
+		t.i32(2, int32(len(page)))
+		t.i32(3, int32(len(page)))
+		t.fieldStruct(5)
+		t.i32(1, int32(w.rows))
+		t.i32(2, parquetPlain)
+		t.i32(3, parquetRLE)
+		t.i32(4, parquetRLE)
+		t.structEnd()
+		t.structEnd()
+
+		offset := w.w.n
+		if _, err := w.w.Write(t.buf.Bytes()); err != nil {
+			return err
+		}
+		if _, err := w.w.Write(page); err != nil {
+			return err
+		}
+
+		group.chunks[i] = parquetChunk{offset: offset, size: w.w.n - offset}
+		group.size += w.w.n - offset
+		w.values[i].Reset()
+	}
+
+	w.rowGroups = append(w.rowGroups, group)
+	w.total += w.rows
+	w.rows = 0
+	return nil
+}
+
+func (w *parquetWriter) writeMagic() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+
+	_, err := w.w.Write([]byte(parquetMagic))
+	return err
+}
+
+// footer encodes the file metadata
+func (w *parquetWriter) footer() []byte {
+	t := new(thriftWriter)
+	t.structBegin()
+	t.i32(1, 1)
+
+	t.list(2, thriftStruct, len(w.columns)+1)
+	t.structBegin()
+	t.binary(4, []byte("schema"))
+	t.i32(5, int32(len(w.columns)))
+	t.structEnd()
+	for _, c := range w.columns {
+		t.structBegin()
+		t.i32(1, c.physicalType())
+		t.i32(3, parquetRequired)
+		t.binary(4, []byte(c.name))
+		if converted, ok := c.convertedType(); ok {
+			t.i32(6, converted)
+		}
+		t.structEnd()
+	}
+
+	t.i64(3, w.total)
+
+	t.list(4, thriftStruct, len(w.rowGroups))
+	for _, group := range w.rowGroups {
+		t.structBegin()
+		t.list(1, thriftStruct, len(w.columns))
+		for i, c := range w.columns {
+			chunk := group.chunks[i]
+			t.structBegin()
+			t.i64(2, chunk.offset)
+			t.fieldStruct(3)
+			t.i32(1, c.physicalType())
+			t.list(2, thriftI32, 1)
+			t.varint(zigzag(int64(parquetPlain)))
+			t.list(3, thriftBinary, 1)
+			t.varint(uint64(len(c.name)))
+			t.buf.WriteString(c.name)
+			t.i32(4, parquetUncompressed)
+			t.i64(5, group.rows)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset)
+			t.structEnd()
+			t.structEnd()
+		}
+		t.i64(2, group.size)
+		t.i64(3, group.rows)
+		t.structEnd()
+	}
+
+	t.binary(6, []byte("evm_event_indexer"))
+	t.structEnd()
+
+	return t.buf.Bytes()
+}
+
+func (c column) physicalType() int32 {
+	if c.kind == kindString {
+		return parquetByteArray
+	}
+	return parquetInt64
+}
+
+func (c column) convertedType() (int32, bool) {
+	switch c.kind {
+	case kindString:
+		return parquetUTF8, true
+	case kindTimestamp:
+		return parquetTimestampMillis, true
+	default:
+		return 0, false
+	}
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// thriftWriter encodes structs with the thrift compact protocol
+type thriftWriter struct {
+	buf    bytes.Buffer
+	fields []int16 // last field id of the open structs
+}
+
+func (t *thriftWriter) structBegin() {
+	t.fields = append(t.fields, 0)
+}
+
+func (t *thriftWriter) structEnd() {
+	t.buf.WriteByte(0)
+	t.fields = t.fields[:len(t.fields)-1]
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.fields[len(t.fields)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, v []byte) {
+	t.field(id, thriftBinary)
+	t.varint(uint64(len(v)))
+	t.buf.Write(v)
+}
+
+// fieldStruct opens a struct field, closed by structEnd
+func (t *thriftWriter) fieldStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.structBegin()
+}
+
+// list writes the header of a list field, followed by the elements
+func (t *thriftWriter) list(id int16, elem byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elem)
+		return
+	}
+	t.buf.WriteByte(0xf0 | elem)
+	t.varint(uint64(size))
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	t.buf.Write(b[:n])
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
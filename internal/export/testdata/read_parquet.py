@@ -0,0 +1,16 @@
+# Reads a parquet file with pyarrow and prints its metadata and rows as json,
+# used by the export tests to check the written files against an independent reader.
+import json
+import sys
+
+import pyarrow.parquet as pq
+
+f = pq.ParquetFile(sys.argv[1])
+table = f.read()
+print(json.dumps({
+    "num_rows": f.metadata.num_rows,
+    "num_row_groups": f.metadata.num_row_groups,
+    "schema": {field.name: str(field.type) for field in table.schema},
+    "first": table.slice(0, 1).to_pylist()[0] if table.num_rows else None,
+    "last": table.slice(table.num_rows - 1, 1).to_pylist()[0] if table.num_rows else None,
+}, default=str))
@@ -18,6 +18,24 @@ var (
 		Help: "The total number of logs indexed",
 	}, []string{"chain_id", "address"})
 
+	// tracking the number of unconfirmed logs inserted from the websocket subscription
+	LiveLogsIndexed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "indexer_live_logs_indexed_total",
+		Help: "Total number of unconfirmed logs inserted from the websocket subscription",
+	}, []string{"chain_id", "address"})
+
+	// tracking whether the websocket subscription of a chain is connected (1) or not (0)
+	SubscriptionConnected = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "indexer_subscription_connected",
+		Help: "Whether the websocket subscription is connected",
+	}, []string{"chain_id"})
+
+	// tracking the number of websocket subscription reconnects
+	SubscriptionReconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "indexer_subscription_reconnects_total",
+		Help: "Total number of websocket subscription reconnects",
+	}, []string{"chain_id"})
+
 	// tracking the duration and status of RPC requests
 	RpcRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "indexer_rpc_duration_seconds",
@@ -44,4 +62,52 @@ var (
 		Name: "indexer_db_write_errors_total",
 		Help: "Total number of failed DB write operations",
 	}, []string{"operation"})
+
+	// tracking the number of connected log stream clients
+	StreamClients = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "indexer_stream_clients",
+		Help: "Number of connected log stream clients",
+	}, []string{"protocol"}) // protocol: sse/websocket
+
+	// tracking the number of log stream clients dropped for falling behind
+	StreamDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "indexer_stream_dropped_total",
+		Help: "Total number of log stream clients dropped for falling behind",
+	}, []string{"protocol"})
+
+	// tracking the number of webhook delivery attempts
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "indexer_webhook_deliveries_total",
+		Help: "Total number of webhook delivery attempts",
+	}, []string{"type", "status"}) // status: success/failure
+
+	// tracking the number of outbox messages delivered to the consumers
+	OutboxDelivered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "indexer_outbox_delivered_total",
+		Help: "Total number of outbox messages delivered to the consumers",
+	}, []string{"consumer", "type"}) // type: log/tombstone
+
+	// tracking the number of failed outbox deliveries
+	OutboxErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "indexer_outbox_errors_total",
+		Help: "Total number of failed outbox deliveries",
+	}, []string{"consumer"})
+
+	// tracking the number of sampled holders whose ledger balance does not match balanceOf in the last run
+	TokenDriftHolders = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "indexer_token_drift_holders",
+		Help: "Number of sampled holders whose ledger balance does not match balanceOf in the last reconciliation",
+	}, []string{"chain_id", "token"})
+
+	// tracking the number of holder balance checks
+	TokenReconcileChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "indexer_token_reconcile_checks_total",
+		Help: "Total number of holder balance checks against balanceOf",
+	}, []string{"chain_id", "token", "result"}) // result: match/drift/error
+
+	// tracking the number of finished export jobs
+	ExportJobs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "indexer_export_jobs_total",
+		Help: "Total number of finished export jobs",
+	}, []string{"format", "status"}) // status: success/failure
 )
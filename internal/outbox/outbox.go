@@ -0,0 +1,52 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+)
+
+// message types
+const (
+	TypeLog       = "log"       // a confirmed log was inserted, the payload is the json encoded log
+	TypeTombstone = "tombstone" // a log was deleted by a reorg or a rescan, the payload is empty
+)
+
+type (
+	// Message is an outbox record, written in the same transaction as the logs
+	Message struct {
+		ID          int64  // outbox id, a message may be delivered more than once so consumers should dedupe on it
+		Type        string // log or tombstone
+		Key         string // identity of the log, a tombstone has the key of the deleted log
+		ChainID     int64
+		Address     string
+		BlockNumber uint64
+		Payload     []byte // nil for tombstones
+	}
+
+	// Consumer reacts to the committed outbox messages, such as a sink, a webhook or a cache.
+	// Consume returns once the whole batch is handled, a failed batch is delivered again from its first message
+	// excluding the messages the consumer already handled.
+	Consumer interface {
+		Name() string
+		Consume(ctx context.Context, msgs ...*Message) error
+	}
+)
+
+var (
+	mu        sync.RWMutex
+	consumers []Consumer
+)
+
+// Register adds a consumer the outbox is delivered to, consumers must be registered before the relay starts
+func Register(c Consumer) {
+	mu.Lock()
+	defer mu.Unlock()
+	consumers = append(consumers, c)
+}
+
+// Consumers returns the registered consumers
+func Consumers() []Consumer {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]Consumer(nil), consumers...)
+}
@@ -0,0 +1,35 @@
+package outbox_test
+
+import (
+	"context"
+	"evm_event_indexer/internal/outbox"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type consumer struct {
+	name string
+}
+
+func (c *consumer) Name() string {
+	return c.name
+}
+
+func (c *consumer) Consume(ctx context.Context, msgs ...*outbox.Message) error {
+	return nil
+}
+
+func Test_Register(t *testing.T) {
+	outbox.Register(&consumer{name: "a"})
+	outbox.Register(&consumer{name: "b"})
+
+	consumers := outbox.Consumers()
+	assert.Len(t, consumers, 2)
+	assert.Equal(t, "a", consumers[0].Name())
+	assert.Equal(t, "b", consumers[1].Name())
+
+	// the returned slice is a copy
+	consumers[0] = nil
+	assert.NotNil(t, outbox.Consumers()[0])
+}
@@ -0,0 +1,120 @@
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned when a host resolves to an address which is not publicly routable
+var ErrForbiddenAddress = errors.New("address is not publicly routable")
+
+// special purpose ranges not covered by the netip helpers
+var forbiddenPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // this network
+	netip.MustParsePrefix("100.64.0.0/10"),  // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // reserved
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, may translate to a private IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),  // documentation
+	netip.MustParsePrefix("2002::/16"),      // 6to4, may embed a private IPv4
+}
+
+// IsPublicIP reports whether an outbound request may be sent to the address,
+// loopback, private, link-local (e.g. the 169.254.169.254 metadata service) and other special purpose addresses are rejected.
+func IsPublicIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+
+	for _, prefix := range forbiddenPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidateURL checks the scheme of an outbound url and that every address its host resolves to is public.
+// The addresses are checked again when dialing, since the DNS record may change after validation.
+func ValidateURL(ctx context.Context, rawURL string, requireHTTPS bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if requireHTTPS {
+			return fmt.Errorf("scheme must be https")
+		}
+	default:
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("host is required")
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve host %s: %w", host, err)
+	}
+
+	if len(addrs) == 0 {
+		return fmt.Errorf("host %s has no address", host)
+	}
+
+	for _, addr := range addrs {
+		if !IsPublicIP(addr) {
+			return fmt.Errorf("host %s resolves to %s: %w", host, addr, ErrForbiddenAddress)
+		}
+	}
+
+	return nil
+}
+
+// NewClient returns an http client which only connects to public addresses and does not follow redirects,
+// the redirect response is returned to the caller. Proxies from the environment are not used.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		// runs on the resolved address right before connecting, so a DNS record changed after validation is still rejected
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+
+			if !IsPublicIP(addrPort.Addr()) {
+				return fmt.Errorf("dial %s: %w", addrPort.Addr(), ErrForbiddenAddress)
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
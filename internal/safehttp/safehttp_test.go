@@ -0,0 +1,78 @@
+package safehttp_test
+
+import (
+	"context"
+	"errors"
+	"evm_event_indexer/internal/safehttp"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, safehttp.IsPublicIP(netip.MustParseAddr(tt.ip)), tt.ip)
+	}
+}
+
+func Test_ValidateURL(t *testing.T) {
+	ctx := context.TODO()
+
+	assert.NoError(t, safehttp.ValidateURL(ctx, "https://8.8.8.8/hook", true))
+	assert.NoError(t, safehttp.ValidateURL(ctx, "http://8.8.8.8/hook", false))
+	assert.Error(t, safehttp.ValidateURL(ctx, "http://8.8.8.8/hook", true))
+	assert.Error(t, safehttp.ValidateURL(ctx, "ftp://8.8.8.8/hook", false))
+
+	for _, u := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://10.1.2.3/hook",
+	} {
+		err := safehttp.ValidateURL(ctx, u, false)
+		assert.True(t, errors.Is(err, safehttp.ErrForbiddenAddress), u)
+	}
+}
+
+func Test_NewClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	// the test server listens on loopback, the dial is rejected
+	_, err := safehttp.NewClient(time.Second).Get(srv.URL)
+	assert.ErrorIs(t, err, safehttp.ErrForbiddenAddress)
+}
+
+func Test_NewClient_Redirect(t *testing.T) {
+	client := safehttp.NewClient(time.Second)
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/hook", nil)
+
+	assert.ErrorIs(t, client.CheckRedirect(req, nil), http.ErrUseLastResponse)
+}
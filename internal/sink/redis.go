@@ -0,0 +1,65 @@
+package sink
+
+import (
+	"context"
+	"evm_event_indexer/internal/outbox"
+	"evm_event_indexer/internal/storage"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ outbox.Consumer = (*RedisStream)(nil)
+
+// RedisStream appends the messages to a redis stream, the metadata and the payload are the entry fields.
+// The stream is trimmed to about maxLen entries, 0 keeps every entry.
+type RedisStream struct {
+	db     string
+	stream string
+	maxLen int64
+}
+
+func NewRedisStream(db string, stream string, maxLen int64) *RedisStream {
+	return &RedisStream{
+		db:     db,
+		stream: stream,
+		maxLen: maxLen,
+	}
+}
+
+func (r *RedisStream) Name() string {
+	return "redis"
+}
+
+func (r *RedisStream) Consume(ctx context.Context, msgs ...*outbox.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	client, err := storage.GetRedis(r.db)
+	if err != nil {
+		return err
+	}
+
+	pipe := client.Pipeline()
+	for _, msg := range msgs {
+		values := make([]any, 0, 12)
+		for k, v := range Headers(msg) {
+			values = append(values, k, v)
+		}
+		values = append(values, "key", msg.Key, "payload", msg.Payload)
+
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: r.stream,
+			MaxLen: r.maxLen,
+			Approx: r.maxLen > 0,
+			Values: values,
+		})
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to add to redis stream %s: %w", r.stream, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package sink
+
+import (
+	"context"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/outbox"
+	"fmt"
+	"strconv"
+)
+
+// InitSink registers the sinks enabled in the config as outbox consumers,
+// sinks of other brokers are registered with outbox.Register.
+func InitSink() {
+	if !config.Get().Outbox.Enabled {
+		return
+	}
+
+	cnf := config.Get().Sink
+	if cnf.RedisStream != "" {
+		outbox.Register(NewRedisStream(config.RedisCache, cnf.RedisStream, cnf.RedisMaxLen))
+	}
+}
+
+// Producer is the client of a message broker such as Kafka or NATS,
+// it sends a keyed record to a topic (subject) and returns once the broker acknowledged it.
+type Producer interface {
+	Produce(ctx context.Context, topic string, key []byte, value []byte, headers map[string]string) error
+}
+
+var _ outbox.Consumer = (*Broker)(nil)
+
+// Broker adapts a Producer to an outbox consumer. Records are keyed by the log identity, so a compacted topic keeps
+// the latest state of each log and a tombstone (nil value) deletes it.
+type Broker struct {
+	name     string
+	topic    string
+	producer Producer
+}
+
+func NewBroker(name string, topic string, producer Producer) *Broker {
+	return &Broker{
+		name:     name,
+		topic:    topic,
+		producer: producer,
+	}
+}
+
+func (b *Broker) Name() string {
+	return b.name
+}
+
+func (b *Broker) Consume(ctx context.Context, msgs ...*outbox.Message) error {
+	for _, msg := range msgs {
+		if err := b.producer.Produce(ctx, b.topic, []byte(msg.Key), msg.Payload, Headers(msg)); err != nil {
+			return fmt.Errorf("failed to produce message %d: %w", msg.ID, err)
+		}
+	}
+	return nil
+}
+
+// Headers returns the metadata of a message
+func Headers(msg *outbox.Message) map[string]string {
+	return map[string]string{
+		"id":           strconv.FormatInt(msg.ID, 10),
+		"type":         msg.Type,
+		"chain_id":     strconv.FormatInt(msg.ChainID, 10),
+		"address":      msg.Address,
+		"block_number": strconv.FormatUint(msg.BlockNumber, 10),
+	}
+}
@@ -0,0 +1,68 @@
+package sink_test
+
+import (
+	"context"
+	"errors"
+	"evm_event_indexer/internal/outbox"
+	"evm_event_indexer/internal/sink"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type record struct {
+	topic   string
+	key     string
+	value   []byte
+	headers map[string]string
+}
+
+type producer struct {
+	records []record
+	failAt  int
+}
+
+func (p *producer) Produce(ctx context.Context, topic string, key []byte, value []byte, headers map[string]string) error {
+	if p.failAt > 0 && len(p.records)+1 == p.failAt {
+		return errors.New("broker unavailable")
+	}
+	p.records = append(p.records, record{topic, string(key), value, headers})
+	return nil
+}
+
+func Test_Broker(t *testing.T) {
+	p := new(producer)
+	b := sink.NewBroker("kafka", "event_log", p)
+
+	err := b.Consume(context.TODO(),
+		&outbox.Message{ID: 1, Type: outbox.TypeLog, Key: "1:0xabc:10:0:0", ChainID: 1, Address: "0xabc", BlockNumber: 10, Payload: []byte(`{}`)},
+		&outbox.Message{ID: 2, Type: outbox.TypeTombstone, Key: "1:0xabc:10:0:0", ChainID: 1, Address: "0xabc", BlockNumber: 10},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "kafka", b.Name())
+
+	assert.Len(t, p.records, 2)
+	assert.Equal(t, "event_log", p.records[0].topic)
+	assert.Equal(t, "1:0xabc:10:0:0", p.records[0].key)
+	assert.Equal(t, []byte(`{}`), p.records[0].value)
+	assert.Equal(t, "1", p.records[0].headers["id"])
+	assert.Equal(t, outbox.TypeLog, p.records[0].headers["type"])
+
+	// a tombstone has the key of the removed log and no value
+	assert.Equal(t, p.records[0].key, p.records[1].key)
+	assert.Nil(t, p.records[1].value)
+	assert.Equal(t, outbox.TypeTombstone, p.records[1].headers["type"])
+	assert.Equal(t, "10", p.records[1].headers["block_number"])
+}
+
+func Test_Broker_Error(t *testing.T) {
+	p := &producer{failAt: 2}
+	b := sink.NewBroker("nats", "event_log", p)
+
+	err := b.Consume(context.TODO(),
+		&outbox.Message{ID: 1, Type: outbox.TypeLog, Key: "a"},
+		&outbox.Message{ID: 2, Type: outbox.TypeLog, Key: "b"},
+	)
+	assert.Error(t, err)
+	assert.Len(t, p.records, 1)
+}
@@ -0,0 +1,114 @@
+package stream
+
+import (
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/eventlog"
+	"strings"
+)
+
+// Filter selects the events of a subscriber, matching logs the same way as the logs query,
+// values within a slice are ORed, fields are ANDed.
+type Filter struct {
+	ChainID   int64
+	Addresses []string
+	TxHashes  []string
+	Topic0s   []string
+	Topic1s   []string
+	Topic2s   []string
+	Topic3s   []string
+	Args      []eventlog.ArgFilter
+}
+
+// NewFilter returns the filter of a logs query, range, order and pagination are not used by a stream.
+func NewFilter(p *eventlog.GetLogParam) Filter {
+	return Filter{
+		ChainID:   p.ChainID,
+		Addresses: p.Addresses,
+		TxHashes:  p.TxHashes,
+		Topic0s:   p.Topic0s,
+		Topic1s:   p.Topic1s,
+		Topic2s:   p.Topic2s,
+		Topic3s:   p.Topic3s,
+		Args:      p.Args,
+	}
+}
+
+// Match reports whether the event is selected by the filter,
+// removed events only carry the chain and address, so only those are matched.
+func (f Filter) Match(e Event) bool {
+	if f.ChainID != 0 && f.ChainID != e.ChainID {
+		return false
+	}
+
+	if !matchAny(f.Addresses, e.Address) {
+		return false
+	}
+
+	if e.Type == EventRemoved {
+		return true
+	}
+
+	log := e.Log
+	if log == nil {
+		return false
+	}
+
+	if !matchAny(f.TxHashes, log.TxHash) ||
+		!matchAny(f.Topic0s, log.Topic0) ||
+		!matchAny(f.Topic1s, log.Topic1) ||
+		!matchAny(f.Topic2s, log.Topic2) ||
+		!matchAny(f.Topic3s, log.Topic3) {
+		return false
+	}
+
+	for _, arg := range f.Args {
+		if !matchArg(arg, log.Args) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchAny reports whether the value is one of the values, an empty list matches everything.
+// hex values are compared case insensitively, like the mysql collation.
+func matchAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchArg compares normalized values as strings, the same comparison as the event_arg query
+func matchArg(f eventlog.ArgFilter, args []*model.EventArg) bool {
+	for _, arg := range args {
+		if arg.Name != f.Name {
+			continue
+		}
+
+		switch f.Op {
+		case model.ArgOpEq:
+			return arg.Value == f.Value
+		case model.ArgOpGt:
+			return arg.Value > f.Value
+		case model.ArgOpGte:
+			return arg.Value >= f.Value
+		case model.ArgOpLt:
+			return arg.Value < f.Value
+		case model.ArgOpLte:
+			return arg.Value <= f.Value
+		case model.ArgOpPrefix:
+			return strings.HasPrefix(arg.Value, f.Value)
+		default:
+			return false
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,139 @@
+package stream
+
+import (
+	"evm_event_indexer/service/model"
+	"sync"
+)
+
+// default number of events buffered per subscriber before InitHub is called
+const defaultBufferSize = 256
+
+// Logs is the process wide hub of indexed logs, published by the service layer after commit.
+var Logs = NewHub(defaultBufferSize)
+
+// InitHub replaces the shared hub with the configured one.
+func InitHub(bufferSize int) {
+	Logs = NewHub(bufferSize)
+}
+
+const (
+	EventLog     EventType = "log"     // a log was indexed
+	EventRemoved EventType = "removed" // the logs of an address after a block were rolled back
+)
+
+type (
+	EventType string
+
+	Event struct {
+		Type        EventType
+		Log         *model.Log // indexed log, only for EventLog
+		ChainID     int64
+		Address     string
+		BlockNumber uint64 // logs after this block were removed, only for EventRemoved
+	}
+
+	// Hub fans out events to the subscribers, a subscriber that does not keep up is dropped
+	// instead of blocking the indexer, it is expected to reconnect and resume from its cursor.
+	Hub struct {
+		mu         sync.RWMutex
+		bufferSize int
+		subs       map[*Subscriber]struct{}
+	}
+
+	Subscriber struct {
+		filter  Filter
+		events  chan Event
+		dropped chan struct{}
+		once    sync.Once
+	}
+)
+
+func NewHub(bufferSize int) *Hub {
+	return &Hub{
+		bufferSize: bufferSize,
+		subs:       make(map[*Subscriber]struct{}),
+	}
+}
+
+// Subscribe registers a subscriber receiving the events matching the filter.
+func (h *Hub) Subscribe(filter Filter) *Subscriber {
+	s := &Subscriber{
+		filter:  filter,
+		events:  make(chan Event, h.bufferSize),
+		dropped: make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+
+	return s
+}
+
+// Unsubscribe removes the subscriber, no more events are sent to it.
+func (h *Hub) Unsubscribe(s *Subscriber) {
+	h.mu.Lock()
+	delete(h.subs, s)
+	h.mu.Unlock()
+}
+
+// Publish sends the events to the matching subscribers without blocking.
+func (h *Hub) Publish(events ...Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for s := range h.subs {
+		for _, e := range events {
+			if !s.filter.Match(e) {
+				continue
+			}
+
+			select {
+			case s.events <- e:
+			default:
+				s.drop()
+			}
+		}
+	}
+}
+
+// Len returns the number of subscribers.
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
+
+// Events returns the channel of matching events.
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped is closed when the subscriber fell behind and missed events.
+func (s *Subscriber) Dropped() <-chan struct{} {
+	return s.dropped
+}
+
+func (s *Subscriber) drop() {
+	s.once.Do(func() {
+		close(s.dropped)
+	})
+}
+
+// NewLogEvents returns the events of indexed logs.
+func NewLogEvents(logs []*model.Log) []Event {
+	events := make([]Event, len(logs))
+	for i, log := range logs {
+		events[i] = Event{
+			Type:    EventLog,
+			Log:     log,
+			ChainID: log.ChainID,
+			Address: log.Address,
+		}
+	}
+	return events
+}
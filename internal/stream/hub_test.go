@@ -0,0 +1,87 @@
+package stream_test
+
+import (
+	"evm_event_indexer/internal/stream"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/eventlog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Hub(t *testing.T) {
+	hub := stream.NewHub(2)
+	address := "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+	transfer := "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+	sub := hub.Subscribe(stream.Filter{
+		ChainID:   31337,
+		Addresses: []string{address},
+		Topic0s:   []string{transfer},
+	})
+	other := hub.Subscribe(stream.Filter{ChainID: 1})
+	assert.Equal(t, 2, hub.Len())
+
+	logs := []*model.Log{
+		{ChainID: 31337, Address: "0x5fbdb2315678afecb367f032d93f642f64180aa3", Topic0: transfer, BlockNumber: 1},
+		{ChainID: 31337, Address: address, Topic0: "0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925", BlockNumber: 1},
+	}
+	hub.Publish(stream.NewLogEvents(logs)...)
+	hub.Publish(stream.Event{Type: stream.EventRemoved, ChainID: 31337, Address: address, BlockNumber: 0})
+
+	// address matches case insensitively, the approval is filtered out
+	e := <-sub.Events()
+	assert.Equal(t, stream.EventLog, e.Type)
+	assert.Same(t, logs[0], e.Log)
+
+	// removed events match by chain and address only
+	e = <-sub.Events()
+	assert.Equal(t, stream.EventRemoved, e.Type)
+
+	assert.Len(t, other.Events(), 0)
+
+	// a subscriber that does not keep up is dropped instead of blocking
+	for range 3 {
+		hub.Publish(stream.NewLogEvents(logs[:1])...)
+	}
+	select {
+	case <-sub.Dropped():
+	default:
+		t.Fatal("subscriber should be dropped")
+	}
+
+	hub.Unsubscribe(sub)
+	hub.Unsubscribe(other)
+	assert.Equal(t, 0, hub.Len())
+}
+
+func Test_Filter_Args(t *testing.T) {
+	value := "000000000000000000000000000000000000000000000000000000000000000000000000001000"
+	log := &model.Log{
+		ChainID: 31337,
+		Args: []*model.EventArg{
+			{Name: "from", Value: "0xf39fd6e51aad88f6f4ce6ab8827279cfffb92266"},
+			{Name: "value", Value: value},
+		},
+	}
+	e := stream.Event{Type: stream.EventLog, Log: log, ChainID: log.ChainID}
+
+	testCases := []struct {
+		name  string
+		args  []eventlog.ArgFilter
+		match bool
+	}{
+		{"eq", []eventlog.ArgFilter{{Name: "value", Op: model.ArgOpEq, Value: value}}, true},
+		{"gte", []eventlog.ArgFilter{{Name: "value", Op: model.ArgOpGte, Value: value}}, true},
+		{"gt", []eventlog.ArgFilter{{Name: "value", Op: model.ArgOpGt, Value: value}}, false},
+		{"prefix", []eventlog.ArgFilter{{Name: "from", Op: model.ArgOpPrefix, Value: "0xf39f"}}, true},
+		{"all args", []eventlog.ArgFilter{{Name: "from", Op: model.ArgOpPrefix, Value: "0xf39f"}, {Name: "value", Op: model.ArgOpLt, Value: value}}, false},
+		{"missing arg", []eventlog.ArgFilter{{Name: "owner", Op: model.ArgOpEq, Value: "0x"}}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.match, stream.Filter{Args: tc.args}.Match(e))
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// selector of balanceOf(address)
+var balanceOfSelector = []byte{0x70, 0xa0, 0x82, 0x31}
+
+// EthNode is a fake json-rpc node serving an in-memory chain over http and websocket, only for test file.
+// It answers the calls made by the indexer and counts them by method.
+type EthNode struct {
+	URL string // http endpoint
+	WS  string // websocket endpoint
+
+	server *httptest.Server
+	rpc    *rpc.Server
+	feed   event.Feed // new logs pushed to the log subscriptions
+
+	mu       sync.Mutex
+	chainID  int64
+	head     uint64
+	headers  map[uint64]*types.Header
+	byHash   map[common.Hash]*types.Header
+	logs     []types.Log
+	balances map[common.Address]*big.Int
+	fails    map[string]error
+	calls    map[string]int
+}
+
+// NewEthNode starts a fake node of the chain, it is closed when the test ends
+func NewEthNode(t interface{ Cleanup(func()) }, chainID int64) *EthNode {
+	n := &EthNode{
+		chainID:  chainID,
+		headers:  make(map[uint64]*types.Header),
+		byHash:   make(map[common.Hash]*types.Header),
+		balances: make(map[common.Address]*big.Int),
+		fails:    make(map[string]error),
+		calls:    make(map[string]int),
+	}
+
+	n.rpc = n.newServer()
+	n.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.mu.Lock()
+		srv := n.rpc
+		n.mu.Unlock()
+
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			srv.WebsocketHandler([]string{"*"}).ServeHTTP(w, r)
+			return
+		}
+		srv.ServeHTTP(w, r)
+	}))
+	n.URL = n.server.URL
+	n.WS = "ws" + strings.TrimPrefix(n.server.URL, "http")
+
+	t.Cleanup(func() {
+		n.server.Close()
+		n.rpc.Stop()
+	})
+
+	return n
+}
+
+// AddBlock adds a block with a header distinguished by the extra data and moves the head to it if higher,
+// adding another block at the same number replaces it like a reorg does
+func (n *EthNode) AddBlock(number uint64, extra string) *types.Header {
+	header := &types.Header{
+		Number:     new(big.Int).SetUint64(number),
+		Difficulty: new(big.Int),
+		Time:       1700000000 + number*12,
+		Extra:      []byte(extra),
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.headers[number] = header
+	n.byHash[header.Hash()] = header
+	n.head = max(n.head, number)
+
+	return header
+}
+
+// SetHead sets the latest block number
+func (n *EthNode) SetHead(number uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.head = number
+}
+
+// AddLogs adds canonical logs returned by eth_getLogs
+func (n *EthNode) AddLogs(logs ...types.Log) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, log := range logs {
+		n.logs = append(n.logs, withTopics(log))
+	}
+}
+
+// SetBalance sets the balance returned by balanceOf of the holder
+func (n *EthNode) SetBalance(holder common.Address, balance *big.Int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.balances[holder] = balance
+}
+
+// Fail makes every call of the method return the error, a nil error restores the method
+func (n *EthNode) Fail(method string, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err == nil {
+		delete(n.fails, method)
+		return
+	}
+	n.fails[method] = err
+}
+
+// Calls returns the number of calls of the method, each call of a batch is counted
+func (n *EthNode) Calls(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.calls[method]
+}
+
+// Emit sends a log to the log subscriptions
+func (n *EthNode) Emit(log types.Log) {
+	n.feed.Send(withTopics(log))
+}
+
+// Disconnect drops every open connection, the websocket subscriptions end with an error,
+// new connections are served by a fresh server
+func (n *EthNode) Disconnect() {
+	n.mu.Lock()
+	old := n.rpc
+	n.rpc = n.newServer()
+	n.mu.Unlock()
+
+	old.Stop()
+	n.server.CloseClientConnections()
+}
+
+func (n *EthNode) newServer() *rpc.Server {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("eth", &ethAPI{node: n}); err != nil {
+		panic(err)
+	}
+	return srv
+}
+
+// call counts a call of the method and returns its injected error
+func (n *EthNode) call(method string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.calls[method]++
+	return n.fails[method]
+}
+
+// ethAPI is the eth namespace of the fake node
+type ethAPI struct {
+	node *EthNode
+}
+
+// filterArg is the filter object of eth_getLogs, block numbers are hex encoded
+type filterArg struct {
+	FromBlock hexutil.Uint64   `json:"fromBlock"`
+	ToBlock   hexutil.Uint64   `json:"toBlock"`
+	Addresses []common.Address `json:"address"`
+}
+
+func (api *ethAPI) ChainId() (*hexutil.Big, error) {
+	if err := api.node.call("eth_chainId"); err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(big.NewInt(api.node.chainID)), nil
+}
+
+func (api *ethAPI) BlockNumber() (hexutil.Uint64, error) {
+	if err := api.node.call("eth_blockNumber"); err != nil {
+		return 0, err
+	}
+
+	api.node.mu.Lock()
+	defer api.node.mu.Unlock()
+
+	return hexutil.Uint64(api.node.head), nil
+}
+
+func (api *ethAPI) GetBlockByNumber(number rpc.BlockNumber, _ bool) (*types.Header, error) {
+	if err := api.node.call("eth_getBlockByNumber"); err != nil {
+		return nil, err
+	}
+
+	api.node.mu.Lock()
+	defer api.node.mu.Unlock()
+
+	if number < 0 {
+		return api.node.headers[api.node.head], nil
+	}
+	return api.node.headers[uint64(number)], nil
+}
+
+func (api *ethAPI) GetBlockByHash(hash common.Hash, _ bool) (*types.Header, error) {
+	if err := api.node.call("eth_getBlockByHash"); err != nil {
+		return nil, err
+	}
+
+	api.node.mu.Lock()
+	defer api.node.mu.Unlock()
+
+	return api.node.byHash[hash], nil
+}
+
+func (api *ethAPI) GetLogs(arg filterArg) ([]types.Log, error) {
+	if err := api.node.call("eth_getLogs"); err != nil {
+		return nil, err
+	}
+
+	api.node.mu.Lock()
+	defer api.node.mu.Unlock()
+
+	if uint64(arg.ToBlock) > api.node.head {
+		return nil, errors.New("block range extends beyond current head block")
+	}
+
+	res := make([]types.Log, 0)
+	for _, log := range api.node.logs {
+		if log.BlockNumber < uint64(arg.FromBlock) || log.BlockNumber > uint64(arg.ToBlock) {
+			continue
+		}
+		if len(arg.Addresses) > 0 && !containsAddress(arg.Addresses, log.Address) {
+			continue
+		}
+		res = append(res, log)
+	}
+
+	return res, nil
+}
+
+// Call answers balanceOf(address) from the balances set on the node
+func (api *ethAPI) Call(arg map[string]any, _ any) (hexutil.Bytes, error) {
+	if err := api.node.call("eth_call"); err != nil {
+		return nil, err
+	}
+
+	raw, _ := arg["input"].(string)
+	if raw == "" {
+		raw, _ = arg["data"].(string)
+	}
+	input, err := hexutil.Decode(raw)
+	if err != nil || len(input) != 36 || string(input[:4]) != string(balanceOfSelector) {
+		return nil, errors.New("execution reverted")
+	}
+
+	api.node.mu.Lock()
+	defer api.node.mu.Unlock()
+
+	balance, ok := api.node.balances[common.BytesToAddress(input[4:])]
+	if !ok {
+		balance = new(big.Int)
+	}
+
+	return common.LeftPadBytes(balance.Bytes(), 32), nil
+}
+
+// Logs is the logs subscription of eth_subscribe, every emitted log is sent,
+// the call is counted once the subscription receives logs, so a test can emit right after it sees the count
+func (api *ethAPI) Logs(ctx context.Context, _ map[string]any) (*rpc.Subscription, error) {
+	notifier, ok := rpc.NotifierFromContext(ctx)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+
+	ch := make(chan types.Log, 16)
+	feedSub := api.node.feed.Subscribe(ch)
+	if err := api.node.call("eth_subscribe"); err != nil {
+		feedSub.Unsubscribe()
+		return nil, err
+	}
+
+	sub := notifier.CreateSubscription()
+
+	go func() {
+		defer feedSub.Unsubscribe()
+		for {
+			select {
+			case log := <-ch:
+				_ = notifier.Notify(sub.ID, log)
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+// withTopics sets empty topics on a log without any, logs are rejected by the client without the field
+func withTopics(log types.Log) types.Log {
+	if log.Topics == nil {
+		log.Topics = []common.Hash{}
+	}
+	return log
+}
+
+func containsAddress(addresses []common.Address, address common.Address) bool {
+	for _, v := range addresses {
+		if v == address {
+			return true
+		}
+	}
+	return false
+}
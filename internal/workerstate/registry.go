@@ -0,0 +1,143 @@
+package workerstate
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scanners is the process wide registry of the scanner states, reported by the scanners and read by the sync status api.
+var Scanners = NewRegistry()
+
+const (
+	StateStarting State = "starting" // registered, no scan has finished yet
+	StateSyncing  State = "syncing"  // the last scan indexed a batch, the head is not reached yet
+	StateSynced   State = "synced"   // caught up with the head minus the confirmations
+	StateFailing  State = "failing"  // the last scan failed, see the last error
+	StateStopped  State = "stopped"  // the worker has exited
+	StateInactive State = "inactive" // not scanned by this process, e.g. removed from the scanner config
+)
+
+type (
+	State string
+
+	// Head is the head block of a chain observed by its scanners
+	Head struct {
+		Number    uint64
+		UpdatedAt time.Time
+	}
+
+	// Scanner is the last reported state of the scanner of a contract
+	Scanner struct {
+		State       State
+		SyncedTime  time.Time // timestamp of the last synced block, zero until a batch is indexed
+		ScannedAt   time.Time // end of the last scan
+		LastError   string
+		LastErrorAt time.Time
+	}
+
+	Registry struct {
+		mu       sync.RWMutex
+		heads    map[int64]Head
+		scanners map[string]*Scanner
+	}
+)
+
+func NewRegistry() *Registry {
+	return &Registry{
+		heads:    make(map[int64]Head),
+		scanners: make(map[string]*Scanner),
+	}
+}
+
+// Register adds the scanner of a contract in the starting state
+func (r *Registry) Register(chainID int64, address string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.scanners[scannerKey(chainID, address)] = &Scanner{State: StateStarting}
+}
+
+// SetHead records the head of a chain, scanners of a chain share the same head
+func (r *Registry) SetHead(chainID int64, number uint64, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.heads[chainID] = Head{Number: number, UpdatedAt: now}
+}
+
+// Scanned records a successful scan, syncedTime is the timestamp of the synced block, zero when nothing was indexed
+func (r *Registry) Scanned(chainID int64, address string, syncedTime time.Time, caughtUp bool, now time.Time) {
+	r.update(chainID, address, func(s *Scanner) {
+		s.State = StateSyncing
+		if caughtUp {
+			s.State = StateSynced
+		}
+		if !syncedTime.IsZero() {
+			s.SyncedTime = syncedTime
+		}
+		s.ScannedAt = now
+	})
+}
+
+// Failed records a failed scan, the error is kept after the scanner recovers
+func (r *Registry) Failed(chainID int64, address string, err error, now time.Time) {
+	r.update(chainID, address, func(s *Scanner) {
+		s.State = StateFailing
+		s.ScannedAt = now
+		s.LastError = err.Error()
+		s.LastErrorAt = now
+	})
+}
+
+// Stopped records the exit of a scanner, err is the cause when it did not stop with its context
+func (r *Registry) Stopped(chainID int64, address string, err error, now time.Time) {
+	r.update(chainID, address, func(s *Scanner) {
+		s.State = StateStopped
+		if err != nil {
+			s.LastError = err.Error()
+			s.LastErrorAt = now
+		}
+	})
+}
+
+// Head returns the head of a chain, false if no scanner of the chain has read it yet
+func (r *Registry) Head(chainID int64) (Head, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	head, ok := r.heads[chainID]
+	return head, ok
+}
+
+// Scanner returns a copy of the state of the scanner of a contract, false if it is not running in this process
+func (r *Registry) Scanner(chainID int64, address string) (Scanner, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.scanners[scannerKey(chainID, address)]
+	if !ok {
+		return Scanner{}, false
+	}
+	return *s, true
+}
+
+// update applies a change to the state of a scanner, registering it if needed
+func (r *Registry) update(chainID int64, address string, fn func(s *Scanner)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := scannerKey(chainID, address)
+	s, ok := r.scanners[key]
+	if !ok {
+		s = &Scanner{State: StateStarting}
+		r.scanners[key] = s
+	}
+	fn(s)
+}
+
+// scannerKey is case insensitive, the configured address case may differ from the stored one
+func scannerKey(chainID int64, address string) string {
+	return fmt.Sprintf("%d:%s", chainID, strings.ToLower(address))
+}
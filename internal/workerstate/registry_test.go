@@ -0,0 +1,55 @@
+package workerstate_test
+
+import (
+	"errors"
+	"evm_event_indexer/internal/workerstate"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Registry(t *testing.T) {
+	registry := workerstate.NewRegistry()
+	address := "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+	now := time.Now()
+
+	_, ok := registry.Scanner(31337, address)
+	assert.False(t, ok)
+
+	registry.Register(31337, address)
+	scanner, ok := registry.Scanner(31337, address)
+	require.True(t, ok)
+	assert.Equal(t, workerstate.StateStarting, scanner.State)
+
+	_, ok = registry.Head(31337)
+	assert.False(t, ok)
+	registry.SetHead(31337, 100, now)
+	head, ok := registry.Head(31337)
+	require.True(t, ok)
+	assert.Equal(t, uint64(100), head.Number)
+
+	// addresses are case insensitive
+	synced := now.Add(-time.Minute)
+	registry.Scanned(31337, "0x5fbdb2315678afecb367f032d93f642f64180aa3", synced, false, now)
+	scanner, _ = registry.Scanner(31337, address)
+	assert.Equal(t, workerstate.StateSyncing, scanner.State)
+	assert.Equal(t, synced, scanner.SyncedTime)
+
+	registry.Failed(31337, address, errors.New("rpc unavailable"), now)
+	scanner, _ = registry.Scanner(31337, address)
+	assert.Equal(t, workerstate.StateFailing, scanner.State)
+	assert.Equal(t, "rpc unavailable", scanner.LastError)
+
+	// an idle scan keeps the synced time and the last error
+	registry.Scanned(31337, address, time.Time{}, true, now)
+	scanner, _ = registry.Scanner(31337, address)
+	assert.Equal(t, workerstate.StateSynced, scanner.State)
+	assert.Equal(t, synced, scanner.SyncedTime)
+	assert.Equal(t, "rpc unavailable", scanner.LastError)
+
+	registry.Stopped(31337, address, nil, now)
+	scanner, _ = registry.Scanner(31337, address)
+	assert.Equal(t, workerstate.StateStopped, scanner.State)
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/decoder"
+	"evm_event_indexer/internal/decoder/provider"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/eventlog"
+	"evm_event_indexer/service/repo/eventrollup"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// rollupField are the decoded fields aggregated by the rollups of an event
+type rollupField struct {
+	volume string // first uint256 field, summed
+	sender string // first address field, counted uniquely
+}
+
+// rollupDelta is the change of a rollup bucket by the logs added and removed
+type rollupDelta struct {
+	bucket  *model.EventRollup
+	count   int64
+	volume  *big.Int
+	senders map[string]int64 // log count change of each sender
+}
+
+// newRollupFields returns the aggregated fields of the registered events, keyed by lowercase topic0
+func newRollupFields() map[string]rollupField {
+	res := make(map[string]rollupField)
+	for _, event := range decoder.Provider.Events() {
+		var field rollupField
+		for _, f := range event.Fields {
+			if f.Type == provider.FieldUint256 && field.volume == "" {
+				field.volume = f.Name
+			}
+			if f.Type == provider.FieldAddress && field.sender == "" {
+				field.sender = f.Name
+			}
+		}
+		res[strings.ToLower(event.Topic0.Hex())] = field
+	}
+	return res
+}
+
+// txUpdateEventRollup replaces the confirmed logs of the address from the given block number with the logs in the rollups,
+// it must run before the replaced logs are deleted.
+func txUpdateEventRollup(ctx context.Context, tx *sql.Tx, chainID int64, address string, fromBN uint64, logs []*model.Log, now time.Time) error {
+	removed, err := eventlog.TxGetConfirmedLogFrom(ctx, tx, chainID, address, fromBN)
+	if err != nil {
+		return fmt.Errorf("failed to get replaced logs: %w", err)
+	}
+
+	if len(removed) == 0 && len(logs) == 0 {
+		return nil
+	}
+
+	fields := newRollupFields()
+	keys := make([]string, 0)
+	deltas := make(map[string]*rollupDelta)
+	change := func(log *model.Log, sign int64) error {
+		topic0 := strings.ToLower(log.Topic0)
+		field := fields[topic0]
+
+		args := make(map[string]string, len(log.Args))
+		for _, arg := range log.Args {
+			args[arg.Name] = arg.Value
+		}
+
+		volume := new(big.Int)
+		if value, ok := args[field.volume]; ok {
+			if _, ok := volume.SetString(value, 10); !ok {
+				return fmt.Errorf("invalid %s value %s at block %d log %d", field.volume, value, log.BlockNumber, log.LogIndex)
+			}
+		}
+		volume.Mul(volume, big.NewInt(sign))
+
+		// mints are sent from the zero address, which is not a sender
+		sender := args[field.sender]
+		if sender == zeroAddress {
+			sender = ""
+		}
+
+		for _, bucket := range model.RollupBuckets {
+			start := bucket.Start(log.BlockTimestamp)
+			key := eventrollup.Key(topic0, bucket, start)
+			d, ok := deltas[key]
+			if !ok {
+				d = &rollupDelta{
+					bucket: &model.EventRollup{
+						ChainID:     chainID,
+						Address:     address,
+						Topic0:      topic0,
+						Bucket:      bucket,
+						BucketStart: start,
+					},
+					volume:  new(big.Int),
+					senders: make(map[string]int64),
+				}
+				deltas[key] = d
+				keys = append(keys, key)
+			}
+
+			d.count += sign
+			d.volume.Add(d.volume, volume)
+			if sender != "" {
+				d.senders[sender] += sign
+			}
+		}
+		return nil
+	}
+
+	for _, log := range removed {
+		if err := change(log, -1); err != nil {
+			return err
+		}
+	}
+	for _, log := range logs {
+		if !log.Confirmed {
+			continue
+		}
+		if err := change(log, 1); err != nil {
+			return err
+		}
+	}
+
+	buckets := make([]*model.EventRollup, 0, len(keys))
+	senders := make([]*model.EventRollupSender, 0)
+	for _, key := range keys {
+		d := deltas[key]
+		buckets = append(buckets, d.bucket)
+		for sender, count := range d.senders {
+			if count == 0 {
+				continue
+			}
+			senders = append(senders, &model.EventRollupSender{
+				ChainID:     chainID,
+				Address:     address,
+				Topic0:      d.bucket.Topic0,
+				Bucket:      d.bucket.Bucket,
+				BucketStart: d.bucket.BucketStart,
+				Sender:      sender,
+				LogCount:    count,
+			})
+		}
+	}
+
+	// lock the buckets before their senders
+	current, err := eventrollup.TxGetRollupForUpdate(ctx, tx, chainID, address, buckets)
+	if err != nil {
+		return fmt.Errorf("failed to get rollups: %w", err)
+	}
+
+	if err := eventrollup.TxAddSender(ctx, tx, senders...); err != nil {
+		return fmt.Errorf("failed to add rollup senders: %w", err)
+	}
+
+	if err := eventrollup.TxDeleteEmptySender(ctx, tx, chainID, address, buckets); err != nil {
+		return fmt.Errorf("failed to delete rollup senders: %w", err)
+	}
+
+	counts, err := eventrollup.TxCountSender(ctx, tx, chainID, address, buckets)
+	if err != nil {
+		return fmt.Errorf("failed to count rollup senders: %w", err)
+	}
+
+	upserted := make([]*model.EventRollup, 0, len(keys))
+	emptied := make([]*model.EventRollup, 0)
+	for _, key := range keys {
+		d := deltas[key]
+		rollup, ok := current[key]
+		if !ok {
+			rollup = d.bucket
+			rollup.Volume = new(big.Int)
+		}
+
+		rollup.LogCount += d.count
+		rollup.Volume = new(big.Int).Add(rollup.Volume, d.volume)
+		rollup.Senders = counts[key]
+		rollup.UpdatedAt = now
+
+		if rollup.LogCount <= 0 {
+			emptied = append(emptied, rollup)
+			continue
+		}
+		upserted = append(upserted, rollup)
+	}
+
+	if err := eventrollup.TxDeleteRollup(ctx, tx, chainID, address, emptied); err != nil {
+		return fmt.Errorf("failed to delete rollups: %w", err)
+	}
+
+	if err := eventrollup.TxUpsertRollup(ctx, tx, upserted...); err != nil {
+		return fmt.Errorf("failed to upsert rollups: %w", err)
+	}
+
+	return nil
+}
+
+// GetEventRollups returns the rollups of a contract within a time range, the range must not exceed api.max_stats_buckets buckets
+func GetEventRollups(ctx context.Context, filter *eventrollup.GetRollupFilter) ([]*model.EventRollup, error) {
+	if !filter.Bucket.Valid() {
+		return nil, errors.ErrApiInvalidParam.New(fmt.Sprintf("invalid bucket %s, expected hour or day", filter.Bucket))
+	}
+
+	if filter.EndTime.Before(filter.StartTime) {
+		return nil, errors.ErrApiInvalidParam.New("end time should not be before start time")
+	}
+
+	filter.StartTime = filter.Bucket.Start(filter.StartTime)
+	if n := filter.EndTime.Sub(filter.StartTime)/filter.Bucket.Duration() + 1; n > time.Duration(config.Get().API.MaxStatsBuckets) {
+		return nil, errors.ErrApiInvalidParam.New(fmt.Sprintf("time range should not exceed %d buckets", config.Get().API.MaxStatsBuckets))
+	}
+
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	rollups, err := eventrollup.GetRollups(ctx, db, filter)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get rollups")
+	}
+
+	return rollups, nil
+}
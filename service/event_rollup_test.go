@@ -0,0 +1,82 @@
+package service_test
+
+import (
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/eventrollup"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertRollup checks the Transfer rollups of every bucket size, the test logs all fall into one bucket of each,
+// a zero count means the bucket has no row
+func assertRollup(t *testing.T, contract string, count int64, volume string, senders int64) {
+	t.Helper()
+
+	db, err := storage.GetMySQL(config.EventDBM)
+	require.NoError(t, err)
+
+	for _, bucket := range model.RollupBuckets {
+		rollups, err := eventrollup.GetRollups(ctx, db, &eventrollup.GetRollupFilter{
+			ChainID:   chainID,
+			Address:   contract,
+			Topic0s:   []string{strings.ToLower(transferTopic)},
+			Bucket:    bucket,
+			StartTime: time.Unix(0, 0),
+			EndTime:   time.Now(),
+		})
+		require.NoError(t, err)
+
+		if count == 0 {
+			assert.Empty(t, rollups, bucket)
+			continue
+		}
+
+		if assert.Len(t, rollups, 1, bucket) {
+			assert.Equal(t, count, rollups[0].LogCount, bucket)
+			assert.Equal(t, volume, rollups[0].Volume.String(), bucket)
+			assert.Equal(t, senders, rollups[0].Senders, bucket)
+		}
+	}
+}
+
+func Test_EventRollup_Reorg(t *testing.T) {
+	contract := newContract(t)
+	zero := common.Address{}.Hex()
+	a, b, c := newHolder(1), newHolder(2), newHolder(3)
+
+	scan(t, contract, 1, 4,
+		newEventLog(t, contract, transferTopic, 1, 0, zero, a, 100),
+		newEventLog(t, contract, transferTopic, 2, 0, a, b, 30),
+		newEventLog(t, contract, transferTopic, 3, 0, b, c, 10),
+		newEventLog(t, contract, transferTopic, 4, 0, a, c, 5),
+	)
+
+	// the zero address of mints is not a sender
+	assertRollup(t, contract, 4, "145", 2)
+
+	// a rescan overlapping the synced range subtracts the replaced logs before adding them again
+	scan(t, contract, 3, 5,
+		newEventLog(t, contract, transferTopic, 3, 0, b, c, 10),
+		newEventLog(t, contract, transferTopic, 4, 0, a, c, 5),
+		newEventLog(t, contract, transferTopic, 5, 0, c, a, 1),
+	)
+
+	assertRollup(t, contract, 5, "146", 3)
+
+	// the reorg subtracts the logs after the checkpoint, senders without remaining logs are no longer counted
+	reorg(t, contract, 2)
+
+	assertRollup(t, contract, 2, "130", 1)
+
+	// rolling back every log deletes the emptied buckets
+	reorg(t, contract, 0)
+
+	assertRollup(t, contract, 0, "", 0)
+}
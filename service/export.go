@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/enum"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/internal/export"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/eventlog"
+	"evm_event_indexer/service/repo/exportjob"
+	"evm_event_indexer/utils"
+	"evm_event_indexer/utils/hashing"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// NewExportFilter returns the export filter of a logs query, order and pagination are not used by an export.
+func NewExportFilter(p *eventlog.GetLogParam) *model.ExportFilter {
+	return &model.ExportFilter{
+		WebhookFilter:  *NewWebhookFilter(p),
+		TxHashes:       p.TxHashes,
+		BlockNumberGTE: p.BlockNumberGTE,
+		BlockNumberLTE: p.BlockNumberLTE,
+		StartTime:      p.StartTime,
+		EndTime:        p.EndTime,
+	}
+}
+
+// newExportParam returns the logs query of an export filter, ordered by block number so it can be read with keyset pagination
+func newExportParam(f *model.ExportFilter, batchSize uint64) *eventlog.GetLogParam {
+	param := &eventlog.GetLogParam{
+		ChainID:        f.ChainID,
+		Addresses:      f.Addresses,
+		TxHashes:       f.TxHashes,
+		Topic0s:        f.Topic0s,
+		Topic1s:        f.Topic1s,
+		Topic2s:        f.Topic2s,
+		Topic3s:        f.Topic3s,
+		StartTime:      f.StartTime,
+		EndTime:        f.EndTime,
+		BlockNumberGTE: f.BlockNumberGTE,
+		BlockNumberLTE: f.BlockNumberLTE,
+		OrderBy:        2,
+		Pagination:     &model.Pagination{Page: 1, Size: batchSize},
+	}
+
+	for _, arg := range f.Args {
+		param.Args = append(param.Args, eventlog.ArgFilter{
+			Name:  arg.Name,
+			Op:    arg.Op,
+			Value: arg.Value,
+		})
+	}
+
+	return param
+}
+
+// CheckExportFilter validates an export, exports follow the range limits of the logs query and are read per chain.
+func CheckExportFilter(filter *model.ExportFilter) error {
+	if filter.ChainID == 0 {
+		return errors.ErrApiInvalidParam.New("chain_id is required")
+	}
+
+	return CheckLogRange(newExportParam(filter, 1))
+}
+
+// ExportLogs writes the logs matching the filter in block number order and returns the number of written logs,
+// the logs are read in batches so memory use does not grow with the size of the export. The writer is not closed.
+func ExportLogs(ctx context.Context, filter *model.ExportFilter, w export.Writer) (int64, error) {
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	batchSize := config.Get().Export.BatchSize
+	param := newExportParam(filter, batchSize)
+
+	var rows int64
+	for {
+		logs, err := eventlog.GetLogs(ctx, db, param)
+		if err != nil {
+			return rows, fmt.Errorf("failed to get logs: %w", err)
+		}
+
+		for _, log := range logs {
+			if err := w.Write(log); err != nil {
+				return rows, fmt.Errorf("failed to write log: %w", err)
+			}
+			rows++
+		}
+
+		if uint64(len(logs)) < batchSize {
+			return rows, nil
+		}
+
+		param.Cursor = model.NewLogCursor(logs[len(logs)-1])
+	}
+}
+
+// CreateExportJob queues an export of the logs matching the filter for the user.
+func CreateExportJob(ctx context.Context, userID int64, format export.Format, filter *model.ExportFilter) (*model.ExportJob, error) {
+	if userID <= 0 {
+		return nil, errors.ErrApiInvalidParam.New("invalid user id")
+	}
+	if !format.Valid() {
+		return nil, errors.ErrApiInvalidParam.New(fmt.Sprintf("unsupported format: %s", format))
+	}
+	if filter == nil {
+		return nil, errors.ErrApiInvalidParam.New("filter is required")
+	}
+	if err := CheckExportFilter(filter); err != nil {
+		return nil, err
+	}
+
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	now := time.Now()
+	job := &model.ExportJob{
+		UserID:    userID,
+		Format:    string(format),
+		Filter:    filter,
+		Status:    enum.ExportStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := utils.NewTx(db).Exec(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		job.ID, err = exportjob.TxInsertJob(ctx, tx, job)
+		return err
+	}); err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to insert export job")
+	}
+
+	return job, nil
+}
+
+// GetExportJob retrieves an export job of the user, any job when the user id is 0.
+func GetExportJob(ctx context.Context, userID int64, id int64) (*model.ExportJob, error) {
+	if id <= 0 {
+		return nil, errors.ErrApiInvalidParam.New("invalid export id")
+	}
+
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	jobs, err := exportjob.GetJobs(ctx, db, &exportjob.GetJobFilter{
+		IDs:    []int64{id},
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get export job")
+	}
+
+	if len(jobs) == 0 {
+		return nil, errors.ErrExportNotFound.New()
+	}
+
+	return jobs[0], nil
+}
+
+// ClaimExportJob marks the next pending job as running and returns it, nil when there is none.
+// A running job not finished within the export timeout is claimed again.
+func ClaimExportJob(ctx context.Context) (*model.ExportJob, error) {
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	now := time.Now()
+	var job *model.ExportJob
+	if err := utils.NewTx(db).Exec(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		job, err = exportjob.TxClaimJob(ctx, tx, now, now.Add(-config.Get().Export.Timeout))
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to claim export job: %w", err)
+	}
+
+	return job, nil
+}
+
+// SaveExportJobResult saves the result of a running job.
+func SaveExportJobResult(ctx context.Context, job *model.ExportJob) error {
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	return exportjob.UpdateJobResult(ctx, db, job)
+}
+
+// GetExpiredExportJobs returns the finished jobs older than the retention.
+func GetExpiredExportJobs(ctx context.Context, limit uint64) ([]*model.ExportJob, error) {
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	return exportjob.GetJobs(ctx, db, &exportjob.GetJobFilter{
+		Statuses:      []enum.ExportStatus{enum.ExportStatusSucceeded, enum.ExportStatusFailed},
+		UpdatedBefore: time.Now().Add(-config.Get().Export.Retention),
+		Limit:         limit,
+	})
+}
+
+// DeleteExportJobs deletes export jobs, their files are removed by the caller.
+func DeleteExportJobs(ctx context.Context, ids []int64) error {
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	return exportjob.DeleteJobs(ctx, db, ids)
+}
+
+// ExportFilePath returns the path of the file of a succeeded job.
+func ExportFilePath(job *model.ExportJob) string {
+	return filepath.Join(config.Get().Export.Dir, job.FileName)
+}
+
+// ExportExpiresAt returns the time the file of a finished job is deleted, download links expire with the file.
+func ExportExpiresAt(job *model.ExportJob) time.Time {
+	return job.UpdatedAt.Add(config.Get().Export.Retention)
+}
+
+// SignExportDownload returns the signature of a download link of an export job valid until expires.
+func SignExportDownload(id int64, expires int64) string {
+	return hashing.HmacSha256([]byte(config.Get().Export.Secret), []byte(strconv.FormatInt(id, 10)+":"+strconv.FormatInt(expires, 10)))
+}
+
+// VerifyExportDownload checks the signature and the expiry of a download link.
+func VerifyExportDownload(id int64, expires int64, signature string) error {
+	expected := SignExportDownload(id, expires)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) != 1 {
+		return errors.ErrPermissionDenied.New("invalid download signature")
+	}
+
+	if time.Now().Unix() > expires {
+		return errors.ErrPermissionDenied.New("download link expired")
+	}
+
+	return nil
+}
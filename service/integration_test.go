@@ -0,0 +1,125 @@
+package service_test
+
+import (
+	"context"
+	"evm_event_indexer/internal/decoder"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/internal/testutil"
+	"evm_event_indexer/service"
+	"evm_event_indexer/service/model"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+var ctx = context.TODO()
+
+const chainID = int64(31337)
+
+var (
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")).Hex()
+	approvalTopic = crypto.Keccak256Hash([]byte("Approval(address,address,uint256)")).Hex()
+)
+
+func TestMain(m *testing.M) {
+	testutil.SetupTestConfig()
+	decoder.InitDecoder()
+	dbManager := storage.Forge()
+	if err := dbManager.Init(); err != nil {
+		panic(fmt.Sprintf("failed to init database: %s\n", err))
+	}
+
+	code := m.Run()
+	dbManager.Shutdown()
+	os.Exit(code)
+}
+
+// newContract returns an unused contract address, so the rows of a test are not shared with other runs,
+// the rows of the contract are rolled back when the test ends.
+func newContract(t *testing.T) string {
+	t.Helper()
+
+	address := common.HexToAddress(fmt.Sprintf("0x%040x", time.Now().UnixNano())).Hex()
+	t.Cleanup(func() {
+		_ = service.ReorgLog(ctx, &service.ReorgLogParam{
+			ChainID:   chainID,
+			Address:   address,
+			ReorgHash: common.Hash{}.Hex(),
+			Now:       time.Now(),
+		})
+	})
+
+	return address
+}
+
+// newHolder returns a lowercase address, the form arguments are stored in
+func newHolder(n int64) string {
+	return strings.ToLower(common.BigToAddress(big.NewInt(n + 0x1000)).Hex())
+}
+
+// newEventLog returns a decoded log of an event with two indexed addresses and a uint256 in the data,
+// decoded the same way as the scanner does.
+func newEventLog(t *testing.T, contract string, topic0 string, blockNumber uint64, logIndex int32, a string, b string, value int64) *model.Log {
+	t.Helper()
+
+	log := &model.Log{
+		ChainID:        chainID,
+		Address:        contract,
+		BlockHash:      common.BigToHash(new(big.Int).SetUint64(blockNumber)).Hex(),
+		BlockNumber:    blockNumber,
+		Topic0:         topic0,
+		Topic1:         common.BytesToHash(common.HexToAddress(a).Bytes()).Hex(),
+		Topic2:         common.BytesToHash(common.HexToAddress(b).Bytes()).Hex(),
+		TxIndex:        0,
+		LogIndex:       logIndex,
+		TxHash:         common.BigToHash(new(big.Int).SetUint64(blockNumber*1000 + uint64(logIndex))).Hex(),
+		Data:           common.LeftPadBytes(big.NewInt(value).Bytes(), 32),
+		BlockTimestamp: time.Unix(1700000000+int64(blockNumber)*12, 0),
+		Confirmed:      true,
+		CreatedAt:      time.Now(),
+	}
+
+	name, args, err := decoder.Provider.Decode(log)
+	require.NoError(t, err)
+	log.DecodedEvent = &model.DecodedEvent{EventName: name, EventData: args}
+
+	log.Args, err = decoder.Provider.Args(log)
+	require.NoError(t, err)
+
+	return log
+}
+
+// scan upserts the logs of a scanned range, as the scanner does
+func scan(t *testing.T, contract string, fromBN uint64, lastBN uint64, logs ...*model.Log) {
+	t.Helper()
+
+	require.NoError(t, service.UpsertLog(ctx, &service.UpsertLogParam{
+		ChainID:        chainID,
+		Address:        contract,
+		FromBlock:      fromBN,
+		LastSyncNumber: lastBN,
+		LastSyncHash:   common.BigToHash(new(big.Int).SetUint64(lastBN)).Hex(),
+		Now:            time.Now(),
+		Logs:           logs,
+	}))
+}
+
+// reorg rolls the contract back to the checkpoint, as the reorg consumer does
+func reorg(t *testing.T, contract string, checkpoint uint64) {
+	t.Helper()
+
+	require.NoError(t, service.ReorgLog(ctx, &service.ReorgLogParam{
+		ChainID:    chainID,
+		Address:    contract,
+		Checkpoint: checkpoint,
+		ReorgHash:  common.BigToHash(new(big.Int).SetUint64(checkpoint)).Hex(),
+		Now:        time.Now(),
+	}))
+}
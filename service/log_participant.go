@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/decoder"
+	"evm_event_indexer/internal/decoder/provider"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/eventlog"
+)
+
+// logParticipants collects the addresses involved in the logs, the decoded address arguments of the decoded logs
+// and the address topics of the others. The zero address of mints and burns is skipped.
+func logParticipants(logs []*model.Log) []*model.LogParticipant {
+	participants := make([]*model.LogParticipant, 0)
+	for _, log := range logs {
+		addresses := make([]string, 0, 3)
+		if log.DecodedEvent != nil {
+			// arguments are typed by the event of the log, the same name may have another type in other events
+			types := make(map[string]provider.FieldType)
+			for _, field := range decoder.Provider.EventFields(log.Topic0) {
+				types[field.Name] = field.Type
+			}
+
+			for _, arg := range log.Args {
+				if types[arg.Name] == provider.FieldAddress {
+					addresses = append(addresses, arg.Value)
+				}
+			}
+		} else {
+			for _, topic := range []string{log.Topic1, log.Topic2, log.Topic3} {
+				if address, ok := model.TopicAddress(topic); ok {
+					addresses = append(addresses, address)
+				}
+			}
+		}
+
+		seen := make(map[string]bool, len(addresses))
+		for _, address := range addresses {
+			if address == "" || seen[address] || address == zeroAddress {
+				continue
+			}
+			seen[address] = true
+
+			participants = append(participants, &model.LogParticipant{
+				ChainID:     log.ChainID,
+				Address:     log.Address,
+				BlockNumber: log.BlockNumber,
+				TxIndex:     log.TxIndex,
+				LogIndex:    log.LogIndex,
+				Participant: address,
+			})
+		}
+	}
+
+	return participants
+}
+
+// GetAddressLogs returns the logs involving an address across the indexed contracts, latest first.
+func GetAddressLogs(ctx context.Context, filter *eventlog.GetParticipantParam) ([]*model.Log, error) {
+	if filter.ChainID == 0 {
+		return nil, errors.ErrApiInvalidParam.New("chain_id is required")
+	}
+	if filter.Participant == "" {
+		return nil, errors.ErrApiInvalidParam.New("address is required")
+	}
+	if filter.BlockNumberLTE > 0 && filter.BlockNumberLTE < filter.BlockNumberGTE {
+		return nil, errors.ErrApiInvalidParam.New("end block number should not be less than start block number")
+	}
+
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	logs, err := eventlog.GetParticipantLogs(ctx, db, filter)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get address logs")
+	}
+
+	return logs, nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/blocksync"
+	"evm_event_indexer/service/repo/eventlog"
+	"strings"
+)
+
+// LogSync is the sync status of the contract of an indexed log
+type LogSync struct {
+	Status         model.LogStatus
+	LastSyncNumber uint64 // last block scanned for the contract, 0 if not synced yet
+}
+
+// GetTxLogs returns the indexed logs of a transaction ordered by log index, ErrTxNotFound if there is none.
+func GetTxLogs(ctx context.Context, chainID int64, txHash string) ([]*model.Log, error) {
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	logs, err := eventlog.GetTxLogs(ctx, db, chainID, txHash)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get tx logs")
+	}
+
+	if len(logs) == 0 {
+		return nil, errors.ErrTxNotFound.New()
+	}
+
+	return logs, nil
+}
+
+// GetTxsLogs returns the indexed logs of the transactions, at most limit logs of each transaction.
+func GetTxsLogs(ctx context.Context, chainID int64, txHashes []string, limit uint64) ([]*model.Log, error) {
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	logs, err := eventlog.GetTxsLogs(ctx, db, chainID, txHashes, limit)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get tx logs")
+	}
+
+	return logs, nil
+}
+
+// GetLog returns an indexed log by its position in a transaction, ErrLogNotFound if it is not indexed.
+func GetLog(ctx context.Context, chainID int64, txHash string, logIndex int32) (*model.Log, error) {
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	logs, err := eventlog.GetTxLogs(ctx, db, chainID, txHash)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get tx logs")
+	}
+
+	for _, log := range logs {
+		if log.LogIndex == logIndex {
+			return log, nil
+		}
+	}
+
+	return nil, errors.ErrLogNotFound.New()
+}
+
+// GetLogSyncs returns the sync status of the logs of a chain in the order of the logs.
+// A scanned log is finalized once its contract is synced past the reorg window of the chain.
+func GetLogSyncs(ctx context.Context, chainID int64, logs []*model.Log) ([]*LogSync, error) {
+	if len(logs) == 0 {
+		return []*LogSync{}, nil
+	}
+
+	reorgWindow := config.Get().ReorgWindow
+	if chain, ok := config.Get().GetScanner(chainID); ok {
+		reorgWindow = chain.GetReorgWindow()
+	}
+
+	addresses := make([]string, 0, len(logs))
+	seen := make(map[string]bool, len(logs))
+	for _, log := range logs {
+		if address := strings.ToLower(log.Address); !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	syncs, err := blocksync.GetBlockSyncMap(ctx, db, chainID, addresses)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get block sync")
+	}
+
+	// the configured address case may differ from the logs
+	byAddress := make(map[string]*model.BlockSync, len(syncs))
+	for address, sync := range syncs {
+		byAddress[strings.ToLower(address)] = sync
+	}
+
+	res := make([]*LogSync, len(logs))
+	for i, log := range logs {
+		sync := byAddress[strings.ToLower(log.Address)]
+
+		res[i] = &LogSync{
+			Status: sync.LogStatus(log, uint64(reorgWindow)),
+		}
+		if sync != nil {
+			res[i].LastSyncNumber = sync.LastSyncNumber
+		}
+	}
+
+	return res, nil
+}
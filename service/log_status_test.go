@@ -0,0 +1,40 @@
+package service_test
+
+import (
+	"evm_event_indexer/service"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetTxsLogs_PerTxLimit(t *testing.T) {
+	contract := newContract(t)
+	a, b := newHolder(1), newHolder(2)
+
+	// the tx hashes are derived from the contract, so they are not shared with the logs of other tests
+	busy := common.BytesToHash(append(common.HexToAddress(contract).Bytes(), 1)).Hex()
+	quiet := common.BytesToHash(append(common.HexToAddress(contract).Bytes(), 2)).Hex()
+
+	l1 := newEventLog(t, contract, transferTopic, 1, 0, a, b, 1)
+	l2 := newEventLog(t, contract, transferTopic, 1, 1, a, b, 2)
+	l3 := newEventLog(t, contract, transferTopic, 1, 2, a, b, 3)
+	l4 := newEventLog(t, contract, transferTopic, 2, 0, b, a, 4)
+	l1.TxHash, l2.TxHash, l3.TxHash = busy, busy, busy
+	l4.TxHash = quiet
+
+	scan(t, contract, 1, 2, l1, l2, l3, l4)
+
+	// the busy transaction is cut at the limit, the quiet one is still returned
+	res, err := service.GetTxsLogs(ctx, chainID, []string{busy, quiet}, 2)
+	require.NoError(t, err)
+
+	if assert.Len(t, res, 3) {
+		assert.Equal(t, busy, res[0].TxHash)
+		assert.Equal(t, int32(0), res[0].LogIndex)
+		assert.Equal(t, busy, res[1].TxHash)
+		assert.Equal(t, int32(1), res[1].LogIndex)
+		assert.Equal(t, quiet, res[2].TxHash)
+	}
+}
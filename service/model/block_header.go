@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+)
+
+const TableNameBlockHeader = "event_db.block_header"
+
+type (
+	BlockHeader struct {
+		ChainID        int64     // chain id
+		BlockHash      string    // block hash
+		BlockNumber    uint64    // block number
+		ParentHash     string    // parent block hash
+		BlockTimestamp time.Time // block timestamp
+		L1BlockNumber  uint64    // L1 origin block number
+		L1BlockHash    string    // L1 origin block hash, empty on Arbitrum
+		CreatedAt      time.Time // created at
+	}
+)
@@ -15,3 +15,17 @@ type (
 		UpdatedAt      time.Time // updated at
 	}
 )
+
+// LogStatus returns the finality of a log of the synced contract, a scanned log is finalized
+// once the last synced block is at least reorgWindow blocks after it, nil means not synced yet
+func (b *BlockSync) LogStatus(log *Log, reorgWindow uint64) LogStatus {
+	if !log.Confirmed {
+		return LogStatusPending
+	}
+
+	if b == nil || b.LastSyncNumber < log.BlockNumber+reorgWindow {
+		return LogStatusConfirmed
+	}
+
+	return LogStatusFinalized
+}
@@ -0,0 +1,26 @@
+package model_test
+
+import (
+	"evm_event_indexer/service/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_BlockSyncLogStatus(t *testing.T) {
+	sync := &model.BlockSync{LastSyncNumber: 112}
+
+	// live logs are pending until the scanner confirms them
+	assert.Equal(t, model.LogStatusPending, sync.LogStatus(&model.Log{BlockNumber: 100}, 12))
+
+	assert.Equal(t, model.LogStatusFinalized, sync.LogStatus(&model.Log{BlockNumber: 100, Confirmed: true}, 12))
+	assert.Equal(t, model.LogStatusConfirmed, sync.LogStatus(&model.Log{BlockNumber: 101, Confirmed: true}, 12))
+	assert.Equal(t, model.LogStatusFinalized, sync.LogStatus(&model.Log{BlockNumber: 112, Confirmed: true}, 0))
+
+	// not synced yet
+	var missing *model.BlockSync
+	assert.Equal(t, model.LogStatusConfirmed, missing.LogStatus(&model.Log{BlockNumber: 1, Confirmed: true}, 0))
+
+	assert.Less(t, model.LogStatusPending.Rank(), model.LogStatusConfirmed.Rank())
+	assert.Less(t, model.LogStatusConfirmed.Rank(), model.LogStatusFinalized.Rank())
+}
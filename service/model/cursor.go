@@ -0,0 +1,55 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+)
+
+// encoded size of a log cursor, block number (8 bytes), tx index (4 bytes), log index (4 bytes)
+const logCursorSize = 16
+
+type (
+	// LogCursor is the position of an event log in (block_number, tx_index, log_index) order,
+	// used for keyset pagination.
+	LogCursor struct {
+		BlockNumber uint64
+		TxIndex     int32
+		LogIndex    int32
+	}
+)
+
+func NewLogCursor(log *Log) *LogCursor {
+	return &LogCursor{
+		BlockNumber: log.BlockNumber,
+		TxIndex:     log.TxIndex,
+		LogIndex:    log.LogIndex,
+	}
+}
+
+// Encode returns the opaque url safe form of the cursor
+func (c LogCursor) Encode() string {
+	b := make([]byte, logCursorSize)
+	binary.BigEndian.PutUint64(b[0:8], c.BlockNumber)
+	binary.BigEndian.PutUint32(b[8:12], uint32(c.TxIndex))
+	binary.BigEndian.PutUint32(b[12:16], uint32(c.LogIndex))
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeLogCursor parses a cursor returned by Encode
+func DecodeLogCursor(s string) (*LogCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	if len(b) != logCursorSize {
+		return nil, fmt.Errorf("invalid cursor length: %d", len(b))
+	}
+
+	return &LogCursor{
+		BlockNumber: binary.BigEndian.Uint64(b[0:8]),
+		TxIndex:     int32(binary.BigEndian.Uint32(b[8:12])),
+		LogIndex:    int32(binary.BigEndian.Uint32(b[12:16])),
+	}, nil
+}
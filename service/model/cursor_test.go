@@ -0,0 +1,22 @@
+package model_test
+
+import (
+	"evm_event_indexer/service/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LogCursor(t *testing.T) {
+	cursor := model.NewLogCursor(&model.Log{BlockNumber: 19_000_000, TxIndex: 12, LogIndex: 345})
+
+	got, err := model.DecodeLogCursor(cursor.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, got)
+
+	_, err = model.DecodeLogCursor("not a cursor")
+	assert.Error(t, err)
+
+	_, err = model.DecodeLogCursor("AAAA")
+	assert.Error(t, err)
+}
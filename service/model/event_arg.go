@@ -0,0 +1,28 @@
+package model
+
+const TableNameEventArg = "event_db.event_arg"
+
+// operators of decoded argument filters
+const (
+	ArgOpEq     ArgOp = "eq"
+	ArgOpGt     ArgOp = "gt"
+	ArgOpGte    ArgOp = "gte"
+	ArgOpLt     ArgOp = "lt"
+	ArgOpLte    ArgOp = "lte"
+	ArgOpPrefix ArgOp = "prefix"
+)
+
+type (
+	ArgOp string
+
+	// EventArg is a queryable decoded argument of an event log, keyed by the natural key of the log
+	EventArg struct {
+		ChainID     int64
+		Address     string
+		BlockNumber uint64
+		TxIndex     int32
+		LogIndex    int32
+		Name        string // argument name
+		Value       string // normalized value, see provider.FieldType
+	}
+)
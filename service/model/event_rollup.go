@@ -0,0 +1,66 @@
+package model
+
+import (
+	"math/big"
+	"time"
+)
+
+const (
+	TableNameEventRollup       = "event_db.event_rollup"
+	TableNameEventRollupSender = "event_db.event_rollup_sender"
+)
+
+// rollup bucket sizes, buckets start at the UTC hour or day
+const (
+	RollupBucketHour RollupBucket = "hour"
+	RollupBucketDay  RollupBucket = "day"
+)
+
+// RollupBuckets are the bucket sizes every log is aggregated into
+var RollupBuckets = []RollupBucket{RollupBucketHour, RollupBucketDay}
+
+type (
+	RollupBucket string
+
+	// EventRollup aggregates the confirmed logs of an event emitted by a contract within a time bucket
+	EventRollup struct {
+		ChainID     int64
+		Address     string
+		Topic0      string
+		Bucket      RollupBucket
+		BucketStart time.Time
+		LogCount    int64
+		Volume      *big.Int // sum of the first uint256 decoded field, zero for events without one
+		Senders     int64    // unique values of the first address decoded field, e.g. from of Transfer
+		UpdatedAt   time.Time
+	}
+
+	// EventRollupSender counts the logs of a sender within a bucket, used to maintain the unique senders
+	EventRollupSender struct {
+		ChainID     int64
+		Address     string
+		Topic0      string
+		Bucket      RollupBucket
+		BucketStart time.Time
+		Sender      string
+		LogCount    int64
+	}
+)
+
+// Valid reports whether the bucket size is supported
+func (b RollupBucket) Valid() bool {
+	return b == RollupBucketHour || b == RollupBucketDay
+}
+
+// Duration returns the length of a bucket
+func (b RollupBucket) Duration() time.Duration {
+	if b == RollupBucketDay {
+		return 24 * time.Hour
+	}
+	return time.Hour
+}
+
+// Start returns the start of the bucket containing the time
+func (b RollupBucket) Start(t time.Time) time.Time {
+	return t.UTC().Truncate(b.Duration())
+}
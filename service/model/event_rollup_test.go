@@ -0,0 +1,19 @@
+package model_test
+
+import (
+	"evm_event_indexer/service/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RollupBucket(t *testing.T) {
+	ts := time.Date(2024, 3, 10, 23, 45, 12, 0, time.FixedZone("UTC+8", 8*3600))
+
+	assert.Equal(t, time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC), model.RollupBucketHour.Start(ts))
+	assert.Equal(t, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), model.RollupBucketDay.Start(ts))
+
+	assert.True(t, model.RollupBucketHour.Valid())
+	assert.False(t, model.RollupBucket("week").Valid())
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"evm_event_indexer/internal/enum"
+	"fmt"
+	"time"
+)
+
+const TableNameExportJob = "event_db.export_job"
+
+type (
+	// ExportJob writes the logs matching a filter to a file in the export directory
+	ExportJob struct {
+		ID        int64
+		UserID    int64
+		Format    string // csv, ndjson or parquet
+		Filter    *ExportFilter
+		Status    enum.ExportStatus
+		FileName  string // name of the file in the export directory, set when succeeded
+		Rows      int64  // number of exported logs
+		Error     string // error of a failed job
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+
+	// ExportFilter selects the exported logs, the filters of a webhook with the range of the logs query
+	ExportFilter struct {
+		WebhookFilter
+		TxHashes       []string  `json:"tx_hashes,omitempty"`
+		BlockNumberGTE uint64    `json:"bn_start,omitempty"`
+		BlockNumberLTE uint64    `json:"bn_end,omitempty"`
+		StartTime      time.Time `json:"start_time,omitzero"`
+		EndTime        time.Time `json:"end_time,omitzero"`
+	}
+)
+
+// Scan : implement sql.Scanner interface
+func (t *ExportFilter) Scan(val any) error {
+	switch v := val.(type) {
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	default:
+		return fmt.Errorf("unsupported type: %T", v)
+	}
+}
+
+// Value : implement driver.Valuer interface
+func (t *ExportFilter) Value() (driver.Value, error) {
+	if t == nil {
+		return json.Marshal(&ExportFilter{})
+	}
+	return json.Marshal(t)
+}
@@ -26,15 +26,40 @@ type (
 		Data           []byte
 		DecodedEvent   *DecodedEvent
 		BlockTimestamp time.Time
+		Confirmed      bool   // false when pushed by the live subscription and not yet reconciled by the scanner
+		L1BlockNumber  uint64 // L1 origin block number, 0 on L1 chains
+		L1BlockHash    string // L1 origin block hash, empty on L1 chains and Arbitrum
 		CreatedAt      time.Time
+		Args           []*EventArg // queryable decoded arguments, stored in event_arg
 	}
 
+	// LogStatus is the finality of an indexed log
+	LogStatus string
+
 	DecodedEvent struct {
 		EventName string            `json:"event_name"`
 		EventData map[string]string `json:"event_data"`
 	}
 )
 
+const (
+	LogStatusPending   LogStatus = "pending"   // pushed by the live subscription, not reconciled by the scanner yet
+	LogStatusConfirmed LogStatus = "confirmed" // scanned, still within the reorg window re-read by every sync
+	LogStatusFinalized LogStatus = "finalized" // behind the reorg window, no longer rewritten by the scanner
+)
+
+// Rank orders the statuses from the least to the most final
+func (s LogStatus) Rank() int {
+	switch s {
+	case LogStatusConfirmed:
+		return 1
+	case LogStatusFinalized:
+		return 2
+	default:
+		return 0
+	}
+}
+
 // Scan : implement sql.Scanner interface
 func (t *DecodedEvent) Scan(val any) error {
 	switch v := val.(type) {
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+const TableNameLogParticipant = "event_db.log_participant"
+
+// zero bytes padding an address in a 32-byte topic
+const topicAddressPadding = "000000000000000000000000"
+
+type (
+	// LogParticipant is an address involved in an event log, keyed by the natural key of the log
+	LogParticipant struct {
+		ChainID     int64
+		Address     string // contract address of the log
+		BlockNumber uint64
+		TxIndex     int32
+		LogIndex    int32
+		Participant string // lowercase 20-byte hex
+	}
+)
+
+// TopicAddress returns the address held by an indexed topic of an undecoded log.
+// The event types are unknown, so a topic is taken for an address when it is zero padded to 32 bytes
+// and at least 2^96, smaller values are far more likely numbers such as token ids than addresses.
+func TopicAddress(topic string) (string, bool) {
+	topic = strings.ToLower(topic)
+	if len(topic) != 66 || !strings.HasPrefix(topic, "0x") {
+		return "", false
+	}
+
+	if !strings.HasPrefix(topic[2:], topicAddressPadding) {
+		return "", false
+	}
+
+	address := topic[26:]
+	if _, err := hex.DecodeString(address); err != nil {
+		return "", false
+	}
+
+	// the top 8 of the 20 address bytes, zero below 2^96
+	if strings.Trim(address[:16], "0") == "" {
+		return "", false
+	}
+
+	return "0x" + address, true
+}
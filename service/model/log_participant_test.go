@@ -0,0 +1,31 @@
+package model_test
+
+import (
+	"evm_event_indexer/service/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TopicAddress(t *testing.T) {
+	address, ok := model.TopicAddress("0x000000000000000000000000F39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	assert.True(t, ok)
+	assert.Equal(t, "0xf39fd6e51aad88f6f4ce6ab8827279cfffb92266", address)
+
+	// vanity addresses with leading zero bytes
+	address, ok = model.TopicAddress("0x00000000000000000000000000000000219ab540356cbb839cbe05303d7705fa")
+	assert.True(t, ok)
+	assert.Equal(t, "0x00000000219ab540356cbb839cbe05303d7705fa", address)
+
+	// small numbers, e.g. token ids
+	_, ok = model.TopicAddress("0x0000000000000000000000000000000000000000000000000000000000000005")
+	assert.False(t, ok)
+	_, ok = model.TopicAddress("0x0000000000000000000000000000000000000000000000000000000000000000")
+	assert.False(t, ok)
+
+	// hashes
+	_, ok = model.TopicAddress("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	assert.False(t, ok)
+	_, ok = model.TopicAddress("")
+	assert.False(t, ok)
+}
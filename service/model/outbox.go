@@ -0,0 +1,26 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const TableNameEventOutbox = "event_db.event_outbox"
+
+type (
+	// Outbox is a log insert or deletion written in the same transaction as the logs, delivered to the consumers after commit
+	Outbox struct {
+		ID          int64
+		Type        string // log, tombstone
+		ChainID     int64
+		Address     string
+		BlockNumber uint64
+		TxIndex     int32
+		LogIndex    int32
+		Key         string
+		Payload     json.RawMessage // nil for tombstones
+		ClaimedAt   *time.Time
+		DeliveredAt *time.Time
+		CreatedAt   time.Time
+	}
+)
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"math/big"
+	"time"
+)
+
+const TableNameTokenAllowance = "event_db.token_allowance"
+
+// unlimitedAllowance is the smallest allowance treated as unlimited, the max uint96 used by tokens with 96-bit allowances,
+// which covers the max uint256 approved by most wallets and dapps
+var unlimitedAllowance = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 96), big.NewInt(1))
+
+type (
+	// TokenAllowance is the latest approval of a spender by an owner, derived from the Approval events of a token
+	TokenAllowance struct {
+		ChainID     int64
+		Token       string
+		Owner       string
+		Spender     string
+		Allowance   *big.Int
+		BlockNumber uint64 // block of the latest Approval
+		TxHash      string // tx of the latest Approval
+		UpdatedAt   time.Time
+	}
+)
+
+// Unlimited reports whether the allowance is treated as unlimited
+func (a *TokenAllowance) Unlimited() bool {
+	return IsUnlimitedAllowance(a.Allowance)
+}
+
+// IsUnlimitedAllowance reports whether an approved value is treated as unlimited
+func IsUnlimitedAllowance(value *big.Int) bool {
+	return value.Cmp(unlimitedAllowance) >= 0
+}
@@ -0,0 +1,19 @@
+package model_test
+
+import (
+	"evm_event_indexer/service/model"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IsUnlimitedAllowance(t *testing.T) {
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	maxUint96 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 96), big.NewInt(1))
+
+	assert.True(t, model.IsUnlimitedAllowance(maxUint256))
+	assert.True(t, model.IsUnlimitedAllowance(maxUint96))
+	assert.False(t, model.IsUnlimitedAllowance(new(big.Int).Sub(maxUint96, big.NewInt(1))))
+	assert.False(t, model.IsUnlimitedAllowance(big.NewInt(0)))
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	TableNameTokenBalance        = "event_db.token_balance"
+	TableNameTokenBalanceHistory = "event_db.token_balance_history"
+)
+
+// Uint256Digits is the number of decimal digits of the max uint256,
+// stored uint256 values and balances are zero padded to it so they compare as strings
+const Uint256Digits = 78
+
+type (
+	// TokenBalance is the current balance of a holder derived from the Transfer events of a token
+	TokenBalance struct {
+		ChainID     int64
+		Token       string
+		Holder      string
+		Balance     *big.Int
+		BlockNumber uint64 // block of the last balance change
+		UpdatedAt   time.Time
+	}
+
+	// TokenBalanceHistory is a balance change of a holder by a Transfer log
+	TokenBalanceHistory struct {
+		ChainID     int64
+		Token       string
+		Holder      string
+		BlockNumber uint64
+		TxIndex     int32
+		LogIndex    int32
+		Delta       *big.Int // signed change
+		Balance     *big.Int // balance after the change
+		CreatedAt   time.Time
+	}
+)
+
+// EncodeBalance converts a balance into its stored form, zero padded to 78 digits so non-negative balances compare as strings.
+// A negative balance, only possible when a token is not indexed from its deployment, is prefixed with "-" and sorts first.
+func EncodeBalance(n *big.Int) string {
+	if n.Sign() < 0 {
+		return fmt.Sprintf("-%0*s", Uint256Digits, new(big.Int).Neg(n).String())
+	}
+	return fmt.Sprintf("%0*s", Uint256Digits, n.String())
+}
+
+// DecodeBalance parses a stored balance
+func DecodeBalance(s string) (*big.Int, error) {
+	neg := strings.HasPrefix(s, "-")
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(s, "-"), 10)
+	if !ok || n.Sign() < 0 {
+		return nil, fmt.Errorf("invalid balance: %s", s)
+	}
+
+	if neg {
+		n.Neg(n)
+	}
+	return n, nil
+}
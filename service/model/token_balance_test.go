@@ -0,0 +1,47 @@
+package model_test
+
+import (
+	"evm_event_indexer/service/model"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Balance(t *testing.T) {
+	values := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(5),
+		big.NewInt(-3),
+		new(big.Int).Exp(big.NewInt(10), big.NewInt(30), nil),
+		big.NewInt(40),
+	}
+
+	encoded := make([]string, 0, len(values))
+	for _, v := range values {
+		s := model.EncodeBalance(v)
+		got, err := model.DecodeBalance(s)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, v.Cmp(got))
+		encoded = append(encoded, s)
+	}
+
+	assert.Len(t, encoded[0], 78)
+	assert.Len(t, encoded[2], 79)
+
+	// non-negative balances sort numerically as strings, negative ones first
+	sort.Strings(encoded)
+	assert.Equal(t, []string{
+		model.EncodeBalance(big.NewInt(-3)),
+		model.EncodeBalance(big.NewInt(0)),
+		model.EncodeBalance(big.NewInt(5)),
+		model.EncodeBalance(big.NewInt(40)),
+		model.EncodeBalance(new(big.Int).Exp(big.NewInt(10), big.NewInt(30), nil)),
+	}, encoded)
+
+	_, err := model.DecodeBalance("abc")
+	assert.Error(t, err)
+	_, err = model.DecodeBalance("--1")
+	assert.Error(t, err)
+}
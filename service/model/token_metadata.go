@@ -0,0 +1,66 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const TableNameTokenMetadata = "event_db.token_metadata"
+
+type (
+	// TokenMetadata is the cached metadata of a token, the total supply is refreshed periodically
+	TokenMetadata struct {
+		ChainID     int64
+		Token       string
+		Name        string // empty if the token does not implement name()
+		Symbol      string // empty if the token does not implement symbol()
+		Decimals    uint8
+		TotalSupply *big.Int
+		CreatedAt   time.Time
+		UpdatedAt   time.Time // last refresh of the total supply
+	}
+)
+
+// NewTokenKey returns the key of a token in a metadata map, addresses are compared case-insensitively
+func NewTokenKey(chainID int64, token string) string {
+	return fmt.Sprintf("%d:%s", chainID, strings.ToLower(token))
+}
+
+// FormatAmount formats an amount in the smallest unit with the decimals of the token, trailing zeros are trimmed,
+// e.g. 1500000000000000000 with 18 decimals is 1.5
+func (m *TokenMetadata) FormatAmount(amount string) (string, error) {
+	return FormatAmount(amount, m.Decimals)
+}
+
+// FormatAmount formats a decimal amount in the smallest unit with the decimals
+func FormatAmount(amount string, decimals uint8) (string, error) {
+	n, ok := new(big.Int).SetString(amount, 10)
+	if !ok {
+		return "", fmt.Errorf("invalid amount: %s", amount)
+	}
+
+	sign := ""
+	if n.Sign() < 0 {
+		sign = "-"
+		n.Neg(n)
+	}
+
+	digits := n.String()
+	if decimals == 0 {
+		return sign + digits, nil
+	}
+
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+
+	whole := digits[:len(digits)-int(decimals)]
+	fraction := strings.TrimRight(digits[len(digits)-int(decimals):], "0")
+	if fraction == "" {
+		return sign + whole, nil
+	}
+
+	return sign + whole + "." + fraction, nil
+}
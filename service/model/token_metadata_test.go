@@ -0,0 +1,33 @@
+package model_test
+
+import (
+	"evm_event_indexer/service/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FormatAmount(t *testing.T) {
+	cases := []struct {
+		amount   string
+		decimals uint8
+		want     string
+	}{
+		{"1500000000000000000", 18, "1.5"},
+		{"1000000000000000000", 18, "1"},
+		{"1", 18, "0.000000000000000001"},
+		{"0", 18, "0"},
+		{"123456789", 6, "123.456789"},
+		{"-2500000", 6, "-2.5"},
+		{"42", 0, "42"},
+	}
+
+	for _, c := range cases {
+		got, err := model.FormatAmount(c.amount, c.decimals)
+		assert.NoError(t, err)
+		assert.Equal(t, c.want, got, c.amount)
+	}
+
+	_, err := model.FormatAmount("0x10", 18)
+	assert.Error(t, err)
+}
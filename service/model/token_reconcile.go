@@ -0,0 +1,27 @@
+package model
+
+import (
+	"math/big"
+	"time"
+)
+
+const TableNameTokenReconcile = "event_db.token_reconcile"
+
+type (
+	// TokenReconcile is the last comparison of a holder balance in the ledger with balanceOf on chain
+	TokenReconcile struct {
+		ChainID       int64
+		Token         string
+		Holder        string
+		BlockNumber   uint64   // synced block both balances are read at
+		LedgerBalance *big.Int // balance derived from the Transfer logs
+		ChainBalance  *big.Int // balanceOf at the block
+		Drift         *big.Int // chain balance minus ledger balance
+		CheckedAt     time.Time
+	}
+)
+
+// HasDrift reports whether the ledger does not match the chain
+func (r *TokenReconcile) HasDrift() bool {
+	return r.Drift.Sign() != 0
+}
@@ -0,0 +1,113 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"evm_event_indexer/internal/enum"
+	"fmt"
+	"time"
+)
+
+const (
+	TableNameWebhook         = "event_db.webhook"
+	TableNameWebhookDelivery = "event_db.webhook_delivery"
+)
+
+// webhook event types
+const (
+	WebhookEventLog     = "log"     // a confirmed log matched the filter
+	WebhookEventRemoved = "removed" // the logs of an address after a block were rolled back by a reorg
+)
+
+type (
+	Webhook struct {
+		ID        int64
+		UserID    int64
+		URL       string
+		Secret    string // HMAC key of the payload signature
+		Filter    *WebhookFilter
+		Status    enum.WebhookStatus
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+
+	// WebhookFilter selects the logs delivered to a webhook, with the normalized values of the logs query filters
+	WebhookFilter struct {
+		ChainID   int64        `json:"chain_id,omitempty"`
+		Addresses []string     `json:"addresses,omitempty"`
+		Topic0s   []string     `json:"topic0s,omitempty"`
+		Topic1s   []string     `json:"topic1s,omitempty"`
+		Topic2s   []string     `json:"topic2s,omitempty"`
+		Topic3s   []string     `json:"topic3s,omitempty"`
+		Args      []WebhookArg `json:"args,omitempty"`
+	}
+
+	// WebhookArg is a decoded argument condition, the value is normalized by the field type
+	WebhookArg struct {
+		Name  string `json:"name"`
+		Op    ArgOp  `json:"op"`
+		Value string `json:"value"`
+	}
+
+	// WebhookDelivery is a queued payload of a webhook, retried with backoff until delivered or exhausted
+	WebhookDelivery struct {
+		ID             int64
+		WebhookID      int64
+		Type           string // WebhookEventLog or WebhookEventRemoved
+		ChainID        int64
+		Address        string
+		BlockNumber    uint64
+		TxIndex        int32
+		LogIndex       int32
+		Payload        json.RawMessage
+		Status         enum.DeliveryStatus
+		Attempts       int32
+		NextAttemptAt  time.Time
+		LastStatusCode int32 // http status of the last attempt, 0 when no response was received
+		LastError      string
+		CreatedAt      time.Time
+		UpdatedAt      time.Time
+	}
+
+	// WebhookPayload is the signed json body posted to a webhook
+	WebhookPayload struct {
+		Type        string      `json:"type"`
+		ChainID     int64       `json:"chain_id"`
+		Address     string      `json:"address"`
+		BlockNumber uint64      `json:"block_number"` // removed: logs after this block were rolled back
+		Log         *WebhookLog `json:"log,omitempty"`
+	}
+
+	WebhookLog struct {
+		BlockNumber    uint64        `json:"block_number"`
+		BlockHash      string        `json:"block_hash"`
+		TxHash         string        `json:"tx_hash"`
+		Address        string        `json:"address"`
+		Topics         []string      `json:"topics"`
+		Data           string        `json:"data"`
+		TxIndex        int32         `json:"tx_index"`
+		LogIndex       int32         `json:"log_index"`
+		DecodedEvent   *DecodedEvent `json:"decoded_event"`
+		BlockTimestamp time.Time     `json:"block_timestamp"`
+	}
+)
+
+// Scan : implement sql.Scanner interface
+func (t *WebhookFilter) Scan(val any) error {
+	switch v := val.(type) {
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	default:
+		return fmt.Errorf("unsupported type: %T", v)
+	}
+}
+
+// Value : implement driver.Valuer interface
+func (t *WebhookFilter) Value() (driver.Value, error) {
+	if t == nil {
+		return json.Marshal(&WebhookFilter{})
+	}
+	return json.Marshal(t)
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/outbox"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/service/model"
+	outboxRepo "evm_event_indexer/service/repo/outbox"
+	"evm_event_indexer/utils"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// newLogOutbox creates the outbox messages of the logs, the payload is the same log body as webhook deliveries
+func newLogOutbox(logs []*model.Log, now time.Time) ([]*model.Outbox, error) {
+	res := make([]*model.Outbox, 0, len(logs))
+	for _, log := range logs {
+		payload, err := json.Marshal(newWebhookLogPayload(log).Log)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &model.Outbox{
+			Type:        outbox.TypeLog,
+			ChainID:     log.ChainID,
+			Address:     log.Address,
+			BlockNumber: log.BlockNumber,
+			TxIndex:     log.TxIndex,
+			LogIndex:    log.LogIndex,
+			Key:         outboxRepo.NewKey(log.ChainID, log.Address, log.BlockNumber, log.TxIndex, log.LogIndex),
+			Payload:     payload,
+			CreatedAt:   now,
+		})
+	}
+
+	return res, nil
+}
+
+// RelayOutbox claims the oldest undelivered outbox messages, delivers them outside of any transaction and marks them as delivered.
+// When deliver fails the claim is released and the batch is delivered again later, a claim not marked delivered within the lease
+// (e.g. the relay crashed) is claimed again by any relay. Returns the number of delivered messages.
+func RelayOutbox(ctx context.Context, limit uint64, lease time.Duration, deliver func(ctx context.Context, msgs []*outbox.Message) error) (int, error) {
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	var records []*model.Outbox
+	if err := utils.NewTx(db).Exec(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		now := time.Now()
+		records, err = outboxRepo.TxClaimOutbox(ctx, tx, now, now.Add(-lease), limit)
+		return err
+	}); err != nil {
+		return 0, fmt.Errorf("failed to claim outbox: %w", err)
+	}
+
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	msgs := make([]*outbox.Message, 0, len(records))
+	ids := make([]int64, 0, len(records))
+	for _, v := range records {
+		msgs = append(msgs, &outbox.Message{
+			ID:          v.ID,
+			Type:        v.Type,
+			Key:         v.Key,
+			ChainID:     v.ChainID,
+			Address:     v.Address,
+			BlockNumber: v.BlockNumber,
+			Payload:     v.Payload,
+		})
+		ids = append(ids, v.ID)
+	}
+
+	if err := deliver(ctx, msgs); err != nil {
+		// the claim expires after the lease when it cannot be released
+		if releaseErr := outboxRepo.ReleaseClaim(ctx, db, ids...); releaseErr != nil {
+			slog.Error("failed to release outbox claim", slog.Any("error", releaseErr))
+		}
+		return 0, err
+	}
+
+	if err := outboxRepo.MarkDelivered(ctx, db, time.Now(), ids...); err != nil {
+		return 0, fmt.Errorf("failed to mark outbox delivered: %w", err)
+	}
+
+	return len(msgs), nil
+}
+
+// CleanOutbox deletes up to limit messages delivered before the given time
+func CleanOutbox(ctx context.Context, before time.Time, limit uint64) (int64, error) {
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	return outboxRepo.DeleteDelivered(ctx, db, before, limit)
+}
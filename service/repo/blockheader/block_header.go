@@ -0,0 +1,50 @@
+package blockheader
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/service/model"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Insert block headers into db, headers are keyed by hash so existing ones are left untouched
+func TxInsertBlockHeader(ctx context.Context, tx *sql.Tx, header ...*model.BlockHeader) error {
+	if len(header) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameBlockHeader).
+		Options("IGNORE").
+		Columns(
+			"chain_id",
+			"block_hash",
+			"block_number",
+			"parent_hash",
+			"block_timestamp",
+			"l1_block_number",
+			"l1_block_hash",
+			"created_at",
+		)
+
+	for _, v := range header {
+		qb = qb.Values(
+			v.ChainID,
+			v.BlockHash,
+			v.BlockNumber,
+			v.ParentHash,
+			v.BlockTimestamp,
+			v.L1BlockNumber,
+			v.L1BlockHash,
+			v.CreatedAt,
+		)
+	}
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
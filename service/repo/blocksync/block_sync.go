@@ -44,7 +44,30 @@ func TxUpsertBlock(ctx context.Context, tx *sql.Tx, blockSync *model.BlockSync)
 
 func GetBlockSync(ctx context.Context, db *sql.DB, chainID int64, address string) (res *model.BlockSync, err error) {
 
-	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+	rows, err := blockSyncQuery(chainID, address).RunWith(db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBlockSync(rows)
+}
+
+// TxGetBlockSyncForShare gets the block sync status and holds a shared lock on it until the tx ends,
+// so a scan or reorg of the address cannot move the synced block number meanwhile
+func TxGetBlockSyncForShare(ctx context.Context, tx *sql.Tx, chainID int64, address string) (*model.BlockSync, error) {
+
+	rows, err := blockSyncQuery(chainID, address).Suffix("FOR SHARE").RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBlockSync(rows)
+}
+
+func blockSyncQuery(chainID int64, address string) sq.SelectBuilder {
+	return sq.StatementBuilder.PlaceholderFormat(sq.Question).
 		Select(
 			"chain_id",
 			"address",
@@ -57,13 +80,9 @@ func GetBlockSync(ctx context.Context, db *sql.DB, chainID int64, address string
 			sq.Eq{"chain_id": chainID},
 			sq.Eq{"address": address},
 		)
+}
 
-	rows, err := qb.RunWith(db).QueryContext(ctx)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
+func scanBlockSync(rows *sql.Rows) (res *model.BlockSync, err error) {
 	for rows.Next() {
 		res = new(model.BlockSync)
 		if err := rows.Scan(
@@ -125,3 +144,44 @@ func GetBlockSyncMap(ctx context.Context, db *sql.DB, chainID int64, addresses [
 
 	return res, nil
 }
+
+// GetBlockSyncs lists the block sync status of every synced contract ordered by chain and address, chainID 0 means all chains
+func GetBlockSyncs(ctx context.Context, db *sql.DB, chainID int64) (res []*model.BlockSync, err error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(
+			"chain_id",
+			"address",
+			"last_sync_number",
+			"last_sync_hash",
+			"updated_at",
+		).
+		From(model.TableNameBlockSync).
+		OrderBy("chain_id ASC", "address ASC")
+
+	if chainID != 0 {
+		qb = qb.Where(sq.Eq{"chain_id": chainID})
+	}
+
+	rows, err := qb.RunWith(db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res = make([]*model.BlockSync, 0)
+	for rows.Next() {
+		bs := new(model.BlockSync)
+		if err := rows.Scan(
+			&bs.ChainID,
+			&bs.Address,
+			&bs.LastSyncNumber,
+			&bs.LastSyncHash,
+			&bs.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		res = append(res, bs)
+	}
+
+	return res, nil
+}
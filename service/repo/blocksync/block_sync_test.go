@@ -59,3 +59,88 @@ func Test_TxUpsertBlock(t *testing.T) {
 	assert.Equal(t, uint64(10), res.LastSyncNumber)
 	assert.Equal(t, common.Address{}.Hex(), res.LastSyncHash)
 }
+
+func Test_GetBlockSyncs(t *testing.T) {
+
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		t.Fatalf("failed to get mysql: %s\n", err)
+	}
+
+	addr := common.HexToAddress(fmt.Sprintf("0x%040x", time.Now().UnixNano())).Hex()
+	err = utils.NewTx(db).Exec(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return blocksync.TxUpsertBlock(ctx, tx, &model.BlockSync{
+			ChainID:        31337,
+			Address:        addr,
+			LastSyncNumber: 20,
+			LastSyncHash:   common.Address{}.Hex(),
+			UpdatedAt:      time.Now(),
+		})
+	})
+	assert.NoError(t, err)
+
+	res, err := blocksync.GetBlockSyncs(ctx, db, 31337)
+	assert.NoError(t, err)
+
+	var found *model.BlockSync
+	for _, bs := range res {
+		assert.Equal(t, int64(31337), bs.ChainID)
+		if bs.Address == addr {
+			found = bs
+		}
+	}
+	if assert.NotNil(t, found) {
+		assert.Equal(t, uint64(20), found.LastSyncNumber)
+	}
+}
+
+func Test_TxGetBlockSyncForShare(t *testing.T) {
+
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		t.Fatalf("failed to get mysql: %s\n", err)
+	}
+
+	addr := common.HexToAddress(fmt.Sprintf("0x%040x", time.Now().UnixNano())).Hex()
+	upsert := func(number uint64) error {
+		return utils.NewTx(db).Exec(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			return blocksync.TxUpsertBlock(ctx, tx, &model.BlockSync{
+				ChainID:        31337,
+				Address:        addr,
+				LastSyncNumber: number,
+				LastSyncHash:   common.Address{}.Hex(),
+				UpdatedAt:      time.Now(),
+			})
+		})
+	}
+	assert.NoError(t, upsert(30))
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to begin tx: %s\n", err)
+	}
+	defer tx.Rollback()
+
+	res, err := blocksync.TxGetBlockSyncForShare(ctx, tx, 31337, addr)
+	assert.NoError(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, uint64(30), res.LastSyncNumber)
+	}
+
+	// the upsert waits for the shared lock to be released
+	done := make(chan error, 1)
+	go func() { done <- upsert(40) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("upsert did not wait for the shared lock: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	assert.NoError(t, tx.Commit())
+	assert.NoError(t, <-done)
+
+	res, err = blocksync.GetBlockSync(ctx, db, 31337, addr)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(40), res.LastSyncNumber)
+}
@@ -0,0 +1,85 @@
+package eventlog
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/service/model"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// TxInsertLogArg inserts queryable decoded arguments, arguments that already exist are left untouched.
+// Arguments are deleted together with their event log by foreign key.
+func TxInsertLogArg(ctx context.Context, tx *sql.Tx, arg ...*model.EventArg) error {
+	if len(arg) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameEventArg).
+		Options("IGNORE").
+		Columns(
+			"chain_id",
+			"address",
+			"block_number",
+			"tx_index",
+			"log_index",
+			"name",
+			"value",
+		)
+
+	for _, v := range arg {
+		qb = qb.Values(
+			v.ChainID,
+			v.Address,
+			v.BlockNumber,
+			v.TxIndex,
+			v.LogIndex,
+			v.Name,
+			v.Value,
+		)
+	}
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ArgFilter filters event logs by a decoded argument, the value is normalized by the field type
+type ArgFilter struct {
+	Name  string
+	Op    model.ArgOp
+	Value string
+}
+
+func (f ArgFilter) ToSql() (string, []any, error) {
+	var cond string
+	value := f.Value
+
+	switch f.Op {
+	case model.ArgOpEq:
+		cond = "a.value = ?"
+	case model.ArgOpGt:
+		cond = "a.value > ?"
+	case model.ArgOpGte:
+		cond = "a.value >= ?"
+	case model.ArgOpLt:
+		cond = "a.value < ?"
+	case model.ArgOpLte:
+		cond = "a.value <= ?"
+	case model.ArgOpPrefix:
+		cond = "a.value LIKE ?"
+		value += "%"
+	default:
+		return "", nil, fmt.Errorf("unsupported arg operator: %s", f.Op)
+	}
+
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM %s a WHERE
+		a.chain_id = event_log.chain_id AND a.address = event_log.address AND a.block_number = event_log.block_number
+		AND a.tx_index = event_log.tx_index AND a.log_index = event_log.log_index
+		AND a.name = ? AND %s)`, model.TableNameEventArg, cond), []any{f.Name, value}, nil
+}
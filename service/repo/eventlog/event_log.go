@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"evm_event_indexer/service/model"
+	"fmt"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
@@ -14,6 +15,29 @@ func TxInsertLog(ctx context.Context, tx *sql.Tx, log ...*model.Log) error {
 		return nil
 	}
 
+	_, err := newInsertLog(log...).RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// TxInsertLogIgnore inserts event logs, logs that already exist are left untouched
+func TxInsertLogIgnore(ctx context.Context, tx *sql.Tx, log ...*model.Log) error {
+	if len(log) == 0 {
+		return nil
+	}
+
+	_, err := newInsertLog(log...).Options("IGNORE").RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func newInsertLog(log ...*model.Log) sq.InsertBuilder {
 	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
 		Insert(model.TableNameEventLog).
 		Columns(
@@ -31,6 +55,9 @@ func TxInsertLog(ctx context.Context, tx *sql.Tx, log ...*model.Log) error {
 			"data",
 			"decoded_event",
 			"block_timestamp",
+			"confirmed",
+			"l1_block_number",
+			"l1_block_hash",
 			"created_at",
 		)
 
@@ -50,33 +77,35 @@ func TxInsertLog(ctx context.Context, tx *sql.Tx, log ...*model.Log) error {
 			v.Data,
 			v.DecodedEvent,
 			v.BlockTimestamp,
+			v.Confirmed,
+			v.L1BlockNumber,
+			v.L1BlockHash,
 			v.CreatedAt,
 		)
 	}
 
-	_, err := qb.RunWith(tx).ExecContext(ctx)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return qb
 }
 
+// GetLogParam filters event logs, values within a slice are ORed, fields are ANDed
 type GetLogParam struct {
 	ChainID        int64
-	Address        string
+	Addresses      []string
 	OrderBy        int8 // 1:block_timestamp 2:block_number
 	StartTime      time.Time
 	EndTime        time.Time
 	BlockNumberLTE uint64
 	BlockNumberGTE uint64
-	TxHash         string
+	TxHashes       []string
 	BlockHash      string
-	Topic0         string
-	Topic1         string
-	Topic2         string
-	Topic3         string
+	Topic0s        []string
+	Topic1s        []string
+	Topic2s        []string
+	Topic3s        []string
 	Desc           bool
+	ConfirmedOnly  bool             // excludes the unconfirmed live logs
+	Cursor         *model.LogCursor // keyset pagination, returns logs after the cursor ordered by block number
+	Args           []ArgFilter      // decoded argument filters
 	Pagination     *model.Pagination
 }
 
@@ -86,28 +115,28 @@ func (p GetLogParam) ToWhere() sq.And {
 		conds = append(conds, sq.Eq{"chain_id": p.ChainID})
 	}
 
-	if p.Address != "" {
-		conds = append(conds, sq.Eq{"address": p.Address})
+	if len(p.Addresses) > 0 {
+		conds = append(conds, sq.Eq{"address": p.Addresses})
 	}
 
-	if p.TxHash != "" {
-		conds = append(conds, sq.Eq{"tx_hash": p.TxHash})
+	if len(p.TxHashes) > 0 {
+		conds = append(conds, sq.Eq{"tx_hash": p.TxHashes})
 	}
 
-	if p.Topic0 != "" {
-		conds = append(conds, sq.Eq{"topic_0": p.Topic0})
+	if len(p.Topic0s) > 0 {
+		conds = append(conds, sq.Eq{"topic_0": p.Topic0s})
 	}
 
-	if p.Topic1 != "" {
-		conds = append(conds, sq.Eq{"topic_1": p.Topic1})
+	if len(p.Topic1s) > 0 {
+		conds = append(conds, sq.Eq{"topic_1": p.Topic1s})
 	}
 
-	if p.Topic2 != "" {
-		conds = append(conds, sq.Eq{"topic_2": p.Topic2})
+	if len(p.Topic2s) > 0 {
+		conds = append(conds, sq.Eq{"topic_2": p.Topic2s})
 	}
 
-	if p.Topic3 != "" {
-		conds = append(conds, sq.Eq{"topic_3": p.Topic3})
+	if len(p.Topic3s) > 0 {
+		conds = append(conds, sq.Eq{"topic_3": p.Topic3s})
 	}
 
 	if !p.StartTime.IsZero() {
@@ -130,60 +159,200 @@ func (p GetLogParam) ToWhere() sq.And {
 		conds = append(conds, sq.Eq{"block_hash": p.BlockHash})
 	}
 
+	if p.ConfirmedOnly {
+		conds = append(conds, sq.Eq{"confirmed": true})
+	}
+
+	if p.Cursor != nil {
+		conds = append(conds, p.cursorWhere())
+	}
+
+	for _, arg := range p.Args {
+		conds = append(conds, arg)
+	}
+
 	return conds
 }
 
-func (p GetLogParam) ToOrderBy() string {
-	var orderby = ""
+// ToFrom returns the event log table with an index hint for the filter shape,
+// every index starts with chain_id, so no hint is given without it.
+func (p GetLogParam) ToFrom() string {
+	if p.ChainID == 0 {
+		return model.TableNameEventLog
+	}
 
-	switch p.OrderBy {
-	case 1:
-		orderby = "block_timestamp"
-	case 2:
-		orderby = "block_number"
+	hasTime := !p.StartTime.IsZero() || !p.EndTime.IsZero()
+	hasBlock := p.BlockNumberGTE > 0 || p.BlockNumberLTE > 0
+
+	var index string
+	switch {
+	case len(p.TxHashes) > 0:
+		index = "idx_chainId_txHash"
+	case len(p.Topic0s) > 0 && len(p.Topic1s) > 0 && hasTime:
+		index = "idx_chainId_t0_t1_bt"
+	case len(p.Topic0s) > 0 && len(p.Topic2s) > 0 && hasTime:
+		index = "idx_chainId_t0_t2_bt"
+	case len(p.Addresses) > 0 && hasBlock:
+		index = "idx_chainId_addr_bn"
+	case len(p.Addresses) > 0 && hasTime:
+		index = "idx_chainId_addr_bt"
+	case len(p.Topic0s) > 0 && hasTime:
+		index = "idx_chainId_t0_bt"
+	case hasBlock:
+		index = "idx_chainId_bn"
 	default:
-		orderby = "id"
+		return model.TableNameEventLog
 	}
 
+	return fmt.Sprintf("%s USE INDEX (%s)", model.TableNameEventLog, index)
+}
+
+// cursorWhere matches the logs after the cursor, (block_number, tx_index, log_index) > cursor,
+// expanded so mysql can use a range scan on block_number
+func (p GetLogParam) cursorWhere() sq.Or {
+	after := func(column string, value any) sq.Sqlizer {
+		if p.Desc {
+			return sq.Lt{column: value}
+		}
+		return sq.Gt{column: value}
+	}
+
+	return sq.Or{
+		after("block_number", p.Cursor.BlockNumber),
+		sq.And{
+			sq.Eq{"block_number": p.Cursor.BlockNumber},
+			sq.Or{
+				after("tx_index", p.Cursor.TxIndex),
+				sq.And{
+					sq.Eq{"tx_index": p.Cursor.TxIndex},
+					after("log_index", p.Cursor.LogIndex),
+				},
+			},
+		},
+	}
+}
+
+// ToOrderBy returns the order of the logs, ties are broken by the log position so pages are stable
+func (p GetLogParam) ToOrderBy() []string {
+	var columns []string
+
+	switch {
+	case p.Cursor != nil:
+		columns = []string{"block_number", "tx_index", "log_index"}
+	case p.OrderBy == 1:
+		columns = []string{"block_timestamp", "block_number", "tx_index", "log_index"}
+	case p.OrderBy == 2:
+		columns = []string{"block_number", "tx_index", "log_index"}
+	default:
+		columns = []string{"id"}
+	}
+
+	direction := " ASC"
 	if p.Desc {
-		orderby += " DESC"
-	} else {
-		orderby += " ASC"
+		direction = " DESC"
+	}
+
+	for i := range columns {
+		columns[i] += direction
 	}
-	return orderby
+	return columns
 }
 
 func GetLogs(ctx context.Context, db *sql.DB, filter *GetLogParam) ([]*model.Log, error) {
 
 	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
-		Select(
-			"id",
-			"chain_id",
-			"address",
-			"block_hash",
-			"block_number",
-			"topic_0",
-			"topic_1",
-			"topic_2",
-			"topic_3",
-			"tx_index",
-			"log_index",
-			"tx_hash",
-			"data",
-			"decoded_event",
-			"block_timestamp",
-			"created_at",
-		).
-		From(model.TableNameEventLog).
+		Select(logColumns...).
+		From(filter.ToFrom()).
 		Where(filter.ToWhere()).
-		OrderBy(filter.ToOrderBy()).
-		Limit(filter.Pagination.Limit()).
-		Offset(filter.Pagination.Offset())
+		OrderBy(filter.ToOrderBy()...).
+		Limit(filter.Pagination.Limit())
+
+	// the cursor already skips the previous pages
+	if filter.Cursor == nil {
+		qb = qb.Offset(filter.Pagination.Offset())
+	}
+
+	rows, err := qb.RunWith(db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanLogs(rows)
+}
+
+// GetTxLogs returns the logs of a transaction ordered by log index
+func GetTxLogs(ctx context.Context, db *sql.DB, chainID int64, txHash string) ([]*model.Log, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(logColumns...).
+		From(model.TableNameEventLog+" USE INDEX (idx_chainId_txHash)").
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"tx_hash": txHash},
+		).
+		OrderBy("log_index ASC")
 
 	rows, err := qb.RunWith(db).QueryContext(ctx)
 	if err != nil {
 		return nil, err
 	}
+
+	return scanLogs(rows)
+}
+
+// GetTxsLogs returns the logs of the transactions ordered by position in the chain,
+// at most limit logs of each transaction so one transaction with many logs cannot crowd out the others
+func GetTxsLogs(ctx context.Context, db *sql.DB, chainID int64, txHashes []string, limit uint64) ([]*model.Log, error) {
+	if len(txHashes) == 0 {
+		return nil, nil
+	}
+
+	ranked := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(logColumns...).
+		Column("ROW_NUMBER() OVER (PARTITION BY tx_hash ORDER BY log_index ASC) AS rn").
+		From(model.TableNameEventLog+" USE INDEX (idx_chainId_txHash)").
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"tx_hash": txHashes},
+		)
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(logColumns...).
+		FromSelect(ranked, "a").
+		Where(sq.LtOrEq{"rn": limit}).
+		OrderBy("block_number ASC", "tx_index ASC", "log_index ASC")
+
+	rows, err := qb.RunWith(db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanLogs(rows)
+}
+
+var logColumns = []string{
+	"id",
+	"chain_id",
+	"address",
+	"block_hash",
+	"block_number",
+	"topic_0",
+	"topic_1",
+	"topic_2",
+	"topic_3",
+	"tx_index",
+	"log_index",
+	"tx_hash",
+	"data",
+	"decoded_event",
+	"block_timestamp",
+	"confirmed",
+	"l1_block_number",
+	"l1_block_hash",
+	"created_at",
+}
+
+// scanLogs scans rows of the logColumns
+func scanLogs(rows *sql.Rows) ([]*model.Log, error) {
 	defer rows.Close()
 
 	var logs []*model.Log
@@ -205,6 +374,9 @@ func GetLogs(ctx context.Context, db *sql.DB, filter *GetLogParam) ([]*model.Log
 			&log.Data,
 			&log.DecodedEvent,
 			&log.BlockTimestamp,
+			&log.Confirmed,
+			&log.L1BlockNumber,
+			&log.L1BlockHash,
 			&log.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -219,7 +391,7 @@ func GetLogs(ctx context.Context, db *sql.DB, filter *GetLogParam) ([]*model.Log
 func GetTotal(ctx context.Context, db *sql.DB, filter *GetLogParam) (int64, error) {
 	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
 		Select("COUNT(*)").
-		From(model.TableNameEventLog).
+		From(filter.ToFrom()).
 		Where(filter.ToWhere())
 
 	var total int64
@@ -231,11 +403,12 @@ func GetTotal(ctx context.Context, db *sql.DB, filter *GetLogParam) (int64, erro
 }
 
 // deletes event logs after a given block number
-func TxDeleteLog(ctx context.Context, tx *sql.Tx, address string, fromBN uint64) error {
+func TxDeleteLog(ctx context.Context, tx *sql.Tx, chainID int64, address string, fromBN uint64) error {
 
 	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
 		Delete(model.TableNameEventLog).
 		Where(
+			sq.Eq{"chain_id": chainID},
 			sq.Eq{"address": address},
 			sq.Gt{"block_number": fromBN},
 		)
@@ -247,3 +420,133 @@ func TxDeleteLog(ctx context.Context, tx *sql.Tx, address string, fromBN uint64)
 
 	return nil
 }
+
+// deletes confirmed event logs after a given block number, unconfirmed logs are left for the scanner to reconcile
+func TxDeleteConfirmedLog(ctx context.Context, tx *sql.Tx, chainID int64, address string, fromBN uint64) error {
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Delete(model.TableNameEventLog).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"address": address},
+			sq.Gt{"block_number": fromBN},
+			sq.Eq{"confirmed": true},
+		)
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// deletes event logs within a block range, both ends inclusive
+func TxDeleteLogRange(ctx context.Context, tx *sql.Tx, chainID int64, address string, fromBN uint64, toBN uint64) error {
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Delete(model.TableNameEventLog).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"address": address},
+			sq.GtOrEq{"block_number": fromBN},
+			sq.LtOrEq{"block_number": toBN},
+		)
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// TxGetConfirmedLogFrom locks the confirmed logs of the address from the given block number,
+// returned with their topic0, position, block timestamp and queryable arguments only.
+func TxGetConfirmedLogFrom(ctx context.Context, tx *sql.Tx, chainID int64, address string, fromBN uint64) ([]*model.Log, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(
+			"chain_id",
+			"address",
+			"block_number",
+			"tx_index",
+			"log_index",
+			"topic_0",
+			"block_timestamp",
+		).
+		From(model.TableNameEventLog).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"address": address},
+			sq.GtOrEq{"block_number": fromBN},
+			sq.Eq{"confirmed": true},
+		).
+		Suffix("FOR UPDATE")
+
+	rows, err := qb.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := make([]*model.Log, 0)
+	keys := make(map[string]*model.Log)
+	for rows.Next() {
+		log := new(model.Log)
+		if err := rows.Scan(
+			&log.ChainID,
+			&log.Address,
+			&log.BlockNumber,
+			&log.TxIndex,
+			&log.LogIndex,
+			&log.Topic0,
+			&log.BlockTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		logs = append(logs, log)
+		keys[logKey(log.BlockNumber, log.TxIndex, log.LogIndex)] = log
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(logs) == 0 {
+		return logs, nil
+	}
+
+	args := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select("block_number", "tx_index", "log_index", "name", "value").
+		From(model.TableNameEventArg).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"address": address},
+			sq.GtOrEq{"block_number": fromBN},
+		)
+
+	argRows, err := args.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer argRows.Close()
+
+	for argRows.Next() {
+		arg := &model.EventArg{ChainID: chainID, Address: address}
+		if err := argRows.Scan(&arg.BlockNumber, &arg.TxIndex, &arg.LogIndex, &arg.Name, &arg.Value); err != nil {
+			return nil, err
+		}
+
+		// arguments of unconfirmed logs
+		log, ok := keys[logKey(arg.BlockNumber, arg.TxIndex, arg.LogIndex)]
+		if !ok {
+			continue
+		}
+		log.Args = append(log.Args, arg)
+	}
+
+	return logs, argRows.Err()
+}
+
+func logKey(blockNumber uint64, txIndex int32, logIndex int32) string {
+	return fmt.Sprintf("%d:%d:%d", blockNumber, txIndex, logIndex)
+}
@@ -61,7 +61,7 @@ func Test_LogRepo(t *testing.T) {
 	assert.NoError(t, err)
 
 	param := &eventlog.GetLogParam{
-		Address:    addr,
+		Addresses:  []string{addr},
 		StartTime:  time.Now().Add(-time.Second),
 		EndTime:    time.Now().Add(time.Second),
 		Pagination: &model.Pagination{Page: 1, Size: 10},
@@ -75,3 +75,31 @@ func Test_LogRepo(t *testing.T) {
 	assert.NotEmpty(t, logs)
 	assert.Equal(t, addr, logs[0].Address)
 }
+
+func Test_LogParam_ToFrom(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name  string
+		param eventlog.GetLogParam
+		index string
+	}{
+		{name: "no chain id", param: eventlog.GetLogParam{TxHashes: []string{"0x1"}}},
+		{name: "tx hash", param: eventlog.GetLogParam{ChainID: 1, TxHashes: []string{"0x1"}, StartTime: now}, index: "idx_chainId_txHash"},
+		{name: "topic 1", param: eventlog.GetLogParam{ChainID: 1, Addresses: []string{"0x1"}, Topic0s: []string{"0x2"}, Topic1s: []string{"0x3"}, StartTime: now}, index: "idx_chainId_t0_t1_bt"},
+		{name: "address block range", param: eventlog.GetLogParam{ChainID: 1, Addresses: []string{"0x1"}, BlockNumberLTE: 10}, index: "idx_chainId_addr_bn"},
+		{name: "address time range", param: eventlog.GetLogParam{ChainID: 1, Addresses: []string{"0x1"}, StartTime: now}, index: "idx_chainId_addr_bt"},
+		{name: "block range", param: eventlog.GetLogParam{ChainID: 1, BlockNumberGTE: 1}, index: "idx_chainId_bn"},
+		{name: "chain only", param: eventlog.GetLogParam{ChainID: 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.index == "" {
+				assert.Equal(t, model.TableNameEventLog, tc.param.ToFrom())
+				return
+			}
+			assert.Equal(t, model.TableNameEventLog+" USE INDEX ("+tc.index+")", tc.param.ToFrom())
+		})
+	}
+}
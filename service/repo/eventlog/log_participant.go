@@ -0,0 +1,117 @@
+package eventlog
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/service/model"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// TxInsertLogParticipant inserts the participants of event logs, participants that already exist are left untouched.
+// Participants are deleted together with their event log by foreign key.
+func TxInsertLogParticipant(ctx context.Context, tx *sql.Tx, participant ...*model.LogParticipant) error {
+	if len(participant) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameLogParticipant).
+		Options("IGNORE").
+		Columns(
+			"chain_id",
+			"address",
+			"block_number",
+			"tx_index",
+			"log_index",
+			"participant",
+		)
+
+	for _, v := range participant {
+		qb = qb.Values(
+			v.ChainID,
+			v.Address,
+			v.BlockNumber,
+			v.TxIndex,
+			v.LogIndex,
+			v.Participant,
+		)
+	}
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// GetParticipantParam filters the logs involving an address, latest first
+type GetParticipantParam struct {
+	ChainID        int64
+	Participant    string
+	Addresses      []string // contract addresses, all contracts when empty
+	BlockNumberGTE uint64
+	BlockNumberLTE uint64
+	Cursor         *model.LogCursor // keyset pagination, returns logs before the cursor
+	Limit          uint64
+}
+
+func (p GetParticipantParam) ToWhere() sq.And {
+	conds := sq.And{
+		sq.Eq{"p.chain_id": p.ChainID},
+		sq.Eq{"p.participant": p.Participant},
+	}
+
+	if len(p.Addresses) > 0 {
+		conds = append(conds, sq.Eq{"p.address": p.Addresses})
+	}
+
+	if p.BlockNumberGTE > 0 {
+		conds = append(conds, sq.GtOrEq{"p.block_number": p.BlockNumberGTE})
+	}
+
+	if p.BlockNumberLTE > 0 {
+		conds = append(conds, sq.LtOrEq{"p.block_number": p.BlockNumberLTE})
+	}
+
+	// (block_number, tx_index, log_index) < cursor, expanded so mysql can use a range scan on block_number
+	if p.Cursor != nil {
+		conds = append(conds, sq.Or{
+			sq.Lt{"p.block_number": p.Cursor.BlockNumber},
+			sq.And{
+				sq.Eq{"p.block_number": p.Cursor.BlockNumber},
+				sq.Or{
+					sq.Lt{"p.tx_index": p.Cursor.TxIndex},
+					sq.And{
+						sq.Eq{"p.tx_index": p.Cursor.TxIndex},
+						sq.Lt{"p.log_index": p.Cursor.LogIndex},
+					},
+				},
+			},
+		})
+	}
+
+	return conds
+}
+
+// GetParticipantLogs returns the logs involving an address across the contracts, latest first
+func GetParticipantLogs(ctx context.Context, db *sql.DB, filter *GetParticipantParam) ([]*model.Log, error) {
+	columns := make([]string, len(logColumns))
+	for i, column := range logColumns {
+		columns[i] = "l." + column
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(columns...).
+		From(model.TableNameLogParticipant+" AS p").
+		Join(fmt.Sprintf("%s AS l ON %s", model.TableNameEventLog,
+			"l.chain_id = p.chain_id AND l.address = p.address AND l.block_number = p.block_number AND l.tx_index = p.tx_index AND l.log_index = p.log_index")).
+		Where(filter.ToWhere()).
+		OrderBy("p.block_number DESC", "p.tx_index DESC", "p.log_index DESC").
+		Limit(filter.Limit)
+
+	rows, err := qb.RunWith(db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanLogs(rows)
+}
@@ -0,0 +1,301 @@
+package eventrollup
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/service/model"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+var rollupColumns = []string{
+	"chain_id",
+	"address",
+	"topic_0",
+	"bucket",
+	"bucket_start",
+	"log_count",
+	"volume",
+	"senders",
+	"updated_at",
+}
+
+// GetRollupFilter filters the rollups of a contract by bucket size and bucket start, both ends inclusive
+type GetRollupFilter struct {
+	ChainID   int64
+	Address   string
+	Topic0s   []string
+	Bucket    model.RollupBucket
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+func (f GetRollupFilter) ToWhere() sq.And {
+	conds := sq.And{
+		sq.Eq{"chain_id": f.ChainID},
+		sq.Eq{"address": f.Address},
+		sq.Eq{"bucket": f.Bucket},
+		sq.GtOrEq{"bucket_start": f.StartTime},
+		sq.LtOrEq{"bucket_start": f.EndTime},
+	}
+
+	if len(f.Topic0s) > 0 {
+		conds = append(conds, sq.Eq{"topic_0": f.Topic0s})
+	}
+
+	return conds
+}
+
+// Key returns the key of the bucket of a rollup
+func Key(topic0 string, bucket model.RollupBucket, start time.Time) string {
+	return fmt.Sprintf("%s:%s:%d", strings.ToLower(topic0), bucket, start.Unix())
+}
+
+// TxGetRollupForUpdate locks and returns the rollups of the buckets, keyed by Key
+func TxGetRollupForUpdate(ctx context.Context, tx *sql.Tx, chainID int64, address string, buckets []*model.EventRollup) (map[string]*model.EventRollup, error) {
+	res := make(map[string]*model.EventRollup, len(buckets))
+	if len(buckets) == 0 {
+		return res, nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(rollupColumns...).
+		From(model.TableNameEventRollup).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"address": address},
+			bucketWhere(buckets),
+		).
+		Suffix("FOR UPDATE")
+
+	rows, err := qb.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rollups, err := scanRollups(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range rollups {
+		res[Key(v.Topic0, v.Bucket, v.BucketStart)] = v
+	}
+
+	return res, nil
+}
+
+// TxUpsertRollup inserts or replaces the rollups
+func TxUpsertRollup(ctx context.Context, tx *sql.Tx, rollup ...*model.EventRollup) error {
+	if len(rollup) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameEventRollup).
+		Columns(rollupColumns...)
+
+	for _, v := range rollup {
+		qb = qb.Values(
+			v.ChainID,
+			v.Address,
+			v.Topic0,
+			v.Bucket,
+			v.BucketStart,
+			v.LogCount,
+			v.Volume.String(),
+			v.Senders,
+			v.UpdatedAt,
+		)
+	}
+
+	qb = qb.Suffix(`
+	ON DUPLICATE KEY UPDATE
+		log_count = VALUES(log_count),
+		volume = VALUES(volume),
+		senders = VALUES(senders),
+		updated_at = VALUES(updated_at)
+	`)
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// TxDeleteRollup deletes the rollups of the buckets together with their senders
+func TxDeleteRollup(ctx context.Context, tx *sql.Tx, chainID int64, address string, buckets []*model.EventRollup) error {
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	for _, table := range []string{model.TableNameEventRollup, model.TableNameEventRollupSender} {
+		qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+			Delete(table).
+			Where(
+				sq.Eq{"chain_id": chainID},
+				sq.Eq{"address": address},
+				bucketWhere(buckets),
+			)
+
+		if _, err := qb.RunWith(tx).ExecContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// TxAddSender adds the log count changes of the senders, senders left without logs must be deleted with TxDeleteEmptySender
+func TxAddSender(ctx context.Context, tx *sql.Tx, sender ...*model.EventRollupSender) error {
+	if len(sender) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameEventRollupSender).
+		Columns(
+			"chain_id",
+			"address",
+			"topic_0",
+			"bucket",
+			"bucket_start",
+			"sender",
+			"log_count",
+		)
+
+	for _, v := range sender {
+		qb = qb.Values(
+			v.ChainID,
+			v.Address,
+			v.Topic0,
+			v.Bucket,
+			v.BucketStart,
+			v.Sender,
+			v.LogCount,
+		)
+	}
+
+	qb = qb.Suffix(`
+	ON DUPLICATE KEY UPDATE
+		log_count = log_count + VALUES(log_count)
+	`)
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// TxDeleteEmptySender deletes the senders of the buckets without logs
+func TxDeleteEmptySender(ctx context.Context, tx *sql.Tx, chainID int64, address string, buckets []*model.EventRollup) error {
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Delete(model.TableNameEventRollupSender).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"address": address},
+			bucketWhere(buckets),
+			sq.LtOrEq{"log_count": 0},
+		)
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// TxCountSender counts the senders of the buckets, keyed by Key. Buckets without senders are omitted.
+func TxCountSender(ctx context.Context, tx *sql.Tx, chainID int64, address string, buckets []*model.EventRollup) (map[string]int64, error) {
+	res := make(map[string]int64, len(buckets))
+	if len(buckets) == 0 {
+		return res, nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select("topic_0", "bucket", "bucket_start", "COUNT(*)").
+		From(model.TableNameEventRollupSender).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"address": address},
+			bucketWhere(buckets),
+		).
+		GroupBy("topic_0", "bucket", "bucket_start")
+
+	rows, err := qb.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var topic0 string
+		var bucket model.RollupBucket
+		var start time.Time
+		var count int64
+		if err := rows.Scan(&topic0, &bucket, &start, &count); err != nil {
+			return nil, err
+		}
+		res[Key(topic0, bucket, start)] = count
+	}
+
+	return res, rows.Err()
+}
+
+// GetRollups returns the rollups of a contract, ordered by bucket start and event
+func GetRollups(ctx context.Context, db *sql.DB, filter *GetRollupFilter) ([]*model.EventRollup, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(rollupColumns...).
+		From(model.TableNameEventRollup).
+		Where(filter.ToWhere()).
+		OrderBy("bucket_start", "topic_0")
+
+	rows, err := qb.RunWith(db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRollups(rows)
+}
+
+// bucketWhere matches the buckets of the rollups
+func bucketWhere(buckets []*model.EventRollup) sq.Or {
+	conds := make(sq.Or, 0, len(buckets))
+	for _, v := range buckets {
+		conds = append(conds, sq.Eq{"topic_0": v.Topic0, "bucket": v.Bucket, "bucket_start": v.BucketStart})
+	}
+	return conds
+}
+
+func scanRollups(rows *sql.Rows) ([]*model.EventRollup, error) {
+	res := make([]*model.EventRollup, 0)
+	for rows.Next() {
+		v := new(model.EventRollup)
+		var volume string
+		if err := rows.Scan(
+			&v.ChainID,
+			&v.Address,
+			&v.Topic0,
+			&v.Bucket,
+			&v.BucketStart,
+			&v.LogCount,
+			&volume,
+			&v.Senders,
+			&v.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		n, ok := new(big.Int).SetString(volume, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid volume: %s", volume)
+		}
+		v.Volume = n
+		res = append(res, v)
+	}
+
+	return res, rows.Err()
+}
@@ -0,0 +1,200 @@
+package exportjob
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/internal/enum"
+	"evm_event_indexer/service/model"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+var jobColumns = []string{
+	"id",
+	"user_id",
+	"format",
+	"filter",
+	"status",
+	"file_name",
+	"row_count",
+	"error",
+	"created_at",
+	"updated_at",
+}
+
+// TxInsertJob inserts an export job and returns its id
+func TxInsertJob(ctx context.Context, tx *sql.Tx, job *model.ExportJob) (int64, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameExportJob).
+		Columns(
+			"user_id",
+			"format",
+			"filter",
+			"status",
+			"created_at",
+			"updated_at",
+		).
+		Values(
+			job.UserID,
+			job.Format,
+			job.Filter,
+			job.Status,
+			job.CreatedAt,
+			job.UpdatedAt,
+		)
+
+	result, err := qb.RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// TxClaimJob locks the oldest pending job, or a running job not updated since staleBefore, and marks it running,
+// rows locked by another instance are skipped. Returns nil when there is no job to run.
+func TxClaimJob(ctx context.Context, tx *sql.Tx, now time.Time, staleBefore time.Time) (*model.ExportJob, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(jobColumns...).
+		From(model.TableNameExportJob).
+		Where(sq.Or{
+			sq.Eq{"status": enum.ExportStatusPending},
+			sq.And{
+				sq.Eq{"status": enum.ExportStatusRunning},
+				sq.Lt{"updated_at": staleBefore},
+			},
+		}).
+		OrderBy("id").
+		Limit(1).
+		Suffix("FOR UPDATE SKIP LOCKED")
+
+	rows, err := qb.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := scanJobs(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+
+	job := jobs[0]
+	job.Status = enum.ExportStatusRunning
+	job.UpdatedAt = now
+
+	claim := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Update(model.TableNameExportJob).
+		Set("status", job.Status).
+		Set("updated_at", job.UpdatedAt).
+		Where(sq.Eq{"id": job.ID})
+
+	if _, err := claim.RunWith(tx).ExecContext(ctx); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// UpdateJobResult saves the result of a running job
+func UpdateJobResult(ctx context.Context, db *sql.DB, job *model.ExportJob) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Update(model.TableNameExportJob).
+		Set("status", job.Status).
+		Set("file_name", job.FileName).
+		Set("row_count", job.Rows).
+		Set("error", job.Error).
+		Set("updated_at", job.UpdatedAt).
+		Where(sq.Eq{"id": job.ID, "status": enum.ExportStatusRunning})
+
+	_, err := qb.RunWith(db).ExecContext(ctx)
+	return err
+}
+
+// DeleteJobs deletes export jobs by id
+func DeleteJobs(ctx context.Context, db *sql.DB, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Delete(model.TableNameExportJob).
+		Where(sq.Eq{"id": ids})
+
+	_, err := qb.RunWith(db).ExecContext(ctx)
+	return err
+}
+
+type GetJobFilter struct {
+	IDs           []int64
+	UserID        int64
+	Statuses      []enum.ExportStatus
+	UpdatedBefore time.Time
+	Limit         uint64
+}
+
+func (p GetJobFilter) ToWhere() sq.And {
+	var conds sq.And
+	if len(p.IDs) > 0 {
+		conds = append(conds, sq.Eq{"id": p.IDs})
+	}
+	if p.UserID != 0 {
+		conds = append(conds, sq.Eq{"user_id": p.UserID})
+	}
+	if len(p.Statuses) > 0 {
+		conds = append(conds, sq.Eq{"status": p.Statuses})
+	}
+	if !p.UpdatedBefore.IsZero() {
+		conds = append(conds, sq.Lt{"updated_at": p.UpdatedBefore})
+	}
+	return conds
+}
+
+func GetJobs(ctx context.Context, db *sql.DB, filter *GetJobFilter) ([]*model.ExportJob, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(jobColumns...).
+		From(model.TableNameExportJob).
+		Where(filter.ToWhere()).
+		OrderBy("id")
+
+	if filter.Limit > 0 {
+		qb = qb.Limit(filter.Limit)
+	}
+
+	rows, err := qb.RunWith(db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanJobs(rows)
+}
+
+func scanJobs(rows *sql.Rows) ([]*model.ExportJob, error) {
+	defer rows.Close()
+
+	res := make([]*model.ExportJob, 0)
+	for rows.Next() {
+		job := &model.ExportJob{Filter: new(model.ExportFilter)}
+		if err := rows.Scan(
+			&job.ID,
+			&job.UserID,
+			&job.Format,
+			job.Filter,
+			&job.Status,
+			&job.FileName,
+			&job.Rows,
+			&job.Error,
+			&job.CreatedAt,
+			&job.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		res = append(res, job)
+	}
+
+	return res, rows.Err()
+}
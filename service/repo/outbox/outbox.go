@@ -0,0 +1,236 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/internal/outbox"
+	"evm_event_indexer/service/model"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+var outboxColumns = []string{
+	"id",
+	"type",
+	"chain_id",
+	"address",
+	"block_number",
+	"tx_index",
+	"log_index",
+	"message_key",
+	"payload",
+	"claimed_at",
+	"delivered_at",
+	"created_at",
+}
+
+// NewKey returns the message key of a log, the same position of a contract always has the same key
+func NewKey(chainID int64, address string, blockNumber uint64, txIndex int32, logIndex int32) string {
+	return fmt.Sprintf("%d:%s:%d:%d:%d", chainID, address, blockNumber, txIndex, logIndex)
+}
+
+// TxInsertOutbox writes outbox messages
+func TxInsertOutbox(ctx context.Context, tx *sql.Tx, outbox ...*model.Outbox) error {
+	if len(outbox) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameEventOutbox).
+		Columns(
+			"type",
+			"chain_id",
+			"address",
+			"block_number",
+			"tx_index",
+			"log_index",
+			"message_key",
+			"payload",
+			"created_at",
+		)
+
+	for _, v := range outbox {
+		var payload []byte
+		if v.Payload != nil {
+			payload = v.Payload
+		}
+
+		qb = qb.Values(
+			v.Type,
+			v.ChainID,
+			v.Address,
+			v.BlockNumber,
+			v.TxIndex,
+			v.LogIndex,
+			v.Key,
+			payload,
+			v.CreatedAt,
+		)
+	}
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// TxInsertTombstone writes a tombstone for every confirmed log of the address from the given block number,
+// it must run before the logs are deleted. Unconfirmed logs are not written to the outbox and get no tombstone.
+func TxInsertTombstone(ctx context.Context, tx *sql.Tx, chainID int64, address string, fromBN uint64, now time.Time) error {
+	selected := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select().
+		Column("?", outbox.TypeTombstone).
+		Columns(
+			"chain_id",
+			"address",
+			"block_number",
+			"tx_index",
+			"log_index",
+			"CONCAT(chain_id, ':', address, ':', block_number, ':', tx_index, ':', log_index)",
+		).
+		Column("?", now).
+		From(model.TableNameEventLog).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"address": address},
+			sq.GtOrEq{"block_number": fromBN},
+			sq.Eq{"confirmed": true},
+		).
+		OrderBy("block_number", "tx_index", "log_index")
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameEventOutbox).
+		Columns(
+			"type",
+			"chain_id",
+			"address",
+			"block_number",
+			"tx_index",
+			"log_index",
+			"message_key",
+			"created_at",
+		).
+		Select(selected)
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// TxClaimOutbox claims the oldest undelivered messages in id order which are not claimed, or whose claim is older than staleBefore,
+// rows locked by another relay are skipped. The claim is kept after commit so messages are delivered outside the transaction.
+func TxClaimOutbox(ctx context.Context, tx *sql.Tx, now time.Time, staleBefore time.Time, limit uint64) ([]*model.Outbox, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(outboxColumns...).
+		From(model.TableNameEventOutbox).
+		Where(
+			sq.Eq{"delivered_at": nil},
+			sq.Or{
+				sq.Eq{"claimed_at": nil},
+				sq.Lt{"claimed_at": staleBefore},
+			},
+		).
+		OrderBy("id").
+		Limit(limit).
+		Suffix("FOR UPDATE SKIP LOCKED")
+
+	rows, err := qb.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]*model.Outbox, 0)
+	ids := make([]int64, 0)
+	for rows.Next() {
+		v := new(model.Outbox)
+		var payload []byte
+		if err := rows.Scan(
+			&v.ID,
+			&v.Type,
+			&v.ChainID,
+			&v.Address,
+			&v.BlockNumber,
+			&v.TxIndex,
+			&v.LogIndex,
+			&v.Key,
+			&payload,
+			&v.ClaimedAt,
+			&v.DeliveredAt,
+			&v.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		v.Payload = payload
+		v.ClaimedAt = &now
+		res = append(res, v)
+		ids = append(ids, v.ID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	claim := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Update(model.TableNameEventOutbox).
+		Set("claimed_at", now).
+		Where(sq.Eq{"id": ids})
+
+	if _, err := claim.RunWith(tx).ExecContext(ctx); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// MarkDelivered marks the messages as delivered
+func MarkDelivered(ctx context.Context, db *sql.DB, now time.Time, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Update(model.TableNameEventOutbox).
+		Set("delivered_at", now).
+		Where(sq.Eq{"id": ids})
+
+	_, err := qb.RunWith(db).ExecContext(ctx)
+	return err
+}
+
+// ReleaseClaim clears the claim of the undelivered messages, so they are claimed again on the next poll instead of after the lease
+func ReleaseClaim(ctx context.Context, db *sql.DB, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Update(model.TableNameEventOutbox).
+		Set("claimed_at", nil).
+		Where(
+			sq.Eq{"id": ids},
+			sq.Eq{"delivered_at": nil},
+		)
+
+	_, err := qb.RunWith(db).ExecContext(ctx)
+	return err
+}
+
+// DeleteDelivered deletes up to limit messages delivered before the given time, returns the number of deleted messages
+func DeleteDelivered(ctx context.Context, db *sql.DB, before time.Time, limit uint64) (int64, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Delete(model.TableNameEventOutbox).
+		Where(sq.Lt{"delivered_at": before}).
+		OrderBy("delivered_at").
+		Limit(limit)
+
+	res, err := qb.RunWith(db).ExecContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,265 @@
+package tokenallowance
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/service/model"
+	"math/big"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var allowanceColumns = []string{
+	"chain_id",
+	"token",
+	"owner",
+	"spender",
+	"allowance",
+	"block_number",
+	"tx_hash",
+	"updated_at",
+}
+
+// GetAllowanceFilter filters the outstanding allowances of an owner, zero allowances are excluded
+type GetAllowanceFilter struct {
+	ChainID       int64
+	Owner         string
+	Token         string
+	UnlimitedOnly bool
+	Pagination    *model.Pagination
+}
+
+func (f GetAllowanceFilter) ToWhere() sq.And {
+	conds := sq.And{
+		sq.Eq{"chain_id": f.ChainID},
+		sq.Eq{"owner": f.Owner},
+		sq.Gt{"allowance": model.EncodeBalance(new(big.Int))},
+	}
+
+	if f.Token != "" {
+		conds = append(conds, sq.Eq{"token": f.Token})
+	}
+
+	if f.UnlimitedOnly {
+		conds = append(conds, sq.Eq{"unlimited": true})
+	}
+
+	return conds
+}
+
+// TxUpsertAllowance inserts or replaces the allowances, revoked allowances are kept with zero
+func TxUpsertAllowance(ctx context.Context, tx *sql.Tx, allowance ...*model.TokenAllowance) error {
+	if len(allowance) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameTokenAllowance).
+		Columns(append(allowanceColumns, "unlimited")...)
+
+	for _, v := range allowance {
+		qb = qb.Values(
+			v.ChainID,
+			v.Token,
+			v.Owner,
+			v.Spender,
+			model.EncodeBalance(v.Allowance),
+			v.BlockNumber,
+			v.TxHash,
+			v.UpdatedAt,
+			v.Unlimited(),
+		)
+	}
+
+	qb = qb.Suffix(`
+	ON DUPLICATE KEY UPDATE
+		allowance = VALUES(allowance),
+		block_number = VALUES(block_number),
+		tx_hash = VALUES(tx_hash),
+		updated_at = VALUES(updated_at),
+		unlimited = VALUES(unlimited)
+	`)
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// TxDeleteAllowance deletes the allowances of the owner and spender pairs
+func TxDeleteAllowance(ctx context.Context, tx *sql.Tx, chainID int64, token string, pairs []*model.TokenAllowance) error {
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	conds := make(sq.Or, 0, len(pairs))
+	for _, v := range pairs {
+		conds = append(conds, sq.Eq{"owner": v.Owner, "spender": v.Spender})
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Delete(model.TableNameTokenAllowance).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"token": token},
+			conds,
+		)
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// TxGetAllowanceFrom locks and returns the allowances of the token last approved from the given block number
+func TxGetAllowanceFrom(ctx context.Context, tx *sql.Tx, chainID int64, token string, fromBN uint64) ([]*model.TokenAllowance, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(allowanceColumns...).
+		From(model.TableNameTokenAllowance).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"token": token},
+			sq.GtOrEq{"block_number": fromBN},
+		).
+		Suffix("FOR UPDATE")
+
+	rows, err := qb.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAllowances(rows)
+}
+
+// TxGetLatestApproval returns the latest confirmed Approval log of each owner and spender pair before the block number,
+// keyed by owner and spender joined with a colon. Pairs without an earlier approval are omitted.
+func TxGetLatestApproval(ctx context.Context, tx *sql.Tx, chainID int64, token string, topic0 string, beforeBN uint64, pairs []*model.TokenAllowance) (map[string]*model.TokenAllowance, error) {
+	res := make(map[string]*model.TokenAllowance, len(pairs))
+	if len(pairs) == 0 {
+		return res, nil
+	}
+
+	conds := make(sq.Or, 0, len(pairs))
+	for _, v := range pairs {
+		conds = append(conds, sq.Eq{"topic_1": addressTopic(v.Owner), "topic_2": addressTopic(v.Spender)})
+	}
+
+	ranked := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select("topic_1", "topic_2", "data", "block_number", "tx_hash").
+		Column("ROW_NUMBER() OVER (PARTITION BY topic_1, topic_2 ORDER BY block_number DESC, tx_index DESC, log_index DESC) AS rn").
+		From(model.TableNameEventLog).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"address": token},
+			sq.Eq{"topic_0": topic0},
+			sq.Eq{"confirmed": true},
+			sq.Lt{"block_number": beforeBN},
+			conds,
+		)
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select("topic_1", "topic_2", "data", "block_number", "tx_hash").
+		FromSelect(ranked, "a").
+		Where(sq.Eq{"rn": 1})
+
+	rows, err := qb.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		v := &model.TokenAllowance{ChainID: chainID, Token: token}
+		var owner, spender string
+		var data []byte
+		if err := rows.Scan(&owner, &spender, &data, &v.BlockNumber, &v.TxHash); err != nil {
+			return nil, err
+		}
+
+		v.Owner = topicAddress(owner)
+		v.Spender = topicAddress(spender)
+		v.Allowance = new(big.Int).SetBytes(data)
+		res[PairKey(v.Owner, v.Spender)] = v
+	}
+
+	return res, rows.Err()
+}
+
+// GetAllowanceTotal counts the outstanding allowances
+func GetAllowanceTotal(ctx context.Context, db *sql.DB, filter *GetAllowanceFilter) (int64, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select("COUNT(*)").
+		From(model.TableNameTokenAllowance).
+		Where(filter.ToWhere())
+
+	var total int64
+	if err := qb.RunWith(db).QueryRowContext(ctx).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// GetAllowances returns the outstanding allowances, latest approval first
+func GetAllowances(ctx context.Context, db *sql.DB, filter *GetAllowanceFilter) ([]*model.TokenAllowance, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(allowanceColumns...).
+		From(model.TableNameTokenAllowance).
+		Where(filter.ToWhere()).
+		OrderBy("block_number DESC", "token", "spender")
+
+	if filter.Pagination != nil {
+		qb = qb.Offset(filter.Pagination.Offset()).Limit(filter.Pagination.Limit())
+	}
+
+	rows, err := qb.RunWith(db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAllowances(rows)
+}
+
+// PairKey returns the key of an owner and spender pair
+func PairKey(owner string, spender string) string {
+	return owner + ":" + spender
+}
+
+func scanAllowances(rows *sql.Rows) ([]*model.TokenAllowance, error) {
+	res := make([]*model.TokenAllowance, 0)
+	for rows.Next() {
+		v := new(model.TokenAllowance)
+		var allowance string
+		if err := rows.Scan(
+			&v.ChainID,
+			&v.Token,
+			&v.Owner,
+			&v.Spender,
+			&allowance,
+			&v.BlockNumber,
+			&v.TxHash,
+			&v.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		n, err := model.DecodeBalance(allowance)
+		if err != nil {
+			return nil, err
+		}
+		v.Allowance = n
+		res = append(res, v)
+	}
+
+	return res, rows.Err()
+}
+
+// addressTopic converts a lowercase address into its indexed topic form
+func addressTopic(address string) string {
+	return common.BytesToHash(common.HexToAddress(address).Bytes()).Hex()
+}
+
+// topicAddress converts an indexed topic into the lowercase address form allowances are stored in
+func topicAddress(topic string) string {
+	return strings.ToLower(common.HexToAddress(topic).Hex())
+}
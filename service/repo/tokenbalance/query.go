@@ -0,0 +1,193 @@
+package tokenbalance
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/service/model"
+	"math/big"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+var historyColumns = []string{
+	"chain_id",
+	"token",
+	"holder",
+	"block_number",
+	"tx_index",
+	"log_index",
+	"delta",
+	"balance",
+	"created_at",
+}
+
+// GetHolderFilter filters the current balances of a token, holders without a positive balance are excluded
+type GetHolderFilter struct {
+	ChainID    int64
+	Token      string
+	Pagination *model.Pagination
+}
+
+func (f GetHolderFilter) ToWhere() sq.And {
+	return sq.And{
+		sq.Eq{"chain_id": f.ChainID},
+		sq.Eq{"token": f.Token},
+		sq.Gt{"balance": model.EncodeBalance(new(big.Int))},
+	}
+}
+
+// GetBalance returns the current balance of a holder, nil if the holder never received or sent the token
+func GetBalance(ctx context.Context, db *sql.DB, chainID int64, token string, holder string) (*model.TokenBalance, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(balanceColumns...).
+		From(model.TableNameTokenBalance).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"token": token},
+			sq.Eq{"holder": holder},
+		)
+
+	rows, err := qb.RunWith(db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances, err := scanBalances(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(balances) == 0 {
+		return nil, nil
+	}
+
+	return balances[0], nil
+}
+
+// GetBalanceAt returns the last balance change of a holder at or before the block number, nil if there is none
+func GetBalanceAt(ctx context.Context, db *sql.DB, chainID int64, token string, holder string, blockNumber uint64) (*model.TokenBalanceHistory, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(historyColumns...).
+		From(model.TableNameTokenBalanceHistory).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"token": token},
+			sq.Eq{"holder": holder},
+			sq.LtOrEq{"block_number": blockNumber},
+		).
+		OrderBy("block_number DESC", "tx_index DESC", "log_index DESC").
+		Limit(1)
+
+	rows, err := qb.RunWith(db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history, err := scanHistory(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(history) == 0 {
+		return nil, nil
+	}
+
+	return history[0], nil
+}
+
+// GetHolderTotal counts the holders with a positive balance
+func GetHolderTotal(ctx context.Context, db *sql.DB, filter *GetHolderFilter) (int64, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select("COUNT(*)").
+		From(model.TableNameTokenBalance).
+		Where(filter.ToWhere())
+
+	var total int64
+	if err := qb.RunWith(db).QueryRowContext(ctx).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// GetHolders returns the holders with a positive balance, largest first
+func GetHolders(ctx context.Context, db *sql.DB, filter *GetHolderFilter) ([]*model.TokenBalance, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(balanceColumns...).
+		From(model.TableNameTokenBalance).
+		Where(filter.ToWhere()).
+		OrderBy("balance DESC", "holder")
+
+	if filter.Pagination != nil {
+		qb = qb.Offset(filter.Pagination.Offset()).Limit(filter.Pagination.Limit())
+	}
+
+	rows, err := qb.RunWith(db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBalances(rows)
+}
+
+func scanBalances(rows *sql.Rows) ([]*model.TokenBalance, error) {
+	res := make([]*model.TokenBalance, 0)
+	for rows.Next() {
+		v := new(model.TokenBalance)
+		var balance string
+		if err := rows.Scan(
+			&v.ChainID,
+			&v.Token,
+			&v.Holder,
+			&balance,
+			&v.BlockNumber,
+			&v.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		n, err := model.DecodeBalance(balance)
+		if err != nil {
+			return nil, err
+		}
+		v.Balance = n
+		res = append(res, v)
+	}
+
+	return res, rows.Err()
+}
+
+func scanHistory(rows *sql.Rows) ([]*model.TokenBalanceHistory, error) {
+	res := make([]*model.TokenBalanceHistory, 0)
+	for rows.Next() {
+		v := new(model.TokenBalanceHistory)
+		var delta, balance string
+		if err := rows.Scan(
+			&v.ChainID,
+			&v.Token,
+			&v.Holder,
+			&v.BlockNumber,
+			&v.TxIndex,
+			&v.LogIndex,
+			&delta,
+			&balance,
+			&v.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		var err error
+		if v.Delta, err = model.DecodeBalance(delta); err != nil {
+			return nil, err
+		}
+		if v.Balance, err = model.DecodeBalance(balance); err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+
+	return res, rows.Err()
+}
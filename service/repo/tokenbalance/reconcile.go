@@ -0,0 +1,197 @@
+package tokenbalance
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/service/model"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+var reconcileColumns = []string{
+	"chain_id",
+	"token",
+	"holder",
+	"block_number",
+	"ledger_balance",
+	"chain_balance",
+	"drift",
+	"drifted",
+	"checked_at",
+}
+
+// GetReconcileFilter filters the reconciliation results, sorted by the latest check
+type GetReconcileFilter struct {
+	ChainID    int64
+	Token      string
+	DriftOnly  bool
+	Pagination *model.Pagination
+}
+
+func (f GetReconcileFilter) ToWhere() sq.And {
+	var conds sq.And
+	if f.ChainID != 0 {
+		conds = append(conds, sq.Eq{"chain_id": f.ChainID})
+	}
+
+	if f.Token != "" {
+		conds = append(conds, sq.Eq{"token": f.Token})
+	}
+
+	if f.DriftOnly {
+		conds = append(conds, sq.Eq{"drifted": true})
+	}
+
+	return conds
+}
+
+// UpsertReconcile saves the latest reconciliation result of each holder
+func UpsertReconcile(ctx context.Context, db *sql.DB, result ...*model.TokenReconcile) error {
+	if len(result) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameTokenReconcile).
+		Columns(reconcileColumns...)
+
+	for _, v := range result {
+		qb = qb.Values(
+			v.ChainID,
+			v.Token,
+			v.Holder,
+			v.BlockNumber,
+			v.LedgerBalance.String(),
+			v.ChainBalance.String(),
+			v.Drift.String(),
+			v.HasDrift(),
+			v.CheckedAt,
+		)
+	}
+
+	qb = qb.Suffix(`
+	ON DUPLICATE KEY UPDATE
+		block_number = VALUES(block_number),
+		ledger_balance = VALUES(ledger_balance),
+		chain_balance = VALUES(chain_balance),
+		drift = VALUES(drift),
+		drifted = VALUES(drifted),
+		checked_at = VALUES(checked_at)
+	`)
+
+	_, err := qb.RunWith(db).ExecContext(ctx)
+	return err
+}
+
+// GetReconcileTotal counts the reconciliation results
+func GetReconcileTotal(ctx context.Context, db *sql.DB, filter *GetReconcileFilter) (int64, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select("COUNT(*)").
+		From(model.TableNameTokenReconcile).
+		Where(filter.ToWhere())
+
+	var total int64
+	if err := qb.RunWith(db).QueryRowContext(ctx).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// GetReconciles returns the reconciliation results, latest checks first
+func GetReconciles(ctx context.Context, db *sql.DB, filter *GetReconcileFilter) ([]*model.TokenReconcile, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(reconcileColumns...).
+		From(model.TableNameTokenReconcile).
+		Where(filter.ToWhere()).
+		OrderBy("checked_at DESC", "chain_id", "token", "holder")
+
+	if filter.Pagination != nil {
+		qb = qb.Offset(filter.Pagination.Offset()).Limit(filter.Pagination.Limit())
+	}
+
+	rows, err := qb.RunWith(db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]*model.TokenReconcile, 0)
+	for rows.Next() {
+		v := new(model.TokenReconcile)
+		var ledger, chain, drift string
+		var drifted bool
+		if err := rows.Scan(
+			&v.ChainID,
+			&v.Token,
+			&v.Holder,
+			&v.BlockNumber,
+			&ledger,
+			&chain,
+			&drift,
+			&drifted,
+			&v.CheckedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		var err error
+		if v.LedgerBalance, err = model.DecodeBalance(ledger); err != nil {
+			return nil, err
+		}
+		if v.ChainBalance, err = model.DecodeBalance(chain); err != nil {
+			return nil, err
+		}
+		if v.Drift, err = model.DecodeBalance(drift); err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+
+	return res, rows.Err()
+}
+
+// GetSampleHolders returns up to size holders to reconcile, half of the largest balances and half of the latest changes
+func GetSampleHolders(ctx context.Context, db *sql.DB, chainID int64, token string, size uint64) ([]string, error) {
+	res := make([]string, 0, size)
+	seen := make(map[string]struct{}, size)
+
+	for _, orderBy := range []string{"balance DESC", "block_number DESC"} {
+		qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+			Select("holder").
+			From(model.TableNameTokenBalance).
+			Where(
+				sq.Eq{"chain_id": chainID},
+				sq.Eq{"token": token},
+			).
+			OrderBy(orderBy).
+			Limit(size/2 + size%2)
+
+		rows, err := qb.RunWith(db).QueryContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var holder string
+			if err := rows.Scan(&holder); err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			if _, ok := seen[holder]; ok {
+				continue
+			}
+			seen[holder] = struct{}{}
+			res = append(res, holder)
+		}
+
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rows.Close()
+	}
+
+	return res, nil
+}
@@ -0,0 +1,237 @@
+package tokenbalance
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/service/model"
+	"math/big"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+var balanceColumns = []string{
+	"chain_id",
+	"token",
+	"holder",
+	"balance",
+	"block_number",
+	"updated_at",
+}
+
+// TxGetBalanceForUpdate locks and returns the current balances of the holders, keyed by holder
+func TxGetBalanceForUpdate(ctx context.Context, tx *sql.Tx, chainID int64, token string, holders []string) (map[string]*big.Int, error) {
+	res := make(map[string]*big.Int, len(holders))
+	if len(holders) == 0 {
+		return res, nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select("holder", "balance").
+		From(model.TableNameTokenBalance).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"token": token},
+			sq.Eq{"holder": holders},
+		).
+		Suffix("FOR UPDATE")
+
+	rows, err := qb.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var holder, balance string
+		if err := rows.Scan(&holder, &balance); err != nil {
+			return nil, err
+		}
+
+		n, err := model.DecodeBalance(balance)
+		if err != nil {
+			return nil, err
+		}
+		res[holder] = n
+	}
+
+	return res, rows.Err()
+}
+
+// TxUpsertBalance inserts or replaces the current balances
+func TxUpsertBalance(ctx context.Context, tx *sql.Tx, balance ...*model.TokenBalance) error {
+	if len(balance) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameTokenBalance).
+		Columns(balanceColumns...)
+
+	for _, v := range balance {
+		qb = qb.Values(
+			v.ChainID,
+			v.Token,
+			v.Holder,
+			model.EncodeBalance(v.Balance),
+			v.BlockNumber,
+			v.UpdatedAt,
+		)
+	}
+
+	qb = qb.Suffix(`
+	ON DUPLICATE KEY UPDATE
+		balance = VALUES(balance),
+		block_number = VALUES(block_number),
+		updated_at = VALUES(updated_at)
+	`)
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// TxDeleteBalance deletes the current balances of the holders
+func TxDeleteBalance(ctx context.Context, tx *sql.Tx, chainID int64, token string, holders []string) error {
+	if len(holders) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Delete(model.TableNameTokenBalance).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"token": token},
+			sq.Eq{"holder": holders},
+		)
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// TxInsertHistory inserts balance changes
+func TxInsertHistory(ctx context.Context, tx *sql.Tx, history ...*model.TokenBalanceHistory) error {
+	if len(history) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameTokenBalanceHistory).
+		Columns(
+			"chain_id",
+			"token",
+			"holder",
+			"block_number",
+			"tx_index",
+			"log_index",
+			"delta",
+			"balance",
+			"created_at",
+		)
+
+	for _, v := range history {
+		qb = qb.Values(
+			v.ChainID,
+			v.Token,
+			v.Holder,
+			v.BlockNumber,
+			v.TxIndex,
+			v.LogIndex,
+			v.Delta.String(),
+			model.EncodeBalance(v.Balance),
+			v.CreatedAt,
+		)
+	}
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// TxGetHistoryHolders locks the balance changes of the token from the given block number and returns their holders
+func TxGetHistoryHolders(ctx context.Context, tx *sql.Tx, chainID int64, token string, fromBN uint64) ([]string, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select("holder").
+		From(model.TableNameTokenBalanceHistory).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"token": token},
+			sq.GtOrEq{"block_number": fromBN},
+		).
+		Suffix("FOR UPDATE")
+
+	rows, err := qb.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]struct{})
+	res := make([]string, 0)
+	for rows.Next() {
+		var holder string
+		if err := rows.Scan(&holder); err != nil {
+			return nil, err
+		}
+
+		if _, ok := seen[holder]; ok {
+			continue
+		}
+		seen[holder] = struct{}{}
+		res = append(res, holder)
+	}
+
+	return res, rows.Err()
+}
+
+// TxDeleteHistory deletes the balance changes of the token from the given block number
+func TxDeleteHistory(ctx context.Context, tx *sql.Tx, chainID int64, token string, fromBN uint64) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Delete(model.TableNameTokenBalanceHistory).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"token": token},
+			sq.GtOrEq{"block_number": fromBN},
+		)
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// TxGetLatestHistory returns the latest balance change of each holder, keyed by holder.
+// Holders without any change are omitted.
+func TxGetLatestHistory(ctx context.Context, tx *sql.Tx, chainID int64, token string, holders []string) (map[string]*model.TokenBalanceHistory, error) {
+	res := make(map[string]*model.TokenBalanceHistory, len(holders))
+	if len(holders) == 0 {
+		return res, nil
+	}
+
+	ranked := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(historyColumns...).
+		Column("ROW_NUMBER() OVER (PARTITION BY holder ORDER BY block_number DESC, tx_index DESC, log_index DESC) AS rn").
+		From(model.TableNameTokenBalanceHistory).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"token": token},
+			sq.Eq{"holder": holders},
+		)
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(historyColumns...).
+		FromSelect(ranked, "h").
+		Where(sq.Eq{"rn": 1})
+
+	rows, err := qb.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history, err := scanHistory(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range history {
+		res[v.Holder] = v
+	}
+
+	return res, nil
+}
@@ -0,0 +1,127 @@
+package tokenmetadata
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/service/model"
+	"fmt"
+	"math/big"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+var metadataColumns = []string{
+	"chain_id",
+	"token",
+	"name",
+	"symbol",
+	"decimals",
+	"total_supply",
+	"created_at",
+	"updated_at",
+}
+
+// GetMetadataFilter filters the cached metadata, a zero chain id matches all chains
+type GetMetadataFilter struct {
+	ChainID int64
+	Tokens  []string
+}
+
+func (f GetMetadataFilter) ToWhere() sq.And {
+	var conds sq.And
+	if f.ChainID != 0 {
+		conds = append(conds, sq.Eq{"chain_id": f.ChainID})
+	}
+
+	if len(f.Tokens) > 0 {
+		conds = append(conds, sq.Eq{"token": f.Tokens})
+	}
+
+	return conds
+}
+
+// UpsertMetadata saves the metadata of a token, created_at is kept on update
+func UpsertMetadata(ctx context.Context, db *sql.DB, metadata *model.TokenMetadata) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameTokenMetadata).
+		Columns(metadataColumns...).
+		Values(
+			metadata.ChainID,
+			metadata.Token,
+			metadata.Name,
+			metadata.Symbol,
+			metadata.Decimals,
+			metadata.TotalSupply.String(),
+			metadata.CreatedAt,
+			metadata.UpdatedAt,
+		).
+		Suffix(`
+	ON DUPLICATE KEY UPDATE
+		name = VALUES(name),
+		symbol = VALUES(symbol),
+		decimals = VALUES(decimals),
+		total_supply = VALUES(total_supply),
+		updated_at = VALUES(updated_at)
+	`)
+
+	_, err := qb.RunWith(db).ExecContext(ctx)
+	return err
+}
+
+// UpdateTotalSupply refreshes the total supply of a token
+func UpdateTotalSupply(ctx context.Context, db *sql.DB, chainID int64, token string, supply *big.Int, now time.Time) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Update(model.TableNameTokenMetadata).
+		Set("total_supply", supply.String()).
+		Set("updated_at", now).
+		Where(
+			sq.Eq{"chain_id": chainID},
+			sq.Eq{"token": token},
+		)
+
+	_, err := qb.RunWith(db).ExecContext(ctx)
+	return err
+}
+
+// GetMetadata returns the cached metadata of the tokens, tokens not cached yet are left out
+func GetMetadata(ctx context.Context, db *sql.DB, filter *GetMetadataFilter) ([]*model.TokenMetadata, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(metadataColumns...).
+		From(model.TableNameTokenMetadata).
+		Where(filter.ToWhere()).
+		OrderBy("chain_id", "token")
+
+	rows, err := qb.RunWith(db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]*model.TokenMetadata, 0)
+	for rows.Next() {
+		v := new(model.TokenMetadata)
+		var supply string
+		if err := rows.Scan(
+			&v.ChainID,
+			&v.Token,
+			&v.Name,
+			&v.Symbol,
+			&v.Decimals,
+			&supply,
+			&v.CreatedAt,
+			&v.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		n, ok := new(big.Int).SetString(supply, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid total supply: %s", supply)
+		}
+		v.TotalSupply = n
+		res = append(res, v)
+	}
+
+	return res, rows.Err()
+}
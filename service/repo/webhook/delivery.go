@@ -0,0 +1,276 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/internal/enum"
+	"evm_event_indexer/service/model"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// TxInsertDelivery queues webhook deliveries
+func TxInsertDelivery(ctx context.Context, tx *sql.Tx, delivery ...*model.WebhookDelivery) error {
+	if len(delivery) == 0 {
+		return nil
+	}
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameWebhookDelivery).
+		Columns(
+			"webhook_id",
+			"type",
+			"chain_id",
+			"address",
+			"block_number",
+			"tx_index",
+			"log_index",
+			"payload",
+			"status",
+			"next_attempt_at",
+			"created_at",
+			"updated_at",
+		)
+
+	for _, v := range delivery {
+		qb = qb.Values(
+			v.WebhookID,
+			v.Type,
+			v.ChainID,
+			v.Address,
+			v.BlockNumber,
+			v.TxIndex,
+			v.LogIndex,
+			[]byte(v.Payload),
+			v.Status,
+			v.NextAttemptAt,
+			v.CreatedAt,
+			v.UpdatedAt,
+		)
+	}
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// TxRetractDelivery queues a removed message to every webhook that was delivered a log of the address after the block,
+// and cancels the pending deliveries of those logs. A pending delivery leased into the future may be in flight
+// or may have reached the receiver in a failed attempt, so it is retracted as well.
+func TxRetractDelivery(ctx context.Context, tx *sql.Tx, chainID int64, address string, fromBN uint64, payload []byte, now time.Time) error {
+	removed := sq.And{
+		sq.Eq{"type": model.WebhookEventLog},
+		sq.Eq{"chain_id": chainID},
+		sq.Eq{"address": address},
+		sq.Gt{"block_number": fromBN},
+	}
+
+	selected := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select("webhook_id").
+		Column("?", model.WebhookEventRemoved).
+		Column("?", chainID).
+		Column("?", address).
+		Column("?", fromBN).
+		Column("?", payload).
+		Column("?", enum.DeliveryStatusPending).
+		Column("?", now).
+		Column("?", now).
+		Column("?", now).
+		Distinct().
+		From(model.TableNameWebhookDelivery).
+		Where(removed).
+		Where(sq.Or{
+			sq.Eq{"status": enum.DeliveryStatusSucceeded},
+			sq.And{
+				sq.Eq{"status": enum.DeliveryStatusPending},
+				sq.Gt{"next_attempt_at": now},
+			},
+		})
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameWebhookDelivery).
+		Columns(
+			"webhook_id",
+			"type",
+			"chain_id",
+			"address",
+			"block_number",
+			"payload",
+			"status",
+			"next_attempt_at",
+			"created_at",
+			"updated_at",
+		).
+		Select(selected)
+
+	if _, err := qb.RunWith(tx).ExecContext(ctx); err != nil {
+		return err
+	}
+
+	cancel := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Update(model.TableNameWebhookDelivery).
+		Set("status", enum.DeliveryStatusCancelled).
+		Set("updated_at", now).
+		Where(removed).
+		Where(sq.Eq{"status": enum.DeliveryStatusPending})
+
+	_, err := cancel.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// TxClaimDelivery locks the due pending deliveries and leases them until the given time,
+// rows locked by another instance are skipped.
+func TxClaimDelivery(ctx context.Context, tx *sql.Tx, now time.Time, leaseUntil time.Time, limit uint64) ([]*model.WebhookDelivery, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(deliveryColumns...).
+		From(model.TableNameWebhookDelivery).
+		Where(sq.Eq{"status": enum.DeliveryStatusPending}).
+		Where(sq.LtOrEq{"next_attempt_at": now}).
+		OrderBy("next_attempt_at", "id").
+		Limit(limit).
+		Suffix("FOR UPDATE SKIP LOCKED")
+
+	rows, err := qb.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	ids := make([]int64, len(deliveries))
+	for i, v := range deliveries {
+		ids[i] = v.ID
+	}
+
+	lease := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Update(model.TableNameWebhookDelivery).
+		Set("next_attempt_at", leaseUntil).
+		Where(sq.Eq{"id": ids})
+
+	if _, err := lease.RunWith(tx).ExecContext(ctx); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// UpdateDeliveryResult saves the result of an attempt, a delivery cancelled meanwhile is left untouched
+func UpdateDeliveryResult(ctx context.Context, db *sql.DB, delivery *model.WebhookDelivery) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Update(model.TableNameWebhookDelivery).
+		Set("status", delivery.Status).
+		Set("attempts", delivery.Attempts).
+		Set("next_attempt_at", delivery.NextAttemptAt).
+		Set("last_status_code", delivery.LastStatusCode).
+		Set("last_error", delivery.LastError).
+		Set("updated_at", delivery.UpdatedAt).
+		Where(sq.Eq{"id": delivery.ID, "status": enum.DeliveryStatusPending})
+
+	_, err := qb.RunWith(db).ExecContext(ctx)
+	return err
+}
+
+type GetDeliveryFilter struct {
+	WebhookID  int64
+	Status     enum.DeliveryStatus
+	Pagination *model.Pagination
+}
+
+func (p GetDeliveryFilter) ToWhere() sq.And {
+	conds := sq.And{sq.Eq{"webhook_id": p.WebhookID}}
+	if p.Status != 0 {
+		conds = append(conds, sq.Eq{"status": p.Status})
+	}
+	return conds
+}
+
+func GetDeliveryTotal(ctx context.Context, db *sql.DB, filter *GetDeliveryFilter) (int64, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select("COUNT(*)").
+		From(model.TableNameWebhookDelivery).
+		Where(filter.ToWhere())
+
+	var total int64
+	if err := qb.RunWith(db).QueryRowContext(ctx).Scan(&total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// GetDeliveries lists the deliveries of a webhook, latest first
+func GetDeliveries(ctx context.Context, db *sql.DB, filter *GetDeliveryFilter) ([]*model.WebhookDelivery, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(deliveryColumns...).
+		From(model.TableNameWebhookDelivery).
+		Where(filter.ToWhere()).
+		OrderBy("id DESC")
+
+	if filter.Pagination != nil {
+		qb = qb.Offset(filter.Pagination.Offset()).Limit(filter.Pagination.Limit())
+	}
+
+	rows, err := qb.RunWith(db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanDeliveries(rows)
+}
+
+var deliveryColumns = []string{
+	"id",
+	"webhook_id",
+	"type",
+	"chain_id",
+	"address",
+	"block_number",
+	"tx_index",
+	"log_index",
+	"payload",
+	"status",
+	"attempts",
+	"next_attempt_at",
+	"last_status_code",
+	"last_error",
+	"created_at",
+	"updated_at",
+}
+
+func scanDeliveries(rows *sql.Rows) ([]*model.WebhookDelivery, error) {
+	defer rows.Close()
+
+	res := make([]*model.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery := new(model.WebhookDelivery)
+		if err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.Type,
+			&delivery.ChainID,
+			&delivery.Address,
+			&delivery.BlockNumber,
+			&delivery.TxIndex,
+			&delivery.LogIndex,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastStatusCode,
+			&delivery.LastError,
+			&delivery.CreatedAt,
+			&delivery.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		res = append(res, delivery)
+	}
+
+	return res, rows.Err()
+}
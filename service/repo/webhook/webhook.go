@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/internal/enum"
+	"evm_event_indexer/service/model"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// TxInsertWebhook inserts a webhook and returns its id
+func TxInsertWebhook(ctx context.Context, tx *sql.Tx, webhook *model.Webhook) (int64, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Insert(model.TableNameWebhook).
+		Columns(
+			"user_id",
+			"url",
+			"secret",
+			"filter",
+			"status",
+			"created_at",
+			"updated_at",
+		).
+		Values(
+			webhook.UserID,
+			webhook.URL,
+			webhook.Secret,
+			webhook.Filter,
+			webhook.Status,
+			webhook.CreatedAt,
+			webhook.UpdatedAt,
+		)
+
+	result, err := qb.RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// TxUpdateWebhook updates the non zero fields of a webhook of the user
+func TxUpdateWebhook(ctx context.Context, tx *sql.Tx, webhook *model.Webhook) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Update(model.TableNameWebhook).
+		Set("updated_at", webhook.UpdatedAt).
+		Where(sq.Eq{"id": webhook.ID, "user_id": webhook.UserID})
+
+	if webhook.URL != "" {
+		qb = qb.Set("url", webhook.URL)
+	}
+	if webhook.Secret != "" {
+		qb = qb.Set("secret", webhook.Secret)
+	}
+	if webhook.Filter != nil {
+		qb = qb.Set("filter", webhook.Filter)
+	}
+	if webhook.Status != 0 {
+		qb = qb.Set("status", webhook.Status)
+	}
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// TxDeleteWebhook deletes a webhook of the user, its deliveries are deleted by foreign key
+func TxDeleteWebhook(ctx context.Context, tx *sql.Tx, userID int64, id int64) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Delete(model.TableNameWebhook).
+		Where(sq.Eq{"id": id, "user_id": userID})
+
+	_, err := qb.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+type GetWebhookFilter struct {
+	IDs        []int64
+	UserID     int64
+	Status     enum.WebhookStatus
+	Pagination *model.Pagination
+}
+
+func (p GetWebhookFilter) ToWhere() sq.And {
+	var conds sq.And
+	if len(p.IDs) > 0 {
+		conds = append(conds, sq.Eq{"id": p.IDs})
+	}
+	if p.UserID != 0 {
+		conds = append(conds, sq.Eq{"user_id": p.UserID})
+	}
+	if p.Status != 0 {
+		conds = append(conds, sq.Eq{"status": p.Status})
+	}
+	return conds
+}
+
+func GetWebhookTotal(ctx context.Context, db *sql.DB, filter *GetWebhookFilter) (int64, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select("COUNT(*)").
+		From(model.TableNameWebhook).
+		Where(filter.ToWhere())
+
+	var total int64
+	if err := qb.RunWith(db).QueryRowContext(ctx).Scan(&total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func GetWebhooks(ctx context.Context, db *sql.DB, filter *GetWebhookFilter) ([]*model.Webhook, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question).
+		Select(
+			"id",
+			"user_id",
+			"url",
+			"secret",
+			"filter",
+			"status",
+			"created_at",
+			"updated_at",
+		).
+		From(model.TableNameWebhook).
+		Where(filter.ToWhere()).
+		OrderBy("id")
+
+	if filter.Pagination != nil {
+		qb = qb.Offset(filter.Pagination.Offset()).Limit(filter.Pagination.Limit())
+	}
+
+	rows, err := qb.RunWith(db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]*model.Webhook, 0)
+	for rows.Next() {
+		webhook := &model.Webhook{Filter: new(model.WebhookFilter)}
+		if err := rows.Scan(
+			&webhook.ID,
+			&webhook.UserID,
+			&webhook.URL,
+			&webhook.Secret,
+			webhook.Filter,
+			&webhook.Status,
+			&webhook.CreatedAt,
+			&webhook.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		res = append(res, webhook)
+	}
+
+	return res, nil
+}
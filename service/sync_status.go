@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/internal/workerstate"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/blocksync"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SyncStatus is the sync progress of the scanner of a contract
+type SyncStatus struct {
+	ChainID        int64
+	Chain          string // chain name, empty when the chain is no longer configured
+	Address        string
+	LastSyncNumber uint64 // 0 until the first batch is synced
+	LastSyncHash   string
+	UpdatedAt      time.Time
+	HeadNumber     uint64 // head of the block tag followed by the scanners of the chain, 0 until read
+	HeadUpdatedAt  time.Time
+	LagBlocks      *uint64 // head minus the last synced block, nil until the head is read
+	LagSeconds     *int64  // age of the last synced block, nil until this process syncs a batch
+	State          workerstate.State
+	LastError      string
+	LastErrorAt    time.Time
+}
+
+// GetSyncStatus lists the sync progress of every synced or configured contract ordered by chain and address,
+// chainID 0 means all chains. Heads and worker states are reported by the scanners running in this process.
+func GetSyncStatus(ctx context.Context, chainID int64, now time.Time) ([]*SyncStatus, error) {
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	syncs, err := blocksync.GetBlockSyncs(ctx, db, chainID)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get block sync")
+	}
+
+	res := make([]*SyncStatus, 0, len(syncs))
+	seen := make(map[string]bool, len(syncs))
+	for _, sync := range syncs {
+		seen[syncStatusKey(sync.ChainID, sync.Address)] = true
+		res = append(res, newSyncStatus(sync, now))
+	}
+
+	// configured contracts without a synced batch yet, e.g. a scanner failing from the start
+	for _, chain := range config.Get().Scanners {
+		if chainID != 0 && chain.ChainID != chainID {
+			continue
+		}
+		for _, address := range chain.Addresses {
+			if seen[syncStatusKey(chain.ChainID, address.Address)] {
+				continue
+			}
+			seen[syncStatusKey(chain.ChainID, address.Address)] = true
+			res = append(res, newSyncStatus(&model.BlockSync{ChainID: chain.ChainID, Address: address.Address}, now))
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].ChainID != res[j].ChainID {
+			return res[i].ChainID < res[j].ChainID
+		}
+		return strings.ToLower(res[i].Address) < strings.ToLower(res[j].Address)
+	})
+
+	return res, nil
+}
+
+func newSyncStatus(sync *model.BlockSync, now time.Time) *SyncStatus {
+	status := &SyncStatus{
+		ChainID:        sync.ChainID,
+		Address:        sync.Address,
+		LastSyncNumber: sync.LastSyncNumber,
+		LastSyncHash:   sync.LastSyncHash,
+		UpdatedAt:      sync.UpdatedAt,
+		State:          workerstate.StateInactive,
+	}
+
+	if chain, ok := config.Get().GetScanner(sync.ChainID); ok {
+		status.Chain = chain.String()
+	}
+
+	if head, ok := workerstate.Scanners.Head(sync.ChainID); ok {
+		lag := head.Number - min(head.Number, sync.LastSyncNumber)
+		status.HeadNumber = head.Number
+		status.HeadUpdatedAt = head.UpdatedAt
+		status.LagBlocks = &lag
+	}
+
+	if scanner, ok := workerstate.Scanners.Scanner(sync.ChainID, sync.Address); ok {
+		status.State = scanner.State
+		status.LastError = scanner.LastError
+		status.LastErrorAt = scanner.LastErrorAt
+
+		if !scanner.SyncedTime.IsZero() {
+			lag := max(0, int64(now.Sub(scanner.SyncedTime).Seconds()))
+			status.LagSeconds = &lag
+		}
+	}
+
+	return status
+}
+
+func syncStatusKey(chainID int64, address string) string {
+	return fmt.Sprintf("%d:%s", chainID, strings.ToLower(address))
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/tokenallowance"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ERC-20 Approval, ERC-721 shares the signature with the token id indexed as topic3
+var approvalTopic = crypto.Keccak256Hash([]byte("Approval(address,address,uint256)")).Hex()
+
+// tokenApproval is an ERC-20 Approval log with its decoded arguments
+type tokenApproval struct {
+	log     *model.Log
+	owner   string
+	spender string
+	value   *big.Int
+}
+
+// newTokenApprovals collects the ERC-20 approvals of the logs in chain order,
+// logs without the decoded owner, spender and value arguments are skipped.
+func newTokenApprovals(logs []*model.Log) ([]*tokenApproval, error) {
+	res := make([]*tokenApproval, 0)
+	for _, log := range logs {
+		if !strings.EqualFold(log.Topic0, approvalTopic) || log.Topic3 != "" {
+			continue
+		}
+
+		args := make(map[string]string, len(log.Args))
+		for _, arg := range log.Args {
+			args[arg.Name] = arg.Value
+		}
+
+		owner, spender, value := args["owner"], args["spender"], args["value"]
+		if owner == "" || spender == "" || value == "" {
+			continue
+		}
+
+		n, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid approval value %s at block %d log %d", value, log.BlockNumber, log.LogIndex)
+		}
+
+		res = append(res, &tokenApproval{log: log, owner: owner, spender: spender, value: n})
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return logBefore(res[i].log, res[j].log)
+	})
+
+	return res, nil
+}
+
+// txUpdateTokenAllowance reverts the allowances of the token approved from the given block number, then applies the approvals.
+// The logs of the scanned range must be replaced before, allowances are restored from the remaining Approval logs.
+func txUpdateTokenAllowance(ctx context.Context, tx *sql.Tx, chainID int64, token string, fromBN uint64, approvals []*tokenApproval, now time.Time) error {
+	if err := txRevertTokenAllowance(ctx, tx, chainID, token, fromBN, now); err != nil {
+		return fmt.Errorf("failed to revert token allowance: %w", err)
+	}
+
+	if err := txApplyTokenAllowance(ctx, tx, chainID, token, approvals, now); err != nil {
+		return fmt.Errorf("failed to apply token allowance: %w", err)
+	}
+
+	return nil
+}
+
+// txRevertTokenAllowance restores the allowances of the token approved from the given block number
+// to the last Approval log before it, allowances without an earlier approval are deleted.
+// The Approval logs are the history of the allowances, so they must be deleted before.
+func txRevertTokenAllowance(ctx context.Context, tx *sql.Tx, chainID int64, token string, fromBN uint64, now time.Time) error {
+	reverted, err := tokenallowance.TxGetAllowanceFrom(ctx, tx, chainID, token, fromBN)
+	if err != nil {
+		return err
+	}
+
+	if len(reverted) == 0 {
+		return nil
+	}
+
+	latest, err := tokenallowance.TxGetLatestApproval(ctx, tx, chainID, token, approvalTopic, fromBN, reverted)
+	if err != nil {
+		return err
+	}
+
+	restored := make([]*model.TokenAllowance, 0, len(latest))
+	removed := make([]*model.TokenAllowance, 0)
+	for _, v := range reverted {
+		last, ok := latest[tokenallowance.PairKey(v.Owner, v.Spender)]
+		if !ok {
+			removed = append(removed, v)
+			continue
+		}
+
+		last.UpdatedAt = now
+		restored = append(restored, last)
+	}
+
+	if err := tokenallowance.TxDeleteAllowance(ctx, tx, chainID, token, removed); err != nil {
+		return err
+	}
+
+	return tokenallowance.TxUpsertAllowance(ctx, tx, restored...)
+}
+
+// txApplyTokenAllowance sets the allowance of each pair to its latest approval.
+// Tokens that emit Approval on transferFrom (e.g. OpenZeppelin before 5.0) also report the consumed allowance this way,
+// otherwise the consumption is not derivable since the spender of a transferFrom is not in the logs.
+func txApplyTokenAllowance(ctx context.Context, tx *sql.Tx, chainID int64, token string, approvals []*tokenApproval, now time.Time) error {
+	if len(approvals) == 0 {
+		return nil
+	}
+
+	// approvals are in chain order, the last one of a pair wins
+	pairs := make([]string, 0)
+	latest := make(map[string]*model.TokenAllowance)
+	for _, a := range approvals {
+		key := tokenallowance.PairKey(a.owner, a.spender)
+		if _, ok := latest[key]; !ok {
+			pairs = append(pairs, key)
+		}
+
+		latest[key] = &model.TokenAllowance{
+			ChainID:     chainID,
+			Token:       token,
+			Owner:       a.owner,
+			Spender:     a.spender,
+			Allowance:   a.value,
+			BlockNumber: a.log.BlockNumber,
+			TxHash:      a.log.TxHash,
+			UpdatedAt:   now,
+		}
+	}
+
+	allowances := make([]*model.TokenAllowance, 0, len(pairs))
+	for _, key := range pairs {
+		allowances = append(allowances, latest[key])
+	}
+
+	return tokenallowance.TxUpsertAllowance(ctx, tx, allowances...)
+}
+
+// GetTokenAllowancesWithTotal returns the outstanding allowances of an owner, latest approval first
+func GetTokenAllowancesWithTotal(ctx context.Context, filter *tokenallowance.GetAllowanceFilter) ([]*model.TokenAllowance, int64, error) {
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	total, err := tokenallowance.GetAllowanceTotal(ctx, db, filter)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get allowance total")
+	}
+
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	allowances, err := tokenallowance.GetAllowances(ctx, db, filter)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get allowances")
+	}
+
+	return allowances, total, nil
+}
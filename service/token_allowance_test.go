@@ -0,0 +1,80 @@
+package service_test
+
+import (
+	"database/sql"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/service/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertAllowance checks the allowance of an owner and spender pair including revoked ones,
+// an empty allowance means the pair has no row
+func assertAllowance(t *testing.T, token string, owner string, spender string, allowance string, blockNumber uint64) {
+	t.Helper()
+
+	db, err := storage.GetMySQL(config.EventDBM)
+	require.NoError(t, err)
+
+	var (
+		value string
+		bn    uint64
+	)
+	err = db.QueryRowContext(ctx,
+		"SELECT allowance, block_number FROM "+model.TableNameTokenAllowance+" WHERE chain_id = ? AND token = ? AND owner = ? AND spender = ?",
+		chainID, token, owner, spender,
+	).Scan(&value, &bn)
+
+	if allowance == "" {
+		assert.ErrorIs(t, err, sql.ErrNoRows, spender)
+		return
+	}
+
+	require.NoError(t, err)
+	n, err := model.DecodeBalance(value)
+	require.NoError(t, err)
+	assert.Equal(t, allowance, n.String(), spender)
+	assert.Equal(t, blockNumber, bn, spender)
+}
+
+func Test_TokenAllowance_Reorg(t *testing.T) {
+	token := newContract(t)
+	owner, s1, s2 := newHolder(1), newHolder(2), newHolder(3)
+
+	// approve, re-approve, approve another spender and revoke
+	scan(t, token, 1, 4,
+		newEventLog(t, token, approvalTopic, 1, 0, owner, s1, 100),
+		newEventLog(t, token, approvalTopic, 2, 0, owner, s1, 50),
+		newEventLog(t, token, approvalTopic, 3, 0, owner, s2, 7),
+		newEventLog(t, token, approvalTopic, 4, 0, owner, s1, 0),
+	)
+
+	// revoked allowances are kept with zero
+	assertAllowance(t, token, owner, s1, "0", 4)
+	assertAllowance(t, token, owner, s2, "7", 3)
+
+	// a rescan overlapping the synced range replaces its approvals
+	scan(t, token, 4, 5,
+		newEventLog(t, token, approvalTopic, 4, 0, owner, s1, 0),
+		newEventLog(t, token, approvalTopic, 5, 0, owner, s2, 9),
+	)
+
+	assertAllowance(t, token, owner, s1, "0", 4)
+	assertAllowance(t, token, owner, s2, "9", 5)
+
+	// the reorg restores the allowances from the latest remaining Approval log,
+	// a pair approved only after the checkpoint is removed
+	reorg(t, token, 2)
+
+	assertAllowance(t, token, owner, s1, "50", 2)
+	assertAllowance(t, token, owner, s2, "", 0)
+
+	// rolling back past the first approval removes every pair
+	reorg(t, token, 0)
+
+	assertAllowance(t, token, owner, s1, "", 0)
+	assertAllowance(t, token, owner, s2, "", 0)
+}
@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/blocksync"
+	"evm_event_indexer/service/repo/tokenbalance"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// ERC-20 Transfer, ERC-721 shares the signature with the token id indexed as topic3
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")).Hex()
+	// mints are sent from and burns are sent to the zero address, which has no balance
+	zeroAddress = strings.ToLower(common.Address{}.Hex())
+)
+
+// tokenTransfer is an ERC-20 Transfer log with its decoded arguments
+type tokenTransfer struct {
+	log   *model.Log
+	from  string
+	to    string
+	value *big.Int
+}
+
+// newTokenTransfers collects the ERC-20 transfers of the logs in chain order,
+// logs without the decoded from, to and value arguments are skipped.
+func newTokenTransfers(logs []*model.Log) ([]*tokenTransfer, error) {
+	res := make([]*tokenTransfer, 0)
+	for _, log := range logs {
+		if !strings.EqualFold(log.Topic0, transferTopic) || log.Topic3 != "" {
+			continue
+		}
+
+		args := make(map[string]string, len(log.Args))
+		for _, arg := range log.Args {
+			args[arg.Name] = arg.Value
+		}
+
+		from, to, value := args["from"], args["to"], args["value"]
+		if from == "" || to == "" || value == "" {
+			continue
+		}
+
+		n, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid transfer value %s at block %d log %d", value, log.BlockNumber, log.LogIndex)
+		}
+
+		res = append(res, &tokenTransfer{log: log, from: from, to: to, value: n})
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return logBefore(res[i].log, res[j].log)
+	})
+
+	return res, nil
+}
+
+// logBefore reports whether a log comes before another in chain order
+func logBefore(a, b *model.Log) bool {
+	if a.BlockNumber != b.BlockNumber {
+		return a.BlockNumber < b.BlockNumber
+	}
+	if a.TxIndex != b.TxIndex {
+		return a.TxIndex < b.TxIndex
+	}
+	return a.LogIndex < b.LogIndex
+}
+
+// txUpdateTokenBalance reverts the balance changes of the token from the given block number, then applies the transfers
+func txUpdateTokenBalance(ctx context.Context, tx *sql.Tx, chainID int64, token string, fromBN uint64, transfers []*tokenTransfer, now time.Time) error {
+	if err := txRevertTokenBalance(ctx, tx, chainID, token, fromBN, now); err != nil {
+		return fmt.Errorf("failed to revert token balance: %w", err)
+	}
+
+	if err := txApplyTokenBalance(ctx, tx, chainID, token, transfers, now); err != nil {
+		return fmt.Errorf("failed to apply token balance: %w", err)
+	}
+
+	return nil
+}
+
+// txRevertTokenBalance deletes the balance changes of the token from the given block number,
+// and restores the balance of each affected holder from its last remaining change.
+func txRevertTokenBalance(ctx context.Context, tx *sql.Tx, chainID int64, token string, fromBN uint64, now time.Time) error {
+	holders, err := tokenbalance.TxGetHistoryHolders(ctx, tx, chainID, token, fromBN)
+	if err != nil {
+		return err
+	}
+
+	if len(holders) == 0 {
+		return nil
+	}
+
+	if err := tokenbalance.TxDeleteHistory(ctx, tx, chainID, token, fromBN); err != nil {
+		return err
+	}
+
+	latest, err := tokenbalance.TxGetLatestHistory(ctx, tx, chainID, token, holders)
+	if err != nil {
+		return err
+	}
+
+	balances := make([]*model.TokenBalance, 0, len(latest))
+	removed := make([]string, 0)
+	for _, holder := range holders {
+		last, ok := latest[holder]
+		if !ok {
+			removed = append(removed, holder)
+			continue
+		}
+
+		balances = append(balances, &model.TokenBalance{
+			ChainID:     chainID,
+			Token:       token,
+			Holder:      holder,
+			Balance:     last.Balance,
+			BlockNumber: last.BlockNumber,
+			UpdatedAt:   now,
+		})
+	}
+
+	if err := tokenbalance.TxDeleteBalance(ctx, tx, chainID, token, removed); err != nil {
+		return err
+	}
+
+	return tokenbalance.TxUpsertBalance(ctx, tx, balances...)
+}
+
+// txApplyTokenBalance records a balance change of the sender and the receiver of each transfer
+func txApplyTokenBalance(ctx context.Context, tx *sql.Tx, chainID int64, token string, transfers []*tokenTransfer, now time.Time) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+
+	holders := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, t := range transfers {
+		for _, holder := range []string{t.from, t.to} {
+			if _, ok := seen[holder]; ok || holder == zeroAddress {
+				continue
+			}
+			seen[holder] = struct{}{}
+			holders = append(holders, holder)
+		}
+	}
+
+	current, err := tokenbalance.TxGetBalanceForUpdate(ctx, tx, chainID, token, holders)
+	if err != nil {
+		return err
+	}
+
+	balances := make(map[string]*model.TokenBalance, len(holders))
+	for _, holder := range holders {
+		balance, ok := current[holder]
+		if !ok {
+			balance = new(big.Int)
+		}
+		balances[holder] = &model.TokenBalance{ChainID: chainID, Token: token, Holder: holder, Balance: balance, UpdatedAt: now}
+	}
+
+	history := make([]*model.TokenBalanceHistory, 0, len(transfers)*2)
+	change := func(t *tokenTransfer, holder string, delta *big.Int) {
+		if holder == zeroAddress {
+			return
+		}
+
+		b := balances[holder]
+		b.Balance = new(big.Int).Add(b.Balance, delta)
+		b.BlockNumber = t.log.BlockNumber
+
+		history = append(history, &model.TokenBalanceHistory{
+			ChainID:     chainID,
+			Token:       token,
+			Holder:      holder,
+			BlockNumber: t.log.BlockNumber,
+			TxIndex:     t.log.TxIndex,
+			LogIndex:    t.log.LogIndex,
+			Delta:       delta,
+			Balance:     b.Balance,
+			CreatedAt:   now,
+		})
+	}
+
+	for _, t := range transfers {
+		// a transfer to oneself does not change the balance
+		if t.from == t.to {
+			continue
+		}
+		change(t, t.from, new(big.Int).Neg(t.value))
+		change(t, t.to, t.value)
+	}
+
+	if err := tokenbalance.TxInsertHistory(ctx, tx, history...); err != nil {
+		return err
+	}
+
+	changed := make([]*model.TokenBalance, 0, len(balances))
+	for _, holder := range holders {
+		if b := balances[holder]; b.BlockNumber > 0 {
+			changed = append(changed, b)
+		}
+	}
+
+	return tokenbalance.TxUpsertBalance(ctx, tx, changed...)
+}
+
+// getTokenSync returns the sync state of a configured token address
+func getTokenSync(ctx context.Context, db *sql.DB, chainID int64, token string) (*model.BlockSync, error) {
+	bs, err := blocksync.GetBlockSync(ctx, db, chainID, token)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get block sync")
+	}
+
+	if bs == nil {
+		return nil, errors.ErrTokenNotIndexed.New(fmt.Sprintf("token %s is not indexed on chain %d", token, chainID))
+	}
+
+	return bs, nil
+}
+
+// GetTokenBalance returns the current balance of a holder, the balance is zero if the holder never held the token.
+// The synced block number of the token is returned with it.
+func GetTokenBalance(ctx context.Context, chainID int64, token string, holder string) (*model.TokenBalance, uint64, error) {
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	bs, err := getTokenSync(ctx, db, chainID, token)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	balance, err := tokenbalance.GetBalance(ctx, db, chainID, token, holder)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get token balance")
+	}
+
+	if balance == nil {
+		balance = &model.TokenBalance{ChainID: chainID, Token: token, Holder: holder, Balance: new(big.Int)}
+	}
+
+	return balance, bs.LastSyncNumber, nil
+}
+
+// GetTokenBalanceAt returns the balance of a holder at the end of the block, the block must be synced.
+func GetTokenBalanceAt(ctx context.Context, chainID int64, token string, holder string, blockNumber uint64) (*model.TokenBalance, uint64, error) {
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	bs, err := getTokenSync(ctx, db, chainID, token)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if blockNumber > bs.LastSyncNumber {
+		return nil, 0, errors.ErrApiInvalidParam.New(fmt.Sprintf("block %d is not synced yet, last synced block is %d", blockNumber, bs.LastSyncNumber))
+	}
+
+	last, err := tokenbalance.GetBalanceAt(ctx, db, chainID, token, holder, blockNumber)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get token balance history")
+	}
+
+	balance := &model.TokenBalance{ChainID: chainID, Token: token, Holder: holder, Balance: new(big.Int)}
+	if last != nil {
+		balance.Balance = last.Balance
+		balance.BlockNumber = last.BlockNumber
+	}
+
+	return balance, bs.LastSyncNumber, nil
+}
+
+// GetTokenHoldersWithTotal returns the holders of a token with a positive balance, largest first
+func GetTokenHoldersWithTotal(ctx context.Context, filter *tokenbalance.GetHolderFilter) ([]*model.TokenBalance, int64, error) {
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	if _, err := getTokenSync(ctx, db, filter.ChainID, filter.Token); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := tokenbalance.GetHolderTotal(ctx, db, filter)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get holder total")
+	}
+
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	holders, err := tokenbalance.GetHolders(ctx, db, filter)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get holders")
+	}
+
+	return holders, total, nil
+}
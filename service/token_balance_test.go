@@ -0,0 +1,107 @@
+package service_test
+
+import (
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/tokenbalance"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertBalance checks the current balance of a holder, an empty balance means the holder has no row
+func assertBalance(t *testing.T, token string, holder string, balance string, blockNumber uint64) {
+	t.Helper()
+
+	db, err := storage.GetMySQL(config.EventDBM)
+	require.NoError(t, err)
+
+	b, err := tokenbalance.GetBalance(ctx, db, chainID, token, holder)
+	require.NoError(t, err)
+
+	if balance == "" {
+		assert.Nil(t, b, holder)
+		return
+	}
+
+	if assert.NotNil(t, b, holder) {
+		assert.Equal(t, balance, b.Balance.String(), holder)
+		assert.Equal(t, blockNumber, b.BlockNumber, holder)
+	}
+}
+
+// countHistory returns the number of balance changes of a token
+func countHistory(t *testing.T, token string) int {
+	t.Helper()
+
+	db, err := storage.GetMySQL(config.EventDBM)
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM "+model.TableNameTokenBalanceHistory+" WHERE chain_id = ? AND token = ?",
+		chainID, token,
+	).Scan(&count))
+
+	return count
+}
+
+func Test_TokenBalance_Reorg(t *testing.T) {
+	token := newContract(t)
+	zero := common.Address{}.Hex()
+	a, b, c := newHolder(1), newHolder(2), newHolder(3)
+
+	// mint, transfer, self-transfer and burn
+	scan(t, token, 1, 4,
+		newEventLog(t, token, transferTopic, 1, 0, zero, a, 100),
+		newEventLog(t, token, transferTopic, 2, 0, a, b, 30),
+		newEventLog(t, token, transferTopic, 3, 0, b, b, 10),
+		newEventLog(t, token, transferTopic, 4, 0, a, zero, 20),
+	)
+
+	assertBalance(t, token, a, "50", 4)
+	assertBalance(t, token, b, "30", 2)
+	// the zero address of mints and burns has no balance, a self-transfer is not a change
+	assertBalance(t, token, zero, "", 0)
+	assert.Equal(t, 4, countHistory(t, token))
+
+	// a rescan overlapping the synced range replaces its changes instead of applying them twice
+	scan(t, token, 3, 5,
+		newEventLog(t, token, transferTopic, 3, 0, b, b, 10),
+		newEventLog(t, token, transferTopic, 4, 0, a, zero, 20),
+		newEventLog(t, token, transferTopic, 5, 0, b, c, 5),
+	)
+
+	assertBalance(t, token, a, "50", 4)
+	assertBalance(t, token, b, "25", 5)
+	assertBalance(t, token, c, "5", 5)
+	assert.Equal(t, 6, countHistory(t, token))
+
+	// the reorg reverts the changes after the checkpoint and restores the balances from the remaining history
+	reorg(t, token, 2)
+
+	assertBalance(t, token, a, "70", 2)
+	assertBalance(t, token, b, "30", 2)
+	assertBalance(t, token, c, "", 0)
+	assert.Equal(t, 3, countHistory(t, token))
+
+	db, err := storage.GetMySQL(config.EventDBM)
+	require.NoError(t, err)
+
+	last, err := tokenbalance.GetBalanceAt(ctx, db, chainID, token, a, 5)
+	require.NoError(t, err)
+	if assert.NotNil(t, last) {
+		assert.Equal(t, "70", last.Balance.String())
+		assert.Equal(t, uint64(2), last.BlockNumber)
+	}
+
+	// rolling back past the mint removes every holder
+	reorg(t, token, 0)
+
+	assertBalance(t, token, a, "", 0)
+	assertBalance(t, token, b, "", 0)
+	assert.Equal(t, 0, countHistory(t, token))
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/tokenmetadata"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// column limits of the token name and symbol
+const (
+	maxTokenNameLength   = 255
+	maxTokenSymbolLength = 64
+)
+
+// GetChainTokenMetadata returns the cached metadata of the tokens of a chain, keyed by model.NewTokenKey
+func GetChainTokenMetadata(ctx context.Context, chainID int64) (map[string]*model.TokenMetadata, error) {
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	metadata, err := tokenmetadata.GetMetadata(ctx, db, &tokenmetadata.GetMetadataFilter{ChainID: chainID})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]*model.TokenMetadata, len(metadata))
+	for _, v := range metadata {
+		res[model.NewTokenKey(v.ChainID, v.Token)] = v
+	}
+
+	return res, nil
+}
+
+// SaveTokenMetadata caches the metadata of a token, name and symbol are truncated to fit the columns
+func SaveTokenMetadata(ctx context.Context, metadata *model.TokenMetadata) error {
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	metadata.Name = truncate(metadata.Name, maxTokenNameLength)
+	metadata.Symbol = truncate(metadata.Symbol, maxTokenSymbolLength)
+
+	return tokenmetadata.UpsertMetadata(ctx, db, metadata)
+}
+
+// UpdateTokenSupply refreshes the cached total supply of a token
+func UpdateTokenSupply(ctx context.Context, chainID int64, token string, supply *big.Int, now time.Time) error {
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	return tokenmetadata.UpdateTotalSupply(ctx, db, chainID, token, supply, now)
+}
+
+// GetTokenMetadata returns the cached metadata of a token
+func GetTokenMetadata(ctx context.Context, chainID int64, token string) (*model.TokenMetadata, error) {
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	metadata, err := tokenmetadata.GetMetadata(ctx, db, &tokenmetadata.GetMetadataFilter{
+		ChainID: chainID,
+		Tokens:  []string{token},
+	})
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get token metadata")
+	}
+
+	if len(metadata) == 0 {
+		return nil, errors.ErrTokenMetadataNotFound.New()
+	}
+
+	return metadata[0], nil
+}
+
+// GetLogTokenMetadata returns the cached metadata of the emitting contracts of the logs, keyed by model.NewTokenKey,
+// contracts without metadata are left out
+func GetLogTokenMetadata(ctx context.Context, logs []*model.Log) (map[string]*model.TokenMetadata, error) {
+	res := make(map[string]*model.TokenMetadata)
+	if len(logs) == 0 {
+		return res, nil
+	}
+
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	// logs of a page rarely span many chains, query the tokens of each chain
+	tokens := make(map[int64][]string)
+	seen := make(map[string]bool)
+	for _, log := range logs {
+		key := model.NewTokenKey(log.ChainID, log.Address)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		tokens[log.ChainID] = append(tokens[log.ChainID], log.Address)
+	}
+
+	for chainID, addresses := range tokens {
+		metadata, err := tokenmetadata.GetMetadata(ctx, db, &tokenmetadata.GetMetadataFilter{
+			ChainID: chainID,
+			Tokens:  addresses,
+		})
+		if err != nil {
+			return nil, errors.ErrInternalServerError.Wrap(err, "failed to get token metadata")
+		}
+
+		for _, v := range metadata {
+			res[model.NewTokenKey(v.ChainID, v.Token)] = v
+		}
+	}
+
+	return res, nil
+}
+
+// truncate drops invalid utf-8 and cuts a string to at most n characters, contracts may return arbitrary bytes
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/tokenbalance"
+	"fmt"
+	"math/big"
+)
+
+// GetReconcileHolders samples the holders of a token to compare with the chain
+func GetReconcileHolders(ctx context.Context, chainID int64, token string, size uint64) ([]string, error) {
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	return tokenbalance.GetSampleHolders(ctx, db, chainID, token, size)
+}
+
+// GetLedgerBalanceAt returns the balance of a holder in the ledger at the end of the block
+func GetLedgerBalanceAt(ctx context.Context, chainID int64, token string, holder string, blockNumber uint64) (*big.Int, error) {
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	last, err := tokenbalance.GetBalanceAt(ctx, db, chainID, token, holder, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if last == nil {
+		return new(big.Int), nil
+	}
+
+	return last.Balance, nil
+}
+
+// SaveReconcileResults saves the latest reconciliation result of each holder
+func SaveReconcileResults(ctx context.Context, results ...*model.TokenReconcile) error {
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	return tokenbalance.UpsertReconcile(ctx, db, results...)
+}
+
+// GetReconcilesWithTotal returns the reconciliation results for the admin report
+func GetReconcilesWithTotal(ctx context.Context, filter *tokenbalance.GetReconcileFilter) ([]*model.TokenReconcile, int64, error) {
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	total, err := tokenbalance.GetReconcileTotal(ctx, db, filter)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get reconcile total")
+	}
+
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	results, err := tokenbalance.GetReconciles(ctx, db, filter)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get reconciles")
+	}
+
+	return results, total, nil
+}
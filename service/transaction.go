@@ -3,13 +3,18 @@ package service
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"evm_event_indexer/internal/config"
 	"evm_event_indexer/internal/errors"
 	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/internal/stream"
 	"evm_event_indexer/internal/tools"
 	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/blockheader"
 	"evm_event_indexer/service/repo/blocksync"
 	"evm_event_indexer/service/repo/eventlog"
+	outboxRepo "evm_event_indexer/service/repo/outbox"
+	webhookRepo "evm_event_indexer/service/repo/webhook"
 	"evm_event_indexer/utils"
 	"fmt"
 	"time"
@@ -18,10 +23,12 @@ import (
 type UpsertLogParam struct {
 	ChainID        int64
 	Address        string
+	FromBlock      uint64 // first block of the scanned range
 	LastSyncNumber uint64
 	LastSyncHash   string
 	Now            time.Time
 	Logs           []*model.Log
+	Headers        []*model.BlockHeader // block headers with L1 origin, only for L2 chains
 }
 
 // UpsertLog upserts event logs and block sync info into database.
@@ -44,12 +51,47 @@ func UpsertLog(ctx context.Context, params *UpsertLogParam) error {
 	if params.LastSyncHash == "" {
 		return fmt.Errorf("last sync hash is empty")
 	}
+	if params.FromBlock > params.LastSyncNumber {
+		return fmt.Errorf("from block %d is greater than last sync number %d", params.FromBlock, params.LastSyncNumber)
+	}
 
 	db, err := storage.GetMySQL(config.EventDBM)
 	if err != nil {
 		return fmt.Errorf("failed to get mysql: %w", err)
 	}
 
+	// confirmed logs are queued to the matching webhooks in the same tx
+	webhooks, err := getEnabledWebhooks(ctx, db, params.ChainID)
+	if err != nil {
+		return fmt.Errorf("failed to get webhooks: %w", err)
+	}
+
+	deliveries, err := newWebhookDeliveries(webhooks, params.Logs, params.Now)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook deliveries: %w", err)
+	}
+
+	// confirmed logs are written to the outbox in the same tx
+	outboxEnabled := config.Get().Outbox.Enabled
+	var messages []*model.Outbox
+	if outboxEnabled {
+		if messages, err = newLogOutbox(params.Logs, params.Now); err != nil {
+			return fmt.Errorf("failed to create outbox messages: %w", err)
+		}
+	}
+
+	// token balances are derived from the transfers in the same tx
+	transfers, err := newTokenTransfers(params.Logs)
+	if err != nil {
+		return fmt.Errorf("failed to collect token transfers: %w", err)
+	}
+
+	// token allowances are derived from the approvals in the same tx
+	approvals, err := newTokenApprovals(params.Logs)
+	if err != nil {
+		return fmt.Errorf("failed to collect token approvals: %w", err)
+	}
+
 	start := time.Now()
 	defer tools.ObserveDBWrite("upsert_log", start, err)
 	if err = utils.NewTx(db).Exec(ctx,
@@ -63,9 +105,25 @@ func UpsertLog(ctx context.Context, params *UpsertLogParam) error {
 				UpdatedAt:      params.Now,
 			})
 		},
-		// delete the logs after the last sync number
+		// tombstone the confirmed logs replaced by the scanned range before they are deleted,
+		// a rescanned log gets a tombstone followed by its new message
 		func(ctx context.Context, tx *sql.Tx) error {
-			return eventlog.TxDeleteLog(ctx, tx, params.Address, params.LastSyncNumber)
+			if !outboxEnabled {
+				return nil
+			}
+			return outboxRepo.TxInsertTombstone(ctx, tx, params.ChainID, params.Address, params.FromBlock, params.Now)
+		},
+		// replace the confirmed logs of the scanned range in the rollups before they are deleted
+		func(ctx context.Context, tx *sql.Tx) error {
+			return txUpdateEventRollup(ctx, tx, params.ChainID, params.Address, params.FromBlock, params.Logs, params.Now)
+		},
+		// delete the confirmed logs after the last sync number
+		func(ctx context.Context, tx *sql.Tx) error {
+			return eventlog.TxDeleteConfirmedLog(ctx, tx, params.ChainID, params.Address, params.LastSyncNumber)
+		},
+		// delete the logs in the scanned range, unconfirmed logs pushed by the live subscription are replaced by the scanned ones
+		func(ctx context.Context, tx *sql.Tx) error {
+			return eventlog.TxDeleteLogRange(ctx, tx, params.ChainID, params.Address, params.FromBlock, params.LastSyncNumber)
 		},
 		// upsert the logs
 		func(ctx context.Context, tx *sql.Tx) error {
@@ -74,12 +132,119 @@ func UpsertLog(ctx context.Context, params *UpsertLogParam) error {
 			}
 			return eventlog.TxInsertLog(ctx, tx, params.Logs...)
 		},
+		// insert the queryable decoded arguments, after the logs they reference
+		func(ctx context.Context, tx *sql.Tx) error {
+			return eventlog.TxInsertLogArg(ctx, tx, logArgs(params.Logs)...)
+		},
+		// insert the participants of the logs, after the logs they reference
+		func(ctx context.Context, tx *sql.Tx) error {
+			return eventlog.TxInsertLogParticipant(ctx, tx, logParticipants(params.Logs)...)
+		},
+		// insert the block headers
+		func(ctx context.Context, tx *sql.Tx) error {
+			return blockheader.TxInsertBlockHeader(ctx, tx, params.Headers...)
+		},
+		// replace the balance changes of the scanned range
+		func(ctx context.Context, tx *sql.Tx) error {
+			return txUpdateTokenBalance(ctx, tx, params.ChainID, params.Address, params.FromBlock, transfers, params.Now)
+		},
+		// replace the allowances approved in the scanned range, after the logs they are restored from
+		func(ctx context.Context, tx *sql.Tx) error {
+			return txUpdateTokenAllowance(ctx, tx, params.ChainID, params.Address, params.FromBlock, approvals, params.Now)
+		},
+		// queue the webhook deliveries
+		func(ctx context.Context, tx *sql.Tx) error {
+			return webhookRepo.TxInsertDelivery(ctx, tx, deliveries...)
+		},
+		// write the outbox messages
+		func(ctx context.Context, tx *sql.Tx) error {
+			return outboxRepo.TxInsertOutbox(ctx, tx, messages...)
+		},
 	); err != nil {
 		return fmt.Errorf("upsert log error for address %s: %w", params.Address, err)
 	}
+
+	// notify the api streams once the logs are committed
+	stream.Logs.Publish(stream.NewLogEvents(params.Logs)...)
 	return nil
 }
 
+type InsertLiveLogParam struct {
+	ChainID int64
+	Address string
+	Logs    []*model.Log
+}
+
+// InsertLiveLog inserts unconfirmed event logs pushed by the live subscription.
+// Logs of blocks already synced by the scanner are skipped, the scanner confirms the rest when it reaches them.
+func InsertLiveLog(ctx context.Context, params *InsertLiveLogParam) error {
+	if params == nil {
+		return fmt.Errorf("params is nil")
+	}
+	if params.ChainID == 0 {
+		return fmt.Errorf("chain id is 0")
+	}
+	if params.Address == "" {
+		return fmt.Errorf("address is empty")
+	}
+
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	start := time.Now()
+	defer func() { tools.ObserveDBWrite("insert_live_log", start, err) }()
+
+	// the synced block number is read in the insert tx and locked until it commits,
+	// otherwise a scan committed in between could have its confirmed logs shadowed by unconfirmed ones
+	logs := make([]*model.Log, 0, len(params.Logs))
+	if err = utils.NewTx(db).Exec(ctx,
+		func(ctx context.Context, tx *sql.Tx) error {
+			bs, err := blocksync.TxGetBlockSyncForShare(ctx, tx, params.ChainID, params.Address)
+			if err != nil {
+				return fmt.Errorf("failed to get block sync: %w", err)
+			}
+
+			for _, log := range params.Logs {
+				if bs != nil && log.BlockNumber <= bs.LastSyncNumber {
+					continue
+				}
+				log.Confirmed = false
+				logs = append(logs, log)
+			}
+			return nil
+		},
+		func(ctx context.Context, tx *sql.Tx) error {
+			return eventlog.TxInsertLogIgnore(ctx, tx, logs...)
+		},
+		func(ctx context.Context, tx *sql.Tx) error {
+			return eventlog.TxInsertLogArg(ctx, tx, logArgs(logs)...)
+		},
+		func(ctx context.Context, tx *sql.Tx) error {
+			return eventlog.TxInsertLogParticipant(ctx, tx, logParticipants(logs)...)
+		},
+	); err != nil {
+		return fmt.Errorf("insert live log error for address %s: %w", params.Address, err)
+	}
+
+	if len(logs) == 0 {
+		return nil
+	}
+
+	stream.Logs.Publish(stream.NewLogEvents(logs)...)
+	return nil
+}
+
+// logArgs collects the queryable decoded arguments of the logs
+func logArgs(logs []*model.Log) []*model.EventArg {
+	args := make([]*model.EventArg, 0)
+	for _, log := range logs {
+		args = append(args, log.Args...)
+	}
+	return args
+}
+
 type ReorgLogParam struct {
 	ChainID    int64
 	Address    string
@@ -110,6 +275,16 @@ func ReorgLog(ctx context.Context, params *ReorgLogParam) error {
 		return fmt.Errorf("failed to get mysql: %w", err)
 	}
 
+	payload, err := json.Marshal(&model.WebhookPayload{
+		Type:        model.WebhookEventRemoved,
+		ChainID:     params.ChainID,
+		Address:     params.Address,
+		BlockNumber: params.Checkpoint,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
 	err = utils.NewTx(db).Exec(ctx,
 		// upsert the block sync record
 		func(ctx context.Context, tx *sql.Tx) error {
@@ -121,15 +296,85 @@ func ReorgLog(ctx context.Context, params *ReorgLogParam) error {
 				UpdatedAt:      params.Now,
 			})
 		},
+		// tombstone the logs after the checkpoint before they are deleted
+		func(ctx context.Context, tx *sql.Tx) error {
+			if !config.Get().Outbox.Enabled {
+				return nil
+			}
+			return outboxRepo.TxInsertTombstone(ctx, tx, params.ChainID, params.Address, params.Checkpoint+1, params.Now)
+		},
+		// remove the logs after the checkpoint from the rollups before they are deleted
+		func(ctx context.Context, tx *sql.Tx) error {
+			return txUpdateEventRollup(ctx, tx, params.ChainID, params.Address, params.Checkpoint+1, nil, params.Now)
+		},
 		// delete the logs after the checkpoint
 		func(ctx context.Context, tx *sql.Tx) error {
-			return eventlog.TxDeleteLog(ctx, tx, params.Address, params.Checkpoint)
+			return eventlog.TxDeleteLog(ctx, tx, params.ChainID, params.Address, params.Checkpoint)
+		},
+		// revert the balance changes after the checkpoint
+		func(ctx context.Context, tx *sql.Tx) error {
+			return txRevertTokenBalance(ctx, tx, params.ChainID, params.Address, params.Checkpoint+1, params.Now)
+		},
+		// revert the allowances approved after the checkpoint
+		func(ctx context.Context, tx *sql.Tx) error {
+			return txRevertTokenAllowance(ctx, tx, params.ChainID, params.Address, params.Checkpoint+1, params.Now)
+		},
+		// retract the logs delivered to webhooks
+		func(ctx context.Context, tx *sql.Tx) error {
+			return webhookRepo.TxRetractDelivery(ctx, tx, params.ChainID, params.Address, params.Checkpoint, payload, params.Now)
 		},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to execute reorg tx: %w", err)
 	}
 
+	stream.Logs.Publish(stream.Event{
+		Type:        stream.EventRemoved,
+		ChainID:     params.ChainID,
+		Address:     params.Address,
+		BlockNumber: params.Checkpoint,
+	})
+	return nil
+}
+
+// CheckLogRange checks that a logs query is bounded by a time range, a block range or tx hashes,
+// and that the ranges do not exceed the configured spans.
+func CheckLogRange(filter *eventlog.GetLogParam) error {
+	hasTime := !filter.StartTime.IsZero() || !filter.EndTime.IsZero()
+	hasBlock := filter.BlockNumberGTE > 0 || filter.BlockNumberLTE > 0
+
+	if hasTime {
+		if filter.StartTime.IsZero() || filter.EndTime.IsZero() {
+			return errors.ErrApiInvalidParam.New("start time and end time are required together")
+		}
+
+		if filter.EndTime.Before(filter.StartTime) {
+			return errors.ErrApiInvalidParam.New("end time should not be before start time")
+		}
+
+		if filter.EndTime.Sub(filter.StartTime) > config.Get().API.MaxTimeSpan {
+			return errors.ErrApiInvalidParam.New(fmt.Sprintf("time range should not exceed %s", config.Get().API.MaxTimeSpan))
+		}
+	}
+
+	if hasBlock {
+		if filter.BlockNumberLTE == 0 {
+			return errors.ErrApiInvalidParam.New("end block number is required with start block number")
+		}
+
+		if filter.BlockNumberLTE < filter.BlockNumberGTE {
+			return errors.ErrApiInvalidParam.New("end block number should not be less than start block number")
+		}
+
+		if filter.BlockNumberLTE-filter.BlockNumberGTE > config.Get().API.MaxBlockSpan {
+			return errors.ErrApiInvalidParam.New(fmt.Sprintf("block range should not exceed %d blocks", config.Get().API.MaxBlockSpan))
+		}
+	}
+
+	if !hasTime && !hasBlock && len(filter.TxHashes) == 0 {
+		return errors.ErrApiInvalidParam.New("one of time range, block range or tx hash is required")
+	}
+
 	return nil
 }
 
@@ -159,6 +404,23 @@ func GetLogsWithTotal(ctx context.Context, filter *eventlog.GetLogParam) (logs [
 	return logs, total, nil
 }
 
+// GetLogsWithoutTotal retrieves event logs matching the filter criteria without counting the total,
+// used by keyset pagination where the count of every page is not needed.
+func GetLogsWithoutTotal(ctx context.Context, filter *eventlog.GetLogParam) (logs []*model.Log, err error) {
+
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	logs, err = eventlog.GetLogs(ctx, db, filter)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get logs")
+	}
+
+	return logs, nil
+}
+
 // GetLogs retrieves event logs matching the filter criteria.
 func GetLogs(ctx context.Context, filter *eventlog.GetLogParam) (logs []*model.Log, err error) {
 	db, err := storage.GetMySQL(config.EventDBS)
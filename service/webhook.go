@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/enum"
+	"evm_event_indexer/internal/errors"
+	"evm_event_indexer/internal/safehttp"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/internal/stream"
+	"evm_event_indexer/service/model"
+	"evm_event_indexer/service/repo/eventlog"
+	webhookRepo "evm_event_indexer/service/repo/webhook"
+	"evm_event_indexer/utils"
+	"fmt"
+	"time"
+)
+
+// NewWebhookFilter returns the webhook filter of a logs query, range, order and pagination are not used by a webhook.
+func NewWebhookFilter(p *eventlog.GetLogParam) *model.WebhookFilter {
+	filter := &model.WebhookFilter{
+		ChainID:   p.ChainID,
+		Addresses: p.Addresses,
+		Topic0s:   p.Topic0s,
+		Topic1s:   p.Topic1s,
+		Topic2s:   p.Topic2s,
+		Topic3s:   p.Topic3s,
+	}
+
+	for _, arg := range p.Args {
+		filter.Args = append(filter.Args, model.WebhookArg{
+			Name:  arg.Name,
+			Op:    arg.Op,
+			Value: arg.Value,
+		})
+	}
+
+	return filter
+}
+
+// validateWebhookURL rejects urls resolving to loopback, private or link-local addresses,
+// deliveries are checked again when dialing.
+func validateWebhookURL(ctx context.Context, url string) error {
+	if err := safehttp.ValidateURL(ctx, url, config.Get().Webhook.RequireHTTPS); err != nil {
+		return errors.ErrApiInvalidParam.New("invalid url: " + err.Error())
+	}
+	return nil
+}
+
+// CreateWebhook registers an enabled webhook of the user.
+func CreateWebhook(ctx context.Context, userID int64, url string, secret string, filter *model.WebhookFilter) (*model.Webhook, error) {
+	if userID <= 0 {
+		return nil, errors.ErrApiInvalidParam.New("invalid user id")
+	}
+	if url == "" {
+		return nil, errors.ErrApiInvalidParam.New("url is required")
+	}
+	if err := validateWebhookURL(ctx, url); err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, errors.ErrApiInvalidParam.New("secret is required")
+	}
+	if filter == nil {
+		return nil, errors.ErrApiInvalidParam.New("filter is required")
+	}
+
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	now := time.Now()
+	webhook := &model.Webhook{
+		UserID:    userID,
+		URL:       url,
+		Secret:    secret,
+		Filter:    filter,
+		Status:    enum.WebhookStatusEnabled,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := utils.NewTx(db).Exec(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		webhook.ID, err = webhookRepo.TxInsertWebhook(ctx, tx, webhook)
+		return err
+	}); err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to insert webhook")
+	}
+
+	return webhook, nil
+}
+
+// GetWebhook retrieves a webhook of the user.
+func GetWebhook(ctx context.Context, userID int64, id int64) (*model.Webhook, error) {
+	if id <= 0 {
+		return nil, errors.ErrApiInvalidParam.New("invalid webhook id")
+	}
+
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	webhooks, err := webhookRepo.GetWebhooks(ctx, db, &webhookRepo.GetWebhookFilter{
+		IDs:    []int64{id},
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get webhook")
+	}
+
+	if len(webhooks) == 0 {
+		return nil, errors.ErrWebhookNotFound.New()
+	}
+
+	return webhooks[0], nil
+}
+
+// GetWebhooksWithTotal returns the webhooks of the user and the total count.
+func GetWebhooksWithTotal(ctx context.Context, userID int64, pagination *model.Pagination) ([]*model.Webhook, int64, error) {
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	filter := &webhookRepo.GetWebhookFilter{
+		UserID:     userID,
+		Pagination: pagination,
+	}
+
+	total, err := webhookRepo.GetWebhookTotal(ctx, db, filter)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get webhook total")
+	}
+
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	webhooks, err := webhookRepo.GetWebhooks(ctx, db, filter)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get webhooks")
+	}
+
+	return webhooks, total, nil
+}
+
+// UpdateWebhook updates the non zero fields of a webhook of the user.
+func UpdateWebhook(ctx context.Context, webhook *model.Webhook) (*model.Webhook, error) {
+	// make sure the webhook belongs to the user
+	if _, err := GetWebhook(ctx, webhook.UserID, webhook.ID); err != nil {
+		return nil, err
+	}
+
+	if webhook.URL != "" {
+		if err := validateWebhookURL(ctx, webhook.URL); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	webhook.UpdatedAt = time.Now()
+	if err := utils.NewTx(db).Exec(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return webhookRepo.TxUpdateWebhook(ctx, tx, webhook)
+	}); err != nil {
+		return nil, errors.ErrInternalServerError.Wrap(err, "failed to update webhook")
+	}
+
+	return GetWebhook(ctx, webhook.UserID, webhook.ID)
+}
+
+// DeleteWebhook deletes a webhook of the user together with its deliveries.
+func DeleteWebhook(ctx context.Context, userID int64, id int64) error {
+	if _, err := GetWebhook(ctx, userID, id); err != nil {
+		return err
+	}
+
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	if err := utils.NewTx(db).Exec(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return webhookRepo.TxDeleteWebhook(ctx, tx, userID, id)
+	}); err != nil {
+		return errors.ErrInternalServerError.Wrap(err, "failed to delete webhook")
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveriesWithTotal returns the delivery log of a webhook of the user, latest first.
+func GetWebhookDeliveriesWithTotal(ctx context.Context, userID int64, filter *webhookRepo.GetDeliveryFilter) ([]*model.WebhookDelivery, int64, error) {
+	if _, err := GetWebhook(ctx, userID, filter.WebhookID); err != nil {
+		return nil, 0, err
+	}
+
+	db, err := storage.GetMySQL(config.EventDBS)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get mysql")
+	}
+
+	total, err := webhookRepo.GetDeliveryTotal(ctx, db, filter)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get delivery total")
+	}
+
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	deliveries, err := webhookRepo.GetDeliveries(ctx, db, filter)
+	if err != nil {
+		return nil, 0, errors.ErrInternalServerError.Wrap(err, "failed to get deliveries")
+	}
+
+	return deliveries, total, nil
+}
+
+// ClaimWebhookDeliveries leases the due deliveries to the caller, with their webhooks keyed by id.
+func ClaimWebhookDeliveries(ctx context.Context, limit uint64, lease time.Duration) ([]*model.WebhookDelivery, map[int64]*model.Webhook, error) {
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	now := time.Now()
+	var deliveries []*model.WebhookDelivery
+	if err := utils.NewTx(db).Exec(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		deliveries, err = webhookRepo.TxClaimDelivery(ctx, tx, now, now.Add(lease), limit)
+		return err
+	}); err != nil {
+		return nil, nil, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+
+	if len(deliveries) == 0 {
+		return nil, nil, nil
+	}
+
+	ids := make([]int64, 0, len(deliveries))
+	for _, v := range deliveries {
+		ids = append(ids, v.WebhookID)
+	}
+
+	webhooks, err := webhookRepo.GetWebhooks(ctx, db, &webhookRepo.GetWebhookFilter{IDs: ids})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+
+	res := make(map[int64]*model.Webhook, len(webhooks))
+	for _, v := range webhooks {
+		res[v.ID] = v
+	}
+
+	return deliveries, res, nil
+}
+
+// SaveWebhookDeliveryResult saves the result of a delivery attempt.
+func SaveWebhookDeliveryResult(ctx context.Context, delivery *model.WebhookDelivery) error {
+	db, err := storage.GetMySQL(config.EventDBM)
+	if err != nil {
+		return fmt.Errorf("failed to get mysql: %w", err)
+	}
+
+	return webhookRepo.UpdateDeliveryResult(ctx, db, delivery)
+}
+
+// getEnabledWebhooks returns the enabled webhooks watching the chain
+func getEnabledWebhooks(ctx context.Context, db *sql.DB, chainID int64) ([]*model.Webhook, error) {
+	webhooks, err := webhookRepo.GetWebhooks(ctx, db, &webhookRepo.GetWebhookFilter{
+		Status: enum.WebhookStatusEnabled,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*model.Webhook, 0, len(webhooks))
+	for _, v := range webhooks {
+		if v.Filter.ChainID == 0 || v.Filter.ChainID == chainID {
+			res = append(res, v)
+		}
+	}
+	return res, nil
+}
+
+// newWebhookDeliveries returns a pending delivery per webhook and matching log
+func newWebhookDeliveries(webhooks []*model.Webhook, logs []*model.Log, now time.Time) ([]*model.WebhookDelivery, error) {
+	deliveries := make([]*model.WebhookDelivery, 0)
+	for _, webhook := range webhooks {
+		filter := newStreamFilter(webhook.Filter)
+
+		for _, e := range stream.NewLogEvents(logs) {
+			if !filter.Match(e) {
+				continue
+			}
+
+			payload, err := json.Marshal(newWebhookLogPayload(e.Log))
+			if err != nil {
+				return nil, err
+			}
+
+			deliveries = append(deliveries, &model.WebhookDelivery{
+				WebhookID:     webhook.ID,
+				Type:          model.WebhookEventLog,
+				ChainID:       e.Log.ChainID,
+				Address:       e.Log.Address,
+				BlockNumber:   e.Log.BlockNumber,
+				TxIndex:       e.Log.TxIndex,
+				LogIndex:      e.Log.LogIndex,
+				Payload:       payload,
+				Status:        enum.DeliveryStatusPending,
+				NextAttemptAt: now,
+				CreatedAt:     now,
+				UpdatedAt:     now,
+			})
+		}
+	}
+
+	return deliveries, nil
+}
+
+// newStreamFilter matches logs in memory with the same semantics as the logs query
+func newStreamFilter(f *model.WebhookFilter) stream.Filter {
+	filter := stream.Filter{
+		ChainID:   f.ChainID,
+		Addresses: f.Addresses,
+		Topic0s:   f.Topic0s,
+		Topic1s:   f.Topic1s,
+		Topic2s:   f.Topic2s,
+		Topic3s:   f.Topic3s,
+	}
+
+	for _, arg := range f.Args {
+		filter.Args = append(filter.Args, eventlog.ArgFilter{
+			Name:  arg.Name,
+			Op:    arg.Op,
+			Value: arg.Value,
+		})
+	}
+
+	return filter
+}
+
+func newWebhookLogPayload(log *model.Log) *model.WebhookPayload {
+	topics := make([]string, 0, 4)
+	for _, topic := range []string{log.Topic0, log.Topic1, log.Topic2, log.Topic3} {
+		if topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+
+	return &model.WebhookPayload{
+		Type:        model.WebhookEventLog,
+		ChainID:     log.ChainID,
+		Address:     log.Address,
+		BlockNumber: log.BlockNumber,
+		Log: &model.WebhookLog{
+			BlockNumber:    log.BlockNumber,
+			BlockHash:      log.BlockHash,
+			TxHash:         log.TxHash,
+			Address:        log.Address,
+			Topics:         topics,
+			Data:           "0x" + hex.EncodeToString(log.Data),
+			TxIndex:        log.TxIndex,
+			LogIndex:       log.LogIndex,
+			DecodedEvent:   log.DecodedEvent,
+			BlockTimestamp: log.BlockTimestamp,
+		},
+	}
+}
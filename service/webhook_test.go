@@ -0,0 +1,119 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"evm_event_indexer/internal/config"
+	"evm_event_indexer/internal/enum"
+	"evm_event_indexer/internal/storage"
+	"evm_event_indexer/service/model"
+	webhookRepo "evm_event_indexer/service/repo/webhook"
+	"evm_event_indexer/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestWebhook inserts a disabled webhook, so no scan of another test queues deliveries to it,
+// and inserts a log delivery of the contract in the given state
+func newTestWebhook(t *testing.T, contract string, blockNumber uint64, status enum.DeliveryStatus, nextAttemptAt time.Time) int64 {
+	t.Helper()
+
+	db, err := storage.GetMySQL(config.EventDBM)
+	require.NoError(t, err)
+
+	now := time.Now()
+	var id int64
+	require.NoError(t, utils.NewTx(db).Exec(ctx,
+		func(ctx context.Context, tx *sql.Tx) error {
+			id, err = webhookRepo.TxInsertWebhook(ctx, tx, &model.Webhook{
+				UserID:    1,
+				URL:       "https://example.com/hook",
+				Secret:    "secret",
+				Filter:    &model.WebhookFilter{ChainID: chainID, Addresses: []string{contract}},
+				Status:    enum.WebhookStatusDisabled,
+				CreatedAt: now,
+				UpdatedAt: now,
+			})
+			return err
+		},
+		func(ctx context.Context, tx *sql.Tx) error {
+			return webhookRepo.TxInsertDelivery(ctx, tx, &model.WebhookDelivery{
+				WebhookID:     id,
+				Type:          model.WebhookEventLog,
+				ChainID:       chainID,
+				Address:       contract,
+				BlockNumber:   blockNumber,
+				Payload:       []byte(`{}`),
+				Status:        status,
+				NextAttemptAt: nextAttemptAt,
+				CreatedAt:     now,
+				UpdatedAt:     now,
+			})
+		},
+	))
+
+	t.Cleanup(func() {
+		_ = utils.NewTx(db).Exec(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			return webhookRepo.TxDeleteWebhook(ctx, tx, 1, id)
+		})
+	})
+
+	return id
+}
+
+// getDeliveries returns the statuses of the deliveries of a webhook keyed by type
+func getDeliveries(t *testing.T, webhookID int64) map[string]enum.DeliveryStatus {
+	t.Helper()
+
+	db, err := storage.GetMySQL(config.EventDBM)
+	require.NoError(t, err)
+
+	deliveries, err := webhookRepo.GetDeliveries(ctx, db, &webhookRepo.GetDeliveryFilter{
+		WebhookID:  webhookID,
+		Pagination: &model.Pagination{Page: 1, Size: 10},
+	})
+	require.NoError(t, err)
+
+	res := make(map[string]enum.DeliveryStatus, len(deliveries))
+	for _, v := range deliveries {
+		res[v.Type] = v.Status
+	}
+	return res
+}
+
+func Test_Webhook_RetractDelivery(t *testing.T) {
+	contract := newContract(t)
+	now := time.Now()
+
+	delivered := newTestWebhook(t, contract, 3, enum.DeliveryStatusSucceeded, now)
+	inFlight := newTestWebhook(t, contract, 4, enum.DeliveryStatusPending, now.Add(time.Minute))
+	due := newTestWebhook(t, contract, 5, enum.DeliveryStatusPending, now.Add(-time.Second))
+	kept := newTestWebhook(t, contract, 2, enum.DeliveryStatusSucceeded, now)
+
+	reorg(t, contract, 2)
+
+	// a delivered log is retracted
+	assert.Equal(t, map[string]enum.DeliveryStatus{
+		model.WebhookEventLog:     enum.DeliveryStatusSucceeded,
+		model.WebhookEventRemoved: enum.DeliveryStatusPending,
+	}, getDeliveries(t, delivered))
+
+	// a leased delivery may be received while it is cancelled, so it is retracted as well
+	assert.Equal(t, map[string]enum.DeliveryStatus{
+		model.WebhookEventLog:     enum.DeliveryStatusCancelled,
+		model.WebhookEventRemoved: enum.DeliveryStatusPending,
+	}, getDeliveries(t, inFlight))
+
+	// a due delivery was never sent, cancelling it is enough
+	assert.Equal(t, map[string]enum.DeliveryStatus{
+		model.WebhookEventLog: enum.DeliveryStatusCancelled,
+	}, getDeliveries(t, due))
+
+	// logs up to the checkpoint are not removed
+	assert.Equal(t, map[string]enum.DeliveryStatus{
+		model.WebhookEventLog: enum.DeliveryStatusSucceeded,
+	}, getDeliveries(t, kept))
+}
@@ -0,0 +1,16 @@
+package hashing_test
+
+import (
+	"evm_event_indexer/utils/hashing"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_HmacSha256(t *testing.T) {
+	// RFC 4231 test case 2
+	got := hashing.HmacSha256([]byte("Jefe"), []byte("what do ya want for nothing?"))
+	assert.Equal(t, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843", got)
+
+	assert.NotEqual(t, got, hashing.HmacSha256([]byte("jefe"), []byte("what do ya want for nothing?")))
+}